@@ -6,9 +6,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"backend-server/internal/app/approach"
 	"backend-server/internal/app/config"
 	"backend-server/internal/app/dsn"
 	"backend-server/internal/app/handler"
+	"backend-server/internal/app/jplde"
+	"backend-server/internal/app/orbitclient"
 	"backend-server/internal/app/redis"
 	"backend-server/internal/app/repository"
 	"backend-server/internal/pkg"
@@ -43,7 +46,27 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	handler := handler.NewHandler(repo, cfg, redisClient)
+	planetSource, eph, err := jplde.OpenSource(cfg.Ephemeris)
+	if err != nil {
+		logrus.Fatalf("failed to open JPL ephemeris: %v", err)
+	}
+	if eph != nil {
+		defer eph.Close()
+		start, end := eph.Span()
+		logrus.Infof("JPL ephemeris %s covers JD %.1f–%.1f", cfg.Ephemeris.Path, start, end)
+	}
+
+	orbitCalc, err := orbitclient.New(cfg.Orbit, planetSource)
+	if err != nil {
+		logrus.Fatalf("failed to initialize orbit calculator: %v", err)
+	}
+
+	if _, err := approach.ParseBodies(cfg.Orbit.ApproachBodies); err != nil {
+		logrus.Fatalf("invalid orbit.approach_bodies: %v", err)
+	}
+
+	handler := handler.NewHandler(repo, cfg, redisClient, orbitCalc, planetSource)
+	handler.StartOrbitWorkers(ctx)
 
 	app := pkg.NewApp(cfg, router, handler)
 	app.RunApp()
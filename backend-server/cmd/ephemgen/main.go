@@ -0,0 +1,142 @@
+// Команда ephemgen строит встроенную чебышёвскую эфемериду планет и Луны
+// (internal/app/planets/data/planets.cheb), которую читает planets.Default.
+//
+// Положения берутся из эфемериды JPL (-de: ядро SPK или двоичный файл DE, например
+// de440s.bsp), а без неё — из аналитических теорий planets.Analytic, ошибка которых
+// у внешних планет достигает угловых минут.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/jplde"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// layout — параметры ряда тела: длина интервала (сутки) и число коэффициентов.
+type layout struct {
+	body     planets.Body
+	center   planets.Body
+	interval float64
+	n        int
+}
+
+var layouts = []layout{
+	{planets.BodyMercury, planets.BodySun, 32, 14},
+	{planets.BodyVenus, planets.BodySun, 64, 12},
+	{planets.BodyEarth, planets.BodySun, 32, 12},
+	{planets.BodyMoon, planets.BodyEarth, 16, 10},
+	{planets.BodyMars, planets.BodySun, 128, 10},
+	{planets.BodyJupiter, planets.BodySun, 256, 8},
+	{planets.BodySaturn, planets.BodySun, 256, 8},
+	{planets.BodyUranus, planets.BodySun, 256, 8},
+	{planets.BodyNeptune, planets.BodySun, 256, 8},
+}
+
+func main() {
+	out := flag.String("out", "internal/app/planets/data/planets.cheb", "output file")
+	from := flag.String("start", "1950-01-01", "first date covered (UTC)")
+	to := flag.String("end", "2150-01-01", "last date covered (UTC)")
+	dePath := flag.String("de", "", "JPL ephemeris (SPK kernel or DE binary file) to convert; analytic theories if empty")
+	flag.Parse()
+
+	start, err := parseDate(*from)
+	if err != nil {
+		log.Fatalf(" Invalid -start: %v", err)
+	}
+	end, err := parseDate(*to)
+	if err != nil {
+		log.Fatalf(" Invalid -end: %v", err)
+	}
+	if end <= start {
+		log.Fatal(" -end must be after -start")
+	}
+
+	source := analyticPosition
+	if *dePath != "" {
+		eph, err := jplde.Open(*dePath, 0)
+		if err != nil {
+			log.Fatalf(" Failed to open JPL ephemeris: %v", err)
+		}
+		defer eph.Close()
+		if first, last := eph.Span(); start < first || end > last {
+			log.Fatalf(" %s covers JD %.1f–%.1f only", *dePath, first, last)
+		}
+		source = dePosition(eph)
+	}
+
+	series := make([]planets.Series, len(layouts))
+	for i, l := range layouts {
+		series[i] = planets.Fit(l.body, l.center, start, end, l.interval, l.n, source(l.body))
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf(" Failed to create %s: %v", *out, err)
+	}
+	if err := planets.WriteChebyshev(f, start, end, series); err != nil {
+		f.Close()
+		log.Fatalf(" Failed to write ephemeris: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf(" Failed to write ephemeris: %v", err)
+	}
+	fmt.Printf("✅ Wrote %s (JD %.1f–%.1f, %d bodies)\n", *out, start, end, len(series))
+}
+
+// parseDate переводит дату UTC в юлианскую дату TT.
+func parseDate(s string) (float64, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return 0, err
+	}
+	return astrotime.TT(t), nil
+}
+
+// analyticPosition возвращает функцию положения тела относительно центра его ряда
+// по аналитическим теориям.
+func analyticPosition(b planets.Body) func(jd float64) orbit.Vec3 {
+	center := centers()[b]
+	return func(jd float64) orbit.Vec3 {
+		r, err := planets.Analytic{}.Position(b, jd)
+		if err != nil {
+			log.Fatalf(" Failed to compute %s: %v", b, err)
+		}
+		c, err := planets.Analytic{}.Position(center, jd)
+		if err != nil {
+			log.Fatalf(" Failed to compute %s: %v", center, err)
+		}
+		return r.Sub(c)
+	}
+}
+
+// dePosition возвращает функцию положения тела относительно центра его ряда по эфемериде JPL.
+// Охват эфемериды проверен заранее, поэтому ошибка чтения прерывает работу.
+func dePosition(eph *jplde.Ephemeris) func(planets.Body) func(jd float64) orbit.Vec3 {
+	return func(b planets.Body) func(jd float64) orbit.Vec3 {
+		target, _ := jplde.BodyTarget(b)
+		center, _ := jplde.BodyTarget(centers()[b])
+		return func(jd float64) orbit.Vec3 {
+			s, err := eph.Relative(target, center, jd)
+			if err != nil {
+				log.Fatalf(" Failed to read %s from the JPL ephemeris: %v", b, err)
+			}
+			return s.R
+		}
+	}
+}
+
+// centers возвращает центральные тела рядов из layouts.
+func centers() map[planets.Body]planets.Body {
+	m := make(map[planets.Body]planets.Body, len(layouts))
+	for _, l := range layouts {
+		m[l.body] = l.center
+	}
+	return m
+}
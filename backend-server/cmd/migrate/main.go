@@ -8,8 +8,14 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/config"
 	"backend-server/internal/app/ds"
 	"backend-server/internal/app/dsn"
+	"backend-server/internal/app/jplde"
+	"backend-server/internal/app/moid"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
 )
 
 func main() {
@@ -33,5 +39,74 @@ func main() {
 		&ds.Observation{},
 		&ds.CloseApproach{},
 		&ds.User{},
+		&ds.Observatory{},
 	)
+
+	// Орбиты раньше хранились через большую полуось: заполняем перигелийное расстояние
+	// у всех комет, где оно ещё не задано (повторный запуск ничего не меняет)
+	if err := db.Exec("UPDATE comets SET q = a * (1 - e) WHERE q = 0 AND a IS NOT NULL").Error; err != nil {
+		log.Fatalf(" Failed to fill comet perihelion distances: %v", err)
+	}
+
+	// Ковариация по a сбрасывается вместе с колонкой sigma_a — она появится после пересчёта орбиты
+	if db.Migrator().HasColumn(&ds.Comet{}, "sigma_a") {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE comets SET covariance = NULL WHERE sigma_q IS NULL").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&ds.Comet{}, "sigma_a")
+		})
+		if err != nil {
+			log.Fatalf(" Failed to drop comet semi-major axis covariance: %v", err)
+		}
+	}
+
+	// MOID появился позже орбит: вычисляем его для комет с рассчитанной орбитой
+	// по тем же настройкам, что и приложение
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf(" Failed to load config: %v", err)
+	}
+	src, eph, err := jplde.OpenSource(cfg.Ephemeris)
+	if err != nil {
+		log.Fatalf(" Failed to open JPL ephemeris: %v", err)
+	}
+	if eph != nil {
+		defer eph.Close()
+	}
+	if err := backfillMOID(db, src, cfg.Orbit.JupiterMOID); err != nil {
+		log.Fatalf(" Failed to compute comet MOIDs: %v", err)
+	}
+}
+
+// backfillMOID вычисляет MOID с Землёй и, если jupiter, с Юпитером для комет, у которых
+// он ещё не рассчитан. Орбиты планет — оскулирующие на эпоху элементов кометы по источнику
+// src (jplde.OpenSource); кометы, эпоха которых вне охвата источника, пропускаются.
+func backfillMOID(db *gorm.DB, src planets.Source, jupiter bool) error {
+	var comets []ds.Comet
+	if err := db.Where("earth_moid IS NULL AND q > 0").Find(&comets).Error; err != nil {
+		return err
+	}
+	updated := 0
+	for _, c := range comets {
+		el := orbit.ElementsFromDegrees(c.Q, c.E, c.I, c.Node, c.ArgPeri, astrotime.TT(c.T))
+		jd := astrotime.TT(c.Epoch)
+		earth, err := moid.Planet(el, src, planets.BodyEarth, jd)
+		if err != nil {
+			log.Printf("⚠️  comet %d: MOID not computed: %v", c.ID, err)
+			continue
+		}
+		columns := map[string]any{"earth_moid": earth.DistanceAU}
+		if jupiter {
+			if res, err := moid.Planet(el, src, planets.BodyJupiter, jd); err == nil {
+				columns["jupiter_moid"] = res.DistanceAU
+			}
+		}
+		if err := db.Model(&ds.Comet{}).Where("id = ?", c.ID).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		updated++
+	}
+	fmt.Printf("✅ MOID computed for %d comets\n", updated)
+	return nil
 }
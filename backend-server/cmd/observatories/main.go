@@ -0,0 +1,87 @@
+// Команда observatories заполняет справочник обсерваторий из списка кодов MPC (ObsCodes).
+// Список берётся из файла (-file) или скачивается с сайта MPC (-url). Существующие записи обновляются.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/dsn"
+	"backend-server/internal/app/mpc"
+)
+
+const defaultObsCodesURL = "https://minorplanetcenter.net/iau/lists/ObsCodes.html"
+
+func main() {
+	file := flag.String("file", "", "path to a local ObsCodes file (downloaded from -url if empty)")
+	url := flag.String("url", defaultObsCodesURL, "URL of the MPC ObsCodes list")
+	flag.Parse()
+
+	// Загружаем переменные окружения из .env
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  .env file not found, using system environment variables")
+	}
+
+	src, err := open(*file, *url)
+	if err != nil {
+		log.Fatalf(" Failed to read observatory codes: %v", err)
+	}
+	defer src.Close()
+
+	parsed, err := mpc.ParseObsCodes(src)
+	if err != nil {
+		log.Fatalf(" Failed to parse observatory codes: %v", err)
+	}
+	if len(parsed) == 0 {
+		log.Fatal(" No observatories found in the input")
+	}
+
+	rows := make([]ds.Observatory, len(parsed))
+	for i, o := range parsed {
+		rows[i] = ds.Observatory{Code: o.Code, Name: o.Name}
+		if s := o.Site; s != nil {
+			rows[i].Longitude, rows[i].RhoCos, rows[i].RhoSin = &s.Longitude, &s.RhoCos, &s.RhoSin
+		}
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn.FromEnv()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf(" Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(&ds.Observatory{}); err != nil {
+		log.Fatalf(" Failed to migrate observatories: %v", err)
+	}
+	err = db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(rows, 500).Error
+	if err != nil {
+		log.Fatalf(" Failed to save observatories: %v", err)
+	}
+	fmt.Printf("✅ Loaded %d observatories\n", len(rows))
+}
+
+// open возвращает локальный файл или тело ответа MPC.
+func open(file, url string) (io.ReadCloser, error) {
+	if file != "" {
+		return os.Open(file)
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
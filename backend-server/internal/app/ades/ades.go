@@ -0,0 +1,267 @@
+// Package ades читает и пишет астрометрические наблюдения в формате ADES
+// (Astrometry Data Exchange Standard) MPC/IAU — в виде XML и PSV (значения,
+// разделённые вертикальной чертой). Поддерживаются оптические наблюдения.
+package ades
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version — версия ADES, которая указывается в создаваемых документах.
+const Version = "2017"
+
+// Коды обсерваторий MPC, для которых положение наблюдателя не берётся из списка обсерваторий.
+const (
+	StnRoving     = "247" // подвижный наблюдатель, координаты в sys/pos1…pos3
+	StnGeocentric = "500" // центр Земли
+)
+
+// sysWGS84 — система координат подвижного наблюдателя: pos1 — долгота к востоку,
+// pos2 — широта (градусы), pos3 — высота над эллипсоидом (м).
+const sysWGS84 = "WGS84"
+
+// timeLayout — формат obsTime в создаваемых документах (UTC, миллисекунды).
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+// Observation — оптическое наблюдение ADES.
+type Observation struct {
+	Line    int       // номер строки PSV или строки начала элемента <optical> в XML
+	PermID  string    // постоянное обозначение объекта
+	ProvID  string    // предварительное обозначение объекта
+	TrkSub  string    // обозначение трека, присвоенное наблюдателем
+	Mode    string    // способ наблюдения: CCD, CMO, PHO и т. д.
+	Stn     string    // код обсерватории MPC
+	ObsTime time.Time // момент наблюдения, UTC
+	RA      float64   // прямое восхождение, градусы
+	Dec     float64   // склонение, градусы
+	RmsRA   *float64  // погрешность RA·cos δ, угловые секунды; nil — не указана
+	RmsDec  *float64  // погрешность склонения, угловые секунды
+	AstCat  string    // астрометрический каталог привязки
+	Mag     *float64  // блеск, nil — не измерен
+	Band    string    // фотометрическая полоса блеска
+
+	// Положение подвижного наблюдателя (Stn = StnRoving) в системе WGS84
+	Roving *Roving
+}
+
+// Roving — координаты подвижного наблюдателя: широта, долгота к востоку (градусы) и высота (м).
+type Roving struct {
+	Latitude, Longitude, Altitude float64
+}
+
+// Context — заголовок блока наблюдений (obsContext) одной обсерватории.
+type Context struct {
+	Observatory string     // код обсерватории MPC
+	Submitter   string     // кто отправляет наблюдения
+	Observers   []string   // наблюдатели
+	Measurers   []string   // кто проводил измерения
+	Telescope   *Telescope // nil — не указан
+}
+
+// Telescope — описание телескопа в заголовке блока.
+type Telescope struct {
+	Design   string  // конструкция: reflector, refractor и т. д.
+	Aperture float64 // апертура, м
+	Detector string  // приёмник: CCD, CMO и т. д.
+}
+
+// Block — блок наблюдений одной обсерватории с общим заголовком.
+type Block struct {
+	Context      Context
+	Observations []Observation
+}
+
+// LineError — ошибка разбора одной строки документа.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ParseError объединяет ошибки разбора отдельных наблюдений.
+type ParseError []LineError
+
+func (e ParseError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("line %d: %s", e[0].Line, e[0].Error)
+	}
+	return fmt.Sprintf("line %d: %s (and %d more errors)", e[0].Line, e[0].Error, len(e)-1)
+}
+
+// Parse разбирает документ ADES, определяя формат по первому непробельному символу:
+// «<» — XML, иначе PSV.
+func Parse(r io.Reader) ([]Observation, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	if bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n\ufeff"), []byte("<")) {
+		return ParseXML(br)
+	}
+	return ParsePSV(br)
+}
+
+// record — поля оптического наблюдения в текстовом виде, как они записаны в документе.
+// Порядок полей соответствует порядку элементов в схеме ADES.
+type record struct {
+	PermID  string `xml:"permID,omitempty"`
+	ProvID  string `xml:"provID,omitempty"`
+	TrkSub  string `xml:"trkSub,omitempty"`
+	Mode    string `xml:"mode,omitempty"`
+	Stn     string `xml:"stn,omitempty"`
+	Sys     string `xml:"sys,omitempty"`
+	Ctr     string `xml:"ctr,omitempty"`
+	Pos1    string `xml:"pos1,omitempty"`
+	Pos2    string `xml:"pos2,omitempty"`
+	Pos3    string `xml:"pos3,omitempty"`
+	ObsTime string `xml:"obsTime,omitempty"`
+	RA      string `xml:"ra,omitempty"`
+	Dec     string `xml:"dec,omitempty"`
+	RmsRA   string `xml:"rmsRA,omitempty"`
+	RmsDec  string `xml:"rmsDec,omitempty"`
+	AstCat  string `xml:"astCat,omitempty"`
+	Mag     string `xml:"mag,omitempty"`
+	Band    string `xml:"band,omitempty"`
+}
+
+// column — столбец PSV и соответствующее ему поле record.
+type column struct {
+	name     string
+	required bool // столбец обязателен в создаваемых документах
+	field    func(*record) *string
+}
+
+var columns = []column{
+	{"permID", false, func(r *record) *string { return &r.PermID }},
+	{"provID", false, func(r *record) *string { return &r.ProvID }},
+	{"trkSub", false, func(r *record) *string { return &r.TrkSub }},
+	{"mode", true, func(r *record) *string { return &r.Mode }},
+	{"stn", true, func(r *record) *string { return &r.Stn }},
+	{"sys", false, func(r *record) *string { return &r.Sys }},
+	{"ctr", false, func(r *record) *string { return &r.Ctr }},
+	{"pos1", false, func(r *record) *string { return &r.Pos1 }},
+	{"pos2", false, func(r *record) *string { return &r.Pos2 }},
+	{"pos3", false, func(r *record) *string { return &r.Pos3 }},
+	{"obsTime", true, func(r *record) *string { return &r.ObsTime }},
+	{"ra", true, func(r *record) *string { return &r.RA }},
+	{"dec", true, func(r *record) *string { return &r.Dec }},
+	{"rmsRA", false, func(r *record) *string { return &r.RmsRA }},
+	{"rmsDec", false, func(r *record) *string { return &r.RmsDec }},
+	{"astCat", true, func(r *record) *string { return &r.AstCat }},
+	{"mag", false, func(r *record) *string { return &r.Mag }},
+	{"band", false, func(r *record) *string { return &r.Band }},
+}
+
+// observation проверяет поля записи и переводит их в наблюдение.
+func (r *record) observation(line int) (Observation, error) {
+	o := Observation{
+		Line:   line,
+		PermID: r.PermID,
+		ProvID: r.ProvID,
+		TrkSub: r.TrkSub,
+		Mode:   r.Mode,
+		Stn:    strings.ToUpper(r.Stn),
+		AstCat: r.AstCat,
+		Band:   r.Band,
+	}
+	if len(o.Stn) != 3 {
+		return o, fmt.Errorf("invalid stn %q", r.Stn)
+	}
+
+	var err error
+	if r.ObsTime == "" {
+		return o, errors.New("obsTime is required")
+	}
+	if o.ObsTime, err = time.Parse(time.RFC3339Nano, r.ObsTime); err != nil {
+		return o, fmt.Errorf("invalid obsTime %q", r.ObsTime)
+	}
+	o.ObsTime = o.ObsTime.UTC()
+	if o.RA, err = strconv.ParseFloat(r.RA, 64); err != nil || o.RA < 0 || o.RA >= 360 {
+		return o, fmt.Errorf("invalid ra %q", r.RA)
+	}
+	if o.Dec, err = strconv.ParseFloat(r.Dec, 64); err != nil || o.Dec < -90 || o.Dec > 90 {
+		return o, fmt.Errorf("invalid dec %q", r.Dec)
+	}
+	if o.RmsRA, err = optionalFloat(r.RmsRA); err != nil || (o.RmsRA != nil && *o.RmsRA <= 0) {
+		return o, fmt.Errorf("invalid rmsRA %q", r.RmsRA)
+	}
+	if o.RmsDec, err = optionalFloat(r.RmsDec); err != nil || (o.RmsDec != nil && *o.RmsDec <= 0) {
+		return o, fmt.Errorf("invalid rmsDec %q", r.RmsDec)
+	}
+	if o.Mag, err = optionalFloat(r.Mag); err != nil {
+		return o, fmt.Errorf("invalid mag %q", r.Mag)
+	}
+
+	if o.Stn == StnRoving || r.Sys != "" {
+		if !strings.EqualFold(r.Sys, sysWGS84) {
+			return o, fmt.Errorf("unsupported sys %q: only %s positions are supported", r.Sys, sysWGS84)
+		}
+		var rv Roving
+		if rv.Longitude, err = strconv.ParseFloat(r.Pos1, 64); err != nil || rv.Longitude < -180 || rv.Longitude > 360 {
+			return o, fmt.Errorf("invalid pos1 %q", r.Pos1)
+		}
+		if rv.Latitude, err = strconv.ParseFloat(r.Pos2, 64); err != nil || rv.Latitude < -90 || rv.Latitude > 90 {
+			return o, fmt.Errorf("invalid pos2 %q", r.Pos2)
+		}
+		if r.Pos3 != "" {
+			if rv.Altitude, err = strconv.ParseFloat(r.Pos3, 64); err != nil {
+				return o, fmt.Errorf("invalid pos3 %q", r.Pos3)
+			}
+		}
+		o.Roving = &rv
+	}
+	return o, nil
+}
+
+// newRecord переводит наблюдение в текстовые поля записи.
+func newRecord(o Observation) record {
+	r := record{
+		PermID:  o.PermID,
+		ProvID:  o.ProvID,
+		TrkSub:  o.TrkSub,
+		Mode:    o.Mode,
+		Stn:     o.Stn,
+		ObsTime: o.ObsTime.UTC().Format(timeLayout),
+		RA:      strconv.FormatFloat(o.RA, 'f', 6, 64),
+		Dec:     strconv.FormatFloat(o.Dec, 'f', 6, 64),
+		RmsRA:   formatOptional(o.RmsRA),
+		RmsDec:  formatOptional(o.RmsDec),
+		AstCat:  o.AstCat,
+		Mag:     formatOptional(o.Mag),
+		Band:    o.Band,
+	}
+	if rv := o.Roving; rv != nil {
+		r.Sys = sysWGS84
+		r.Ctr = "399"
+		r.Pos1 = strconv.FormatFloat(rv.Longitude, 'f', 6, 64)
+		r.Pos2 = strconv.FormatFloat(rv.Latitude, 'f', 6, 64)
+		r.Pos3 = strconv.FormatFloat(rv.Altitude, 'f', 1, 64)
+	}
+	// полоса указывается только вместе с блеском
+	if r.Mag == "" {
+		r.Band = ""
+	}
+	return r
+}
+
+func optionalFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,266 @@
+package ades
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// psvSample — документ PSV из двух блоков в виде, в котором их присылают обсерватории:
+// с выравниванием, лишним столбцом и подвижным наблюдателем во втором блоке.
+const psvSample = `# version=2017
+# observatory
+! mpcCode 695
+# submitter
+! name J. Smith
+permID |provID   |mode|stn|obsTime                 |ra        |dec       |rmsRA|rmsDec|astCat|mag |band|remarks
+2      |         |CCD |695|2017-02-15T03:12:45.120Z|341.012345|-2.5      |0.15 |0.2   |Gaia2 |14.2|G   |ok
+       |2017 AB12|CCD |695|2017-02-15T03:40:00Z    |0.5       |+89.999999|     |      |Gaia2 |    |    |
+
+# observatory
+! mpcCode 247
+provID   |mode|stn|sys  |ctr|pos1      |pos2     |pos3  |obsTime                 |ra        |dec       |astCat
+2017 AB12|CCD |247|WGS84|399|-70.73    |-30.2407 |2715.0|2017-02-16T01:00:00.500Z|12.25     |-0.75     |UCAC4
+`
+
+// xmlSample — те же наблюдения первого блока документом XML.
+const xmlSample = `<?xml version="1.0" encoding="UTF-8"?>
+<ades version="2017">
+  <obsBlock>
+    <obsContext>
+      <observatory><mpcCode>695</mpcCode></observatory>
+    </obsContext>
+    <obsData>
+      <optical>
+        <permID>2</permID>
+        <mode>CCD</mode>
+        <stn>695</stn>
+        <obsTime>2017-02-15T03:12:45.120Z</obsTime>
+        <ra>341.012345</ra>
+        <dec>-2.5</dec>
+        <rmsRA>0.15</rmsRA>
+        <rmsDec>0.2</rmsDec>
+        <astCat>Gaia2</astCat>
+        <mag>14.2</mag>
+        <band>G</band>
+      </optical>
+      <optical>
+        <provID> 2017 AB12 </provID>
+        <mode>CCD</mode>
+        <stn>695</stn>
+        <obsTime>2017-02-15T03:40:00Z</obsTime>
+        <ra>0.5</ra>
+        <dec>+89.999999</dec>
+        <astCat>Gaia2</astCat>
+      </optical>
+    </obsData>
+  </obsBlock>
+</ades>
+`
+
+var (
+	sampleFirst = Observation{
+		PermID: "2", Mode: "CCD", Stn: "695",
+		ObsTime: time.Date(2017, 2, 15, 3, 12, 45, 120e6, time.UTC),
+		RA:      341.012345, Dec: -2.5, RmsRA: ptr(0.15), RmsDec: ptr(0.2),
+		AstCat: "Gaia2", Mag: ptr(14.2), Band: "G",
+	}
+	sampleSecond = Observation{
+		ProvID: "2017 AB12", Mode: "CCD", Stn: "695",
+		ObsTime: time.Date(2017, 2, 15, 3, 40, 0, 0, time.UTC),
+		RA:      0.5, Dec: 89.999999, AstCat: "Gaia2",
+	}
+	sampleRoving = Observation{
+		ProvID: "2017 AB12", Mode: "CCD", Stn: StnRoving,
+		ObsTime: time.Date(2017, 2, 16, 1, 0, 0, 500e6, time.UTC),
+		RA:      12.25, Dec: -0.75, AstCat: "UCAC4",
+		Roving: &Roving{Latitude: -30.2407, Longitude: -70.73, Altitude: 2715},
+	}
+)
+
+// withLine возвращает наблюдение с номером строки документа.
+func withLine(o Observation, line int) Observation {
+	o.Line = line
+	return o
+}
+
+func TestParseSamples(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []Observation
+	}{
+		{
+			name: "psv",
+			doc:  psvSample,
+			want: []Observation{withLine(sampleFirst, 7), withLine(sampleSecond, 8), withLine(sampleRoving, 13)},
+		},
+		{
+			name: "xml",
+			doc:  xmlSample,
+			want: []Observation{withLine(sampleFirst, 8), withLine(sampleSecond, 21)},
+		},
+		{
+			// формат определяется и после BOM и пустых строк
+			name: "xml with bom",
+			doc:  "\ufeff\n\n" + xmlSample,
+			want: []Observation{withLine(sampleFirst, 10), withLine(sampleSecond, 23)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Записанный документ читается обратно без потерь в обоих форматах.
+func TestRoundTrip(t *testing.T) {
+	blocks := []Block{
+		{
+			Context: Context{
+				Observatory: "695",
+				Submitter:   "J. Smith",
+				Observers:   []string{"A. Observer", "B. Observer"},
+				Measurers:   []string{"C. Measurer"},
+				Telescope:   &Telescope{Design: "reflector", Aperture: 0.6, Detector: "CCD"},
+			},
+			Observations: []Observation{sampleFirst, sampleSecond},
+		},
+		{
+			Context:      Context{Observatory: StnRoving},
+			Observations: []Observation{sampleRoving},
+		},
+	}
+	want := []Observation{sampleFirst, sampleSecond, sampleRoving}
+
+	formats := []struct {
+		name  string
+		write func(*bytes.Buffer, []Block) error
+		parse func(*bytes.Buffer) ([]Observation, error)
+	}{
+		{
+			"psv",
+			func(b *bytes.Buffer, blocks []Block) error { return WritePSV(b, blocks) },
+			func(b *bytes.Buffer) ([]Observation, error) { return ParsePSV(b) },
+		},
+		{
+			"xml",
+			func(b *bytes.Buffer, blocks []Block) error { return WriteXML(b, blocks) },
+			func(b *bytes.Buffer) ([]Observation, error) { return ParseXML(b) },
+		},
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := f.write(&buf, blocks); err != nil {
+				t.Fatal(err)
+			}
+			doc := buf.String()
+			for _, s := range []string{"695", "J. Smith", "B. Observer", "C. Measurer", "reflector", "0.6"} {
+				if !strings.Contains(doc, s) {
+					t.Errorf("document lacks %q:\n%s", s, doc)
+				}
+			}
+
+			got, err := f.parse(&buf)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, doc)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d observations, want %d:\n%s", len(got), len(want), doc)
+			}
+			for i := range got {
+				got[i].Line = 0
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("observation %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// Полоса без блеска не записывается, необязательные пустые столбцы PSV не выводятся.
+func TestWritePSVColumns(t *testing.T) {
+	o := sampleSecond
+	o.Band = "V"
+	var buf bytes.Buffer
+	if err := WritePSV(&buf, []Block{{Context: Context{Observatory: "695"}, Observations: []Observation{o}}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	header := splitPSV(lines[len(lines)-2])
+	want := []string{"provID", "mode", "stn", "obsTime", "ra", "dec", "astCat"}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("columns %v, want %v", header, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const head = "stn|obsTime|ra|dec\n"
+	tests := []struct {
+		name      string
+		doc       string
+		wantObs   int
+		wantLines []int
+		wantText  string
+	}{
+		{"missing columns", "stn|ra|dec\n695|1|2\n", 0, []int{1}, "missing columns: obsTime"},
+		{"field count", head + "695|2017-01-01T00:00:00Z|1\n", 0, []int{2}, "expected 4 fields"},
+		{"bad stn", head + "69|2017-01-01T00:00:00Z|1|2\n", 0, []int{2}, "invalid stn"},
+		{"bad time", head + "695|2017-01-01 00:00|1|2\n", 0, []int{2}, "invalid obsTime"},
+		{"ra out of range", head + "695|2017-01-01T00:00:00Z|360|2\n", 0, []int{2}, "invalid ra"},
+		{"dec out of range", head + "695|2017-01-01T00:00:00Z|1|-90.5\n", 0, []int{2}, "invalid dec"},
+		{"zero rms", "stn|obsTime|ra|dec|rmsRA\n695|2017-01-01T00:00:00Z|1|2|0\n", 0, []int{2}, "invalid rmsRA"},
+		{"roving without position", head + "247|2017-01-01T00:00:00Z|1|2\n", 0, []int{2}, "unsupported sys"},
+		{
+			"errors do not stop parsing",
+			head + "695|bad|1|2\n695|2017-01-01T00:00:00Z|1|2\n695|2017-01-01T00:00:00Z|1|100\n",
+			1, []int{2, 4}, "invalid obsTime",
+		},
+		{
+			"new block needs its own header",
+			head + "695|2017-01-01T00:00:00Z|1|2\n# observatory\nmode|stn\nCCD|695\n",
+			1, []int{4}, "missing columns",
+		},
+		{"xml syntax", "<ades><obsBlock><optical><stn>695</stn></obsBlock>", 0, []int{1}, "syntax error"},
+		{
+			"xml observation",
+			"<ades><obsBlock><obsData>\n<optical><stn>695</stn><obsTime>2017-01-01T00:00:00Z</obsTime><ra>1</ra></optical>\n</obsData></obsBlock></ades>",
+			0, []int{2}, "invalid dec",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs, err := Parse(strings.NewReader(tt.doc))
+			var perr ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want ParseError", err)
+			}
+			if len(obs) != tt.wantObs {
+				t.Errorf("got %d observations, want %d", len(obs), tt.wantObs)
+			}
+			var lines []int
+			for _, e := range perr {
+				lines = append(lines, e.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("error lines %v, want %v (%v)", lines, tt.wantLines, err)
+			}
+			if !strings.Contains(perr[0].Error, tt.wantText) {
+				t.Errorf("error %q, want it to mention %q", perr[0].Error, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package ades
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// psvRequired — столбцы, без которых наблюдения блока PSV не разбираются.
+var psvRequired = []string{"stn", "obsTime", "ra", "dec"}
+
+// ParsePSV разбирает документ ADES в формате PSV. Строки заголовка блока («#» и «!»)
+// пропускаются, первая строка после них задаёт имена столбцов; неизвестные столбцы
+// игнорируются. Некорректные строки не прерывают разбор: их ошибки возвращаются
+// как ParseError вместе с успешно разобранными наблюдениями.
+func ParsePSV(r io.Reader) ([]Observation, error) {
+	var (
+		out    []Observation
+		errs   ParseError
+		header []string // имена столбцов текущего блока; nil — ожидается строка столбцов
+		skip   bool     // в строке столбцов нет обязательных, блок пропускается
+	)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "#") || strings.HasPrefix(text, "!"):
+			// заголовок нового блока: за ним следует своя строка столбцов
+			header, skip = nil, false
+		case header == nil:
+			header = splitPSV(text)
+			if missing := missingColumns(header); len(missing) > 0 {
+				errs = append(errs, LineError{Line: line, Error: "missing columns: " + strings.Join(missing, ", ")})
+				skip = true
+			}
+		case skip:
+		default:
+			values := splitPSV(text)
+			if len(values) != len(header) {
+				errs = append(errs, LineError{Line: line, Error: fmt.Sprintf("expected %d fields, got %d", len(header), len(values))})
+				continue
+			}
+			var rec record
+			for i, name := range header {
+				if f := fieldOf(&rec, name); f != nil {
+					*f = values[i]
+				}
+			}
+			o, err := rec.observation(line)
+			if err != nil {
+				errs = append(errs, LineError{Line: line, Error: err.Error()})
+				continue
+			}
+			out = append(out, o)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return out, err
+	}
+	if len(errs) > 0 {
+		return out, errs
+	}
+	return out, nil
+}
+
+// WritePSV записывает блоки наблюдений в формате PSV. Столбцы выравниваются по ширине;
+// необязательные столбцы выводятся, только если заполнены хотя бы у одного наблюдения блока.
+func WritePSV(w io.Writer, blocks []Block) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# version=%s\n", Version)
+	for _, b := range blocks {
+		writeContextPSV(bw, b.Context)
+
+		records := make([]record, len(b.Observations))
+		for i, o := range b.Observations {
+			records[i] = newRecord(o)
+		}
+		var (
+			cols   []column
+			widths []int
+		)
+		for _, c := range columns {
+			used, width := c.required, len(c.name)
+			for i := range records {
+				v := *c.field(&records[i])
+				used = used || v != ""
+				width = max(width, len(v))
+			}
+			if used {
+				cols = append(cols, c)
+				widths = append(widths, width)
+			}
+		}
+
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.name
+		}
+		writeRowPSV(bw, names, widths)
+		values := make([]string, len(cols))
+		for k := range records {
+			for i, c := range cols {
+				values[i] = *c.field(&records[k])
+			}
+			writeRowPSV(bw, values, widths)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeContextPSV записывает заголовок блока: разделы «#» со значениями «!».
+func writeContextPSV(w *bufio.Writer, c Context) {
+	fmt.Fprintf(w, "# observatory\n! mpcCode %s\n", c.Observatory)
+	if c.Submitter != "" {
+		fmt.Fprintf(w, "# submitter\n! name %s\n", c.Submitter)
+	}
+	writeNamesPSV(w, "observers", c.Observers)
+	writeNamesPSV(w, "measurers", c.Measurers)
+	if t := c.Telescope; t != nil {
+		fmt.Fprintln(w, "# telescope")
+		if t.Design != "" {
+			fmt.Fprintf(w, "! design %s\n", t.Design)
+		}
+		if t.Aperture > 0 {
+			fmt.Fprintf(w, "! aperture %s\n", strconv.FormatFloat(t.Aperture, 'f', -1, 64))
+		}
+		if t.Detector != "" {
+			fmt.Fprintf(w, "! detector %s\n", t.Detector)
+		}
+	}
+}
+
+func writeNamesPSV(w *bufio.Writer, section string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# %s\n", section)
+	for _, n := range names {
+		fmt.Fprintf(w, "! name %s\n", n)
+	}
+}
+
+// writeRowPSV записывает строку значений, дополняя их пробелами до ширины столбцов.
+func writeRowPSV(w *bufio.Writer, values []string, widths []int) {
+	for i, v := range values {
+		if i > 0 {
+			w.WriteByte('|')
+		}
+		if i == len(values)-1 {
+			w.WriteString(v)
+			break
+		}
+		fmt.Fprintf(w, "%-*s", widths[i], v)
+	}
+	w.WriteByte('\n')
+}
+
+func splitPSV(s string) []string {
+	fields := strings.Split(s, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// fieldOf возвращает поле записи для столбца name или nil, если столбец не поддерживается.
+func fieldOf(r *record, name string) *string {
+	for _, c := range columns {
+		if c.name == name {
+			return c.field(r)
+		}
+	}
+	return nil
+}
+
+func missingColumns(header []string) []string {
+	var missing []string
+	for _, name := range psvRequired {
+		found := false
+		for _, h := range header {
+			if h == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
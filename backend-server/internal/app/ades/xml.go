@@ -0,0 +1,126 @@
+package ades
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlDocument — корневой элемент документа ADES.
+type xmlDocument struct {
+	XMLName xml.Name   `xml:"ades"`
+	Version string     `xml:"version,attr"`
+	Blocks  []xmlBlock `xml:"obsBlock"`
+}
+
+type xmlBlock struct {
+	Context xmlContext `xml:"obsContext"`
+	Optical []record   `xml:"obsData>optical"`
+}
+
+type xmlContext struct {
+	Observatory struct {
+		MPCCode string `xml:"mpcCode"`
+	} `xml:"observatory"`
+	Submitter *xmlNames     `xml:"submitter"`
+	Observers *xmlNames     `xml:"observers"`
+	Measurers *xmlNames     `xml:"measurers"`
+	Telescope *xmlTelescope `xml:"telescope"`
+}
+
+type xmlNames struct {
+	Names []string `xml:"name"`
+}
+
+type xmlTelescope struct {
+	Design   string `xml:"design,omitempty"`
+	Aperture string `xml:"aperture,omitempty"`
+	Detector string `xml:"detector,omitempty"`
+}
+
+// ParseXML разбирает документ ADES в формате XML — все элементы <optical> независимо
+// от блоков, в которых они находятся. Ошибки отдельных наблюдений возвращаются как
+// ParseError вместе с успешно разобранными наблюдениями; синтаксическая ошибка XML
+// прерывает разбор.
+func ParseXML(r io.Reader) ([]Observation, error) {
+	var (
+		out  []Observation
+		errs ParseError
+	)
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line, _ := d.InputPos()
+			return out, append(errs, LineError{Line: line, Error: err.Error()})
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "optical" {
+			continue
+		}
+
+		line, _ := d.InputPos()
+		var rec record
+		if err := d.DecodeElement(&rec, &start); err != nil {
+			return out, append(errs, LineError{Line: line, Error: err.Error()})
+		}
+		for _, c := range columns {
+			f := c.field(&rec)
+			*f = strings.TrimSpace(*f)
+		}
+		o, err := rec.observation(line)
+		if err != nil {
+			errs = append(errs, LineError{Line: line, Error: err.Error()})
+			continue
+		}
+		out = append(out, o)
+	}
+	if len(errs) > 0 {
+		return out, errs
+	}
+	return out, nil
+}
+
+// WriteXML записывает блоки наблюдений документом ADES в формате XML.
+func WriteXML(w io.Writer, blocks []Block) error {
+	doc := xmlDocument{Version: Version, Blocks: make([]xmlBlock, len(blocks))}
+	for i, b := range blocks {
+		xb := &doc.Blocks[i]
+		xb.Context.Observatory.MPCCode = b.Context.Observatory
+		if b.Context.Submitter != "" {
+			xb.Context.Submitter = &xmlNames{Names: []string{b.Context.Submitter}}
+		}
+		if len(b.Context.Observers) > 0 {
+			xb.Context.Observers = &xmlNames{Names: b.Context.Observers}
+		}
+		if len(b.Context.Measurers) > 0 {
+			xb.Context.Measurers = &xmlNames{Names: b.Context.Measurers}
+		}
+		if t := b.Context.Telescope; t != nil {
+			xb.Context.Telescope = &xmlTelescope{Design: t.Design, Detector: t.Detector}
+			if t.Aperture > 0 {
+				xb.Context.Telescope.Aperture = strconv.FormatFloat(t.Aperture, 'f', -1, 64)
+			}
+		}
+		xb.Optical = make([]record, len(b.Observations))
+		for k, o := range b.Observations {
+			xb.Optical[k] = newRecord(o)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,241 @@
+// Package approach ищет сближения кометы с телом Солнечной системы.
+//
+// Расстояние до тела вычисляется на равномерной сетке с шагом Options.Step; интервал
+// сетки, на котором скорость изменения расстояния (проекция относительной скорости на
+// направление на тело) меняет знак с минуса на плюс, содержит минимум расстояния.
+// Момент минимума уточняется методом Брента как корень скорости изменения расстояния
+// с точностью TimeTolerance — около секунды, так что пролёт не пропускается между
+// узлами сетки и время сближения определяется точнее минуты.
+package approach
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+const (
+	// DefaultStep — шаг сетки по умолчанию, сутки. Сетка должна быть мельче промежутка
+	// между соседними минимумом и максимумом расстояния; для комет и планет земной группы
+	// это недели.
+	DefaultStep = 2.0
+	// TimeTolerance — точность момента сближения, сутки (около 1 с).
+	TimeTolerance = 1e-5
+
+	maxIterations = 100
+	reportNodes   = 50      // узлов сетки между вызовами Options.Progress
+	eps           = 0x1p-52 // машинная точность float64
+)
+
+var errNoConvergence = errors.New("approach: root finding did not converge")
+
+// Target возвращает гелиоцентрическое состояние тела, к которому ищется сближение,
+// в экваториальной системе J2000 на момент jd (TT).
+type Target func(jd float64) (orbit.State, error)
+
+// BodyTarget возвращает Target для тела b из источника src.
+func BodyTarget(src planets.Source, b planets.Body) Target {
+	return func(jd float64) (orbit.State, error) { return src.State(b, jd) }
+}
+
+// ParseBodies разбирает названия тел, с которыми ищутся сближения: планет и Луны
+// (planets.ParseBody). Повторы отбрасываются, порядок сохраняется.
+func ParseBodies(names []string) ([]planets.Body, error) {
+	bodies := make([]planets.Body, 0, len(names))
+	seen := make(map[planets.Body]bool, len(names))
+	for _, name := range names {
+		b, err := planets.ParseBody(name)
+		if err != nil {
+			return nil, err
+		}
+		if b == planets.BodySun {
+			return nil, fmt.Errorf("close approaches to the %s are not searched", b)
+		}
+		if !seen[b] {
+			seen[b] = true
+			bodies = append(bodies, b)
+		}
+	}
+	return bodies, nil
+}
+
+// Options задаёт параметры поиска. Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	Step          float64 // шаг сетки, сутки (DefaultStep)
+	MaxDistanceAU float64 // в Result.Approaches попадают сближения не дальше, а.е.; 0 — все
+	// Progress, если задан, вызывается по ходу поиска с долей пройденного интервала 0…1.
+	Progress func(fraction float64)
+}
+
+// Approach — сближение: локальный минимум расстояния до тела.
+type Approach struct {
+	JD         float64 // момент, JD (TT)
+	DistanceAU float64 // расстояние, а.е.
+	Velocity   float64 // относительная скорость, а.е./сут
+}
+
+// Result — итог поиска.
+type Result struct {
+	// Approaches — сближения не дальше Options.MaxDistanceAU в порядке времени.
+	Approaches []Approach
+	// Closest — наименьшее расстояние на интервале независимо от порога; может
+	// приходиться на край интервала, если минимум лежит за его пределами.
+	Closest Approach
+}
+
+// Window возвращает интервал поиска сближения (JD TT) длительностью years лет от момента
+// перигелия. Незамкнутая или долгопериодическая орбита проходит мимо планет за интервал
+// один раз, и сближение может случиться до перигелия, поэтому для неё интервал
+// продлевается на столько же лет назад.
+func Window(el orbit.Elements, years float64) (start, end float64) {
+	span := years * 365.25
+	if el.Period(orbit.MuSun) > span {
+		return el.Tp - span, el.Tp + span
+	}
+	return el.Tp, el.Tp + span
+}
+
+// sample — относительное положение кометы в момент jd.
+type sample struct {
+	jd       float64
+	distance float64 // а.е.
+	rate     float64 // скорость изменения расстояния, а.е./сут
+	speed    float64 // модуль относительной скорости, а.е./сут
+}
+
+func (s sample) approach() Approach {
+	return Approach{JD: s.jd, DistanceAU: s.distance, Velocity: s.speed}
+}
+
+// Find ищет сближения кометы comet с телом target на интервале [start, end] (JD TT).
+func Find(comet orbit.Propagator, target Target, start, end float64, opts Options) (*Result, error) {
+	if !(end > start) {
+		return nil, fmt.Errorf("approach: empty interval %.1f–%.1f", start, end)
+	}
+	step := opts.Step
+	if step <= 0 {
+		step = DefaultStep
+	}
+	nodes := int(math.Ceil((end - start) / step))
+	step = (end - start) / float64(nodes)
+
+	at := func(jd float64) (sample, error) {
+		c, err := comet.At(jd)
+		if err != nil {
+			return sample{}, err
+		}
+		t, err := target(jd)
+		if err != nil {
+			return sample{}, err
+		}
+		r, v := c.R.Sub(t.R), c.V.Sub(t.V)
+		d := r.Norm()
+		if d == 0 {
+			return sample{jd: jd, speed: v.Norm()}, nil
+		}
+		return sample{jd: jd, distance: d, rate: r.Dot(v) / d, speed: v.Norm()}, nil
+	}
+
+	prev, err := at(start)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Closest: prev.approach()}
+	for k := 1; k <= nodes; k++ {
+		if opts.Progress != nil && k%reportNodes == 0 {
+			opts.Progress(float64(k) / float64(nodes))
+		}
+		jd := start + float64(k)*step
+		if k == nodes {
+			jd = end
+		}
+		cur, err := at(jd)
+		if err != nil {
+			return nil, err
+		}
+		if prev.rate < 0 && cur.rate >= 0 {
+			m, err := refine(at, prev, cur)
+			if err != nil {
+				return nil, err
+			}
+			a := m.approach()
+			if opts.MaxDistanceAU <= 0 || a.DistanceAU <= opts.MaxDistanceAU {
+				res.Approaches = append(res.Approaches, a)
+			}
+			if a.DistanceAU < res.Closest.DistanceAU {
+				res.Closest = a
+			}
+		}
+		if cur.distance < res.Closest.DistanceAU {
+			res.Closest = cur.approach()
+		}
+		prev = cur
+	}
+	if opts.Progress != nil {
+		opts.Progress(1)
+	}
+	return res, nil
+}
+
+// refine находит методом Брента момент, в который скорость изменения расстояния
+// обращается в ноль, между a (rate < 0) и b (rate ≥ 0).
+func refine(at func(jd float64) (sample, error), a, b sample) (sample, error) {
+	if b.rate == 0 {
+		return b, nil
+	}
+	c := a
+	d := b.jd - a.jd
+	e := d
+	for range maxIterations {
+		if (b.rate > 0) == (c.rate > 0) {
+			c = a
+			d = b.jd - a.jd
+			e = d
+		}
+		if math.Abs(c.rate) < math.Abs(b.rate) {
+			a, b, c = b, c, b
+		}
+		tol := 2*eps*math.Abs(b.jd) + TimeTolerance/2
+		half := (c.jd - b.jd) / 2
+		if math.Abs(half) <= tol || b.rate == 0 {
+			return b, nil
+		}
+		if math.Abs(e) >= tol && math.Abs(a.rate) > math.Abs(b.rate) {
+			// обратная квадратичная интерполяция, при двух точках — секущая
+			var p, q float64
+			s := b.rate / a.rate
+			if a.jd == c.jd {
+				p, q = 2*half*s, 1-s
+			} else {
+				r := b.rate / c.rate
+				q = a.rate / c.rate
+				p = s * (2*half*q*(q-r) - (b.jd-a.jd)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			}
+			p = math.Abs(p)
+			if 2*p < math.Min(3*half*q-math.Abs(tol*q), math.Abs(e*q)) {
+				e, d = d, p/q
+			} else {
+				d, e = half, half
+			}
+		} else {
+			d, e = half, half
+		}
+		a = b
+		next := b.jd + math.Copysign(tol, half)
+		if math.Abs(d) > tol {
+			next = b.jd + d
+		}
+		var err error
+		if b, err = at(next); err != nil {
+			return sample{}, err
+		}
+	}
+	return sample{}, errNoConvergence
+}
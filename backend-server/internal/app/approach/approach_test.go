@@ -0,0 +1,272 @@
+package approach
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// propagatorFunc — orbit.Propagator из функции.
+type propagatorFunc func(jd float64) (orbit.State, error)
+
+func (f propagatorFunc) At(jd float64) (orbit.State, error) { return f(jd) }
+
+// linear — равномерное прямолинейное движение через точку r0 в момент t0.
+func linear(r0, v orbit.Vec3, t0 float64) func(jd float64) (orbit.State, error) {
+	return func(jd float64) (orbit.State, error) {
+		return orbit.State{R: r0.Add(v.Scale(jd - t0)), V: v}, nil
+	}
+}
+
+// circular — движение по окружности радиуса radius в плоскости xy с периодом period,
+// в момент t0 тело на оси x.
+func circular(radius, period, t0 float64) func(jd float64) (orbit.State, error) {
+	return func(jd float64) (orbit.State, error) {
+		w := 2 * math.Pi / period
+		a := w * (jd - t0)
+		return orbit.State{
+			R: orbit.Vec3{radius * math.Cos(a), radius * math.Sin(a), 0},
+			V: orbit.Vec3{-radius * w * math.Sin(a), radius * w * math.Cos(a), 0},
+		}, nil
+	}
+}
+
+func TestFindKnownFlybys(t *testing.T) {
+	const t0 = 2460000.5
+
+	// пролёт по прямой мимо неподвижного тела: минимум в t0 + 3.3 сут на расстоянии 0.02 а.е.
+	flybyV := orbit.Vec3{0.01, 0.003, -0.002}
+	flybyMiss := orbit.Vec3{0.003, -0.01, 0}.Scale(0.02 / orbit.Vec3{0.003, -0.01, 0}.Norm())
+	flybyT := t0 + 3.3
+	flyby := linear(flybyMiss.Sub(flybyV.Scale(flybyT-t0)), flybyV, t0)
+
+	tests := []struct {
+		name       string
+		comet      func(float64) (orbit.State, error)
+		target     func(float64) (orbit.State, error)
+		start, end float64
+		opts       Options
+		want       []Approach
+		closest    Approach
+	}{
+		{
+			name:    "straight flyby",
+			comet:   flyby,
+			target:  linear(orbit.Vec3{}, orbit.Vec3{}, t0),
+			start:   t0 - 30,
+			end:     t0 + 30,
+			want:    []Approach{{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()}},
+			closest: Approach{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()},
+		},
+		{
+			// минимум между узлами крупной сетки находится так же точно
+			name:    "straight flyby, coarse grid",
+			comet:   flyby,
+			target:  linear(orbit.Vec3{}, orbit.Vec3{}, t0),
+			start:   t0 - 30,
+			end:     t0 + 30,
+			opts:    Options{Step: 7},
+			want:    []Approach{{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()}},
+			closest: Approach{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()},
+		},
+		{
+			// тело и комета движутся, важна только относительная скорость
+			name: "moving target",
+			comet: func(jd float64) (orbit.State, error) {
+				return addStates(flyby, linear(orbit.Vec3{1, 0, 0}, orbit.Vec3{0, 0.017, 0}, t0), jd)
+			},
+			target:  linear(orbit.Vec3{1, 0, 0}, orbit.Vec3{0, 0.017, 0}, t0),
+			start:   t0 - 30,
+			end:     t0 + 30,
+			want:    []Approach{{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()}},
+			closest: Approach{JD: flybyT, DistanceAU: 0.02, Velocity: flybyV.Norm()},
+		},
+		{
+			// тело обходит неподвижную комету по окружности: сближения раз в период
+			name:   "periodic approaches",
+			comet:  linear(orbit.Vec3{1.1, 0, 0}, orbit.Vec3{}, t0),
+			target: circular(1, 100, t0),
+			start:  t0 - 10,
+			end:    t0 + 250,
+			want: []Approach{
+				{JD: t0, DistanceAU: 0.1, Velocity: 2 * math.Pi / 100},
+				{JD: t0 + 100, DistanceAU: 0.1, Velocity: 2 * math.Pi / 100},
+				{JD: t0 + 200, DistanceAU: 0.1, Velocity: 2 * math.Pi / 100},
+			},
+			closest: Approach{JD: t0, DistanceAU: 0.1, Velocity: 2 * math.Pi / 100},
+		},
+		{
+			name:    "approaches beyond the threshold",
+			comet:   linear(orbit.Vec3{1.1, 0, 0}, orbit.Vec3{}, t0),
+			target:  circular(1, 100, t0),
+			start:   t0 - 10,
+			end:     t0 + 250,
+			opts:    Options{MaxDistanceAU: 0.05},
+			want:    nil,
+			closest: Approach{JD: t0, DistanceAU: 0.1, Velocity: 2 * math.Pi / 100},
+		},
+		{
+			// минимум до начала интервала: ближайшая точка — его край, сближений нет
+			name:    "minimum before the interval",
+			comet:   flyby,
+			target:  linear(orbit.Vec3{}, orbit.Vec3{}, t0),
+			start:   flybyT + 1,
+			end:     flybyT + 20,
+			want:    nil,
+			closest: Approach{JD: flybyT + 1, DistanceAU: math.Hypot(0.02, flybyV.Norm()), Velocity: flybyV.Norm()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Find(propagatorFunc(tt.comet), tt.target, tt.start, tt.end, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Approaches) != len(tt.want) {
+				t.Fatalf("got %d approaches %+v, want %d", len(res.Approaches), res.Approaches, len(tt.want))
+			}
+			for i, a := range res.Approaches {
+				checkApproach(t, "approach", a, tt.want[i])
+			}
+			checkApproach(t, "closest", res.Closest, tt.closest)
+		})
+	}
+}
+
+func addStates(a, b func(float64) (orbit.State, error), jd float64) (orbit.State, error) {
+	sa, err := a(jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	sb, err := b(jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	return orbit.State{R: sa.R.Add(sb.R), V: sa.V.Add(sb.V)}, nil
+}
+
+func checkApproach(t *testing.T, what string, got, want Approach) {
+	t.Helper()
+	if math.Abs(got.JD-want.JD) > TimeTolerance {
+		t.Errorf("%s at JD %.7f, want %.7f (off by %.2f s)", what, got.JD, want.JD, (got.JD-want.JD)*86400)
+	}
+	if math.Abs(got.DistanceAU-want.DistanceAU) > 1e-9 {
+		t.Errorf("%s distance %.10f AU, want %.10f", what, got.DistanceAU, want.DistanceAU)
+	}
+	if math.Abs(got.Velocity-want.Velocity) > 1e-9 {
+		t.Errorf("%s velocity %.10f AU/d, want %.10f", what, got.Velocity, want.Velocity)
+	}
+}
+
+// Метод Брента сходится за несколько вычислений, а не делением пополам до секунды.
+func TestRefineEvaluations(t *testing.T) {
+	const t0 = 2460000.5
+	comet := linear(orbit.Vec3{0, 0.05, 0}, orbit.Vec3{0.02, 0, 0}, t0)
+	target := linear(orbit.Vec3{}, orbit.Vec3{}, t0)
+
+	calls := 0
+	counted := propagatorFunc(func(jd float64) (orbit.State, error) {
+		calls++
+		return comet(jd)
+	})
+	// один интервал сетки шириной 20 сут: деление пополам до 1e-5 сут заняло бы 21 шаг
+	res, err := Find(counted, target, t0-7, t0+13, Options{Step: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkApproach(t, "approach", res.Closest, Approach{JD: t0, DistanceAU: 0.05, Velocity: 0.02})
+	if refineCalls := calls - 2; refineCalls > 12 {
+		t.Errorf("refinement took %d evaluations", refineCalls)
+	}
+}
+
+func TestFindProgressAndErrors(t *testing.T) {
+	const t0 = 2460000.5
+	comet := propagatorFunc(linear(orbit.Vec3{1, 0, 0}, orbit.Vec3{0, 0.01, 0}, t0))
+	target := Target(linear(orbit.Vec3{}, orbit.Vec3{}, t0))
+
+	var fractions []float64
+	if _, err := Find(comet, target, t0, t0+400, Options{Step: 1, Progress: func(f float64) { fractions = append(fractions, f) }}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fractions) < 2 || fractions[len(fractions)-1] != 1 {
+		t.Errorf("progress %v, want it to end at 1", fractions)
+	}
+	for i := 1; i < len(fractions); i++ {
+		if fractions[i] < fractions[i-1] || fractions[i] > 1 {
+			t.Errorf("progress %v is not increasing within [0, 1]", fractions)
+			break
+		}
+	}
+
+	if _, err := Find(comet, target, t0, t0, Options{}); err == nil {
+		t.Error("empty interval: expected an error")
+	}
+
+	outOfRange := errors.New("outside the ephemeris")
+	failing := Target(func(jd float64) (orbit.State, error) {
+		if jd > t0+100 {
+			return orbit.State{}, outOfRange
+		}
+		return target(jd)
+	})
+	if _, err := Find(comet, failing, t0, t0+200, Options{}); !errors.Is(err, outOfRange) {
+		t.Errorf("err = %v, want %v", err, outOfRange)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	const tp = 2460000.5
+	tests := []struct {
+		name       string
+		el         orbit.Elements
+		years      float64
+		start, end float64
+	}{
+		{"short period", orbit.ElementsFromDegrees(0.336, 0.848, 11.8, 334.6, 186.5, tp), 5, tp, tp + 5*365.25},
+		{"period longer than the window", orbit.ElementsFromDegrees(0.336, 0.848, 11.8, 334.6, 186.5, tp), 2, tp - 2*365.25, tp + 2*365.25},
+		{"parabolic", orbit.ElementsFromDegrees(1.2, 1, 45, 10, 20, tp), 5, tp - 5*365.25, tp + 5*365.25},
+		{"hyperbolic", orbit.ElementsFromDegrees(1.2, 1.3, 45, 10, 20, tp), 5, tp - 5*365.25, tp + 5*365.25},
+	}
+	for _, tt := range tests {
+		start, end := Window(tt.el, tt.years)
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s: window %.2f–%.2f, want %.2f–%.2f", tt.name, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseBodies(t *testing.T) {
+	tests := []struct {
+		names   []string
+		want    []planets.Body
+		wantErr bool
+	}{
+		{nil, []planets.Body{}, false},
+		{[]string{"earth"}, []planets.Body{planets.BodyEarth}, false},
+		{[]string{" Mars ", "earth", "MARS", "moon"}, []planets.Body{planets.BodyMars, planets.BodyEarth, planets.BodyMoon}, false},
+		{[]string{"earth", "sun"}, nil, true},
+		{[]string{"pluto"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBodies(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBodies(%q) error = %v, want error %v", tt.names, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseBodies(%q) = %v, want %v", tt.names, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseBodies(%q) = %v, want %v", tt.names, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package astrotime переводит моменты времени между шкалами UTC и TT
+// и юлианскими датами, которые используются в расчётах орбит.
+package astrotime
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// J2000 — юлианская дата эпохи J2000.0 (2000-01-01 12:00 TT).
+	J2000 = 2451545.0
+	// MJDOffset — разность между юлианской и модифицированной юлианской датой.
+	MJDOffset = 2400000.5
+	// DaysPerCentury — число суток в юлианском столетии.
+	DaysPerCentury = 36525.0
+
+	// ttMinusTAI — постоянная разность TT − TAI в секундах.
+	ttMinusTAI = 32.184
+	// unixEpochJD — юлианская дата 1970-01-01 00:00 UTC.
+	unixEpochJD   = 2440587.5
+	secondsPerDay = 86400.0
+)
+
+// leapSecond описывает значение TAI − UTC, действующее с указанной даты.
+type leapSecond struct {
+	since  time.Time
+	offset float64
+}
+
+// leapSeconds — таблица IERS TAI − UTC. Последнее изменение — 2017-01-01.
+var leapSeconds = []leapSecond{
+	{time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC), 10},
+	{time.Date(1972, 7, 1, 0, 0, 0, 0, time.UTC), 11},
+	{time.Date(1973, 1, 1, 0, 0, 0, 0, time.UTC), 12},
+	{time.Date(1974, 1, 1, 0, 0, 0, 0, time.UTC), 13},
+	{time.Date(1975, 1, 1, 0, 0, 0, 0, time.UTC), 14},
+	{time.Date(1976, 1, 1, 0, 0, 0, 0, time.UTC), 15},
+	{time.Date(1977, 1, 1, 0, 0, 0, 0, time.UTC), 16},
+	{time.Date(1978, 1, 1, 0, 0, 0, 0, time.UTC), 17},
+	{time.Date(1979, 1, 1, 0, 0, 0, 0, time.UTC), 18},
+	{time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), 19},
+	{time.Date(1981, 7, 1, 0, 0, 0, 0, time.UTC), 20},
+	{time.Date(1982, 7, 1, 0, 0, 0, 0, time.UTC), 21},
+	{time.Date(1983, 7, 1, 0, 0, 0, 0, time.UTC), 22},
+	{time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC), 23},
+	{time.Date(1988, 1, 1, 0, 0, 0, 0, time.UTC), 24},
+	{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), 25},
+	{time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), 26},
+	{time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC), 27},
+	{time.Date(1993, 7, 1, 0, 0, 0, 0, time.UTC), 28},
+	{time.Date(1994, 7, 1, 0, 0, 0, 0, time.UTC), 29},
+	{time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC), 30},
+	{time.Date(1997, 7, 1, 0, 0, 0, 0, time.UTC), 31},
+	{time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), 32},
+	{time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), 33},
+	{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), 34},
+	{time.Date(2012, 7, 1, 0, 0, 0, 0, time.UTC), 35},
+	{time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 36},
+	{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 37},
+}
+
+// TAIMinusUTC возвращает число секунд TAI − UTC на момент t.
+// До 1972 года используется начальное значение 10 с.
+func TAIMinusUTC(t time.Time) float64 {
+	offset := leapSeconds[0].offset
+	for _, ls := range leapSeconds {
+		if t.Before(ls.since) {
+			break
+		}
+		offset = ls.offset
+	}
+	return offset
+}
+
+// TTMinusUTC возвращает число секунд TT − UTC на момент t.
+func TTMinusUTC(t time.Time) float64 {
+	return TAIMinusUTC(t) + ttMinusTAI
+}
+
+// JulianDate возвращает юлианскую дату момента t без смены шкалы времени.
+func JulianDate(t time.Time) float64 {
+	return unixEpochJD + float64(t.UnixNano())/1e9/secondsPerDay
+}
+
+// FromJulianDate возвращает момент времени, соответствующий юлианской дате jd
+// в той же шкале. Точность — микросекунды.
+func FromJulianDate(jd float64) time.Time {
+	days := jd - unixEpochJD
+	whole := math.Floor(days)
+	micros := math.Round((days - whole) * secondsPerDay * 1e6)
+	return time.Unix(int64(whole)*int64(secondsPerDay), 0).Add(time.Duration(micros) * time.Microsecond).UTC()
+}
+
+// MJD возвращает модифицированную юлианскую дату момента t.
+func MJD(t time.Time) float64 {
+	return JulianDate(t) - MJDOffset
+}
+
+// TT возвращает юлианскую дату в шкале TT для момента t, заданного в UTC.
+// Разность TDB − TT (менее 2 мс) не учитывается.
+func TT(t time.Time) float64 {
+	return JulianDate(t) + TTMinusUTC(t)/secondsPerDay
+}
+
+// UTCFromTT возвращает момент UTC для юлианской даты jdTT в шкале TT.
+func UTCFromTT(jdTT float64) time.Time {
+	approx := FromJulianDate(jdTT - (37+ttMinusTAI)/secondsPerDay)
+	return FromJulianDate(jdTT - (TAIMinusUTC(approx)+ttMinusTAI)/secondsPerDay)
+}
+
+// CenturiesSinceJ2000 возвращает число юлианских столетий от J2000.0 до jd.
+func CenturiesSinceJ2000(jd float64) float64 {
+	return (jd - J2000) / DaysPerCentury
+}
+
+// GMST возвращает среднее гринвичское звёздное время (IAU 1982, рад) для момента t в UTC.
+// Разность UT1 − UTC (менее 0.9 с) не учитывается.
+func GMST(t time.Time) float64 {
+	jd := JulianDate(t)
+	c := CenturiesSinceJ2000(jd)
+	deg := 280.46061837 + 360.98564736629*(jd-J2000) + 0.000387933*c*c - c*c*c/38710000
+	rad := math.Mod(deg, 360) * math.Pi / 180
+	if rad < 0 {
+		rad += 2 * math.Pi
+	}
+	return rad
+}
@@ -35,6 +35,41 @@ type RedisConfig struct {
 	ReadTimeout time.Duration
 }
 
+// OrbitConfig задаёт бэкенд расчёта орбит.
+// Backend, Shadow и Fallback принимают значения "python" или "go".
+// Workers — число обработчиков асинхронных задач, JobAttempts — сколько раз задача запускается
+// до отказа; оба не меньше 1.
+// RiskWorkers — размер пула горутин оценки риска сближения (0 — по числу CPU).
+// ApproachYears — длительность поиска сближений после расчёта орбиты от момента перигелия, годы;
+// ApproachBodies — тела, сближения с которыми ищутся и сохраняются (planets.ParseBody);
+// сохраняются сближения не дальше ApproachMaxAU а.е. или ApproachMaxHill радиусов сферы Хилла
+// тела (оба 0 — все минимумы). JupiterMOID — вычислять ли, кроме MOID с Землёй, MOID с Юпитером.
+type OrbitConfig struct {
+	Backend     string
+	Shadow      string
+	Fallback    string
+	URL         string
+	Timeout     time.Duration
+	Retries     int
+	Workers     int
+	JobAttempts int
+	RiskWorkers int
+
+	ApproachYears   float64
+	ApproachBodies  []string
+	ApproachMaxAU   float64
+	ApproachMaxHill float64
+	JupiterMOID     bool
+}
+
+// EphemerisConfig задаёт файл эфемериды JPL: ядро SPK (de440s.bsp) или двоичный файл DE.
+// Path — путь к файлу, пустой — эфемерида не загружается. CacheSize — число наборов
+// коэффициентов в LRU-кэше (0 — jplde.DefaultCacheSize).
+type EphemerisConfig struct {
+	Path      string
+	CacheSize int
+}
+
 // Config объединяет все настройки приложения.
 type Config struct {
 	ServiceHost string
@@ -42,6 +77,8 @@ type Config struct {
 	Minio       MinioConfig
 	Redis       RedisConfig
 	JWT         JWTConfig
+	Orbit       OrbitConfig
+	Ephemeris   EphemerisConfig
 }
 
 // NewConfig загружает конфигурацию приложения из .env и TOML-файла.
@@ -59,6 +96,18 @@ func NewConfig() (*Config, error) {
 	viper.AddConfigPath(".")
 	viper.WatchConfig()
 
+	viper.SetDefault("orbit.backend", "python")
+	viper.SetDefault("orbit.url", "http://localhost:8000/calculate-orbit")
+	viper.SetDefault("orbit.timeout", "120s")
+	viper.SetDefault("orbit.retries", 2)
+	viper.SetDefault("orbit.workers", 2)
+	viper.SetDefault("orbit.job_attempts", 3)
+	viper.SetDefault("orbit.approach_years", 5)
+	viper.SetDefault("orbit.approach_bodies", []string{"earth"})
+	viper.SetDefault("orbit.approach_max_au", 0.2)
+	viper.SetDefault("orbit.approach_max_hill", 1)
+	viper.SetDefault("orbit.jupiter_moid", true)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
@@ -93,5 +142,37 @@ func NewConfig() (*Config, error) {
 		ReadTimeout: 3 * time.Second,
 	}
 
+	cfg.Orbit = OrbitConfig{
+		Backend:     viper.GetString("orbit.backend"),
+		Shadow:      viper.GetString("orbit.shadow"),
+		Fallback:    viper.GetString("orbit.fallback"),
+		URL:         viper.GetString("orbit.url"),
+		Timeout:     viper.GetDuration("orbit.timeout"),
+		Retries:     viper.GetInt("orbit.retries"),
+		Workers:     viper.GetInt("orbit.workers"),
+		JobAttempts: viper.GetInt("orbit.job_attempts"),
+		RiskWorkers: viper.GetInt("orbit.risk_workers"),
+
+		ApproachYears:   viper.GetFloat64("orbit.approach_years"),
+		ApproachBodies:  viper.GetStringSlice("orbit.approach_bodies"),
+		ApproachMaxAU:   viper.GetFloat64("orbit.approach_max_au"),
+		ApproachMaxHill: viper.GetFloat64("orbit.approach_max_hill"),
+		JupiterMOID:     viper.GetBool("orbit.jupiter_moid"),
+	}
+	// без воркера задачи не выполняются, без попытки — сразу завершаются ошибкой
+	cfg.Orbit.Workers = max(cfg.Orbit.Workers, 1)
+	cfg.Orbit.JobAttempts = max(cfg.Orbit.JobAttempts, 1)
+	if url := os.Getenv("ORBIT_SERVICE_URL"); url != "" {
+		cfg.Orbit.URL = url
+	}
+
+	cfg.Ephemeris = EphemerisConfig{
+		Path:      viper.GetString("ephemeris.path"),
+		CacheSize: viper.GetInt("ephemeris.cache_size"),
+	}
+	if path := os.Getenv("EPHEMERIS_PATH"); path != "" {
+		cfg.Ephemeris.Path = path
+	}
+
 	return cfg, nil
 }
@@ -6,13 +6,16 @@ import (
 	"gorm.io/gorm"
 )
 
-// CloseApproach хранит результаты расчёта минимального сближения с Землей
+// CloseApproach хранит одно сближение кометы с планетой или Луной — локальный минимум расстояния
 type CloseApproach struct {
-	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	CometID     uint           `gorm:"not null;index" json:"comet_id"` // Ссылка на комету
-	ClosestDate time.Time      `gorm:"not null" json:"closest_date"`   // Дата минимального сближения
-	DistanceAU  float64        `gorm:"not null" json:"distance_au"`    // Расстояние до Земли (AU)
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                  uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	CometID             uint           `gorm:"not null;index" json:"comet_id"`                     // Ссылка на комету
+	Body                string         `gorm:"size:16;not null;default:'earth';index" json:"body"` // Тело сближения (earth, mars, jupiter, ...)
+	ClosestDate         time.Time      `gorm:"not null" json:"closest_date"`                       // Дата минимального сближения
+	DistanceAU          float64        `gorm:"not null" json:"distance_au"`                        // Расстояние до тела (AU)
+	DistanceHill        *float64       `json:"distance_hill"`                                      // Расстояние в радиусах сферы Хилла тела
+	RelativeVelocityKmS *float64       `json:"relative_velocity_km_s"`                             // Относительная скорость в момент сближения (км/с)
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
 }
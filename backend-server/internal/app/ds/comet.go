@@ -8,16 +8,32 @@ import (
 
 // Comet представляет комету и её орбитальные параметры
 type Comet struct {
-	ID              uint            `gorm:"primaryKey;autoIncrement" json:"id"` // Уникальный идентификатор
-	Name            string          `gorm:"type:text;not null" json:"name"`     // Имя кометы
-	ImageURL        string          `gorm:"type:text" json:"image_url"`         // Ссылка на изображение в Minio
-	Epoch           time.Time       `gorm:"not null" json:"epoch"`              // Эпоха орбиты
-	A               float64         `gorm:"not null" json:"a"`                  // Большая полуось (AU)
-	E               float64         `gorm:"not null" json:"e"`                  // Эксцентриситет
-	I               float64         `gorm:"not null" json:"i"`                  // Наклонение орбиты (deg)
-	Node            float64         `gorm:"not null" json:"Node"`               // Долгота восходящего узла (deg)
-	ArgPeri         float64         `gorm:"not null" json:"ArgPeri"`            // Аргумент перицентра (deg)
-	T               time.Time       `gorm:"not null" json:"T"`                  // Время прохождения перигелия
+	ID              uint            `gorm:"primaryKey;autoIncrement" json:"id"`           // Уникальный идентификатор
+	Name            string          `gorm:"type:text;not null" json:"name"`               // Имя кометы
+	ImageURL        string          `gorm:"type:text" json:"image_url"`                   // Ссылка на изображение в Minio
+	Epoch           time.Time       `gorm:"not null" json:"epoch"`                        // Эпоха орбиты
+	Q               float64         `gorm:"not null;default:0" json:"q"`                  // Перигелийное расстояние (AU)
+	A               *float64        `json:"a"`                                            // Большая полуось q/(1−e) (AU): отрицательна для гиперболы, nil для параболы
+	E               float64         `gorm:"not null" json:"e"`                            // Эксцентриситет
+	I               float64         `gorm:"not null" json:"i"`                            // Наклонение орбиты (deg)
+	Node            float64         `gorm:"not null" json:"Node"`                         // Долгота восходящего узла (deg)
+	ArgPeri         float64         `gorm:"not null" json:"ArgPeri"`                      // Аргумент перицентра (deg)
+	T               time.Time       `gorm:"not null" json:"T"`                            // Время прохождения перигелия
+	RMS             *float64        `json:"rms"`                                          // Среднеквадратичная невязка подгонки (угл. сек), nil — не рассчитана
+	Chi2            *float64        `json:"chi2"`                                         // Нормированный χ² подгонки с учётом погрешностей наблюдений, nil — не рассчитан
+	Iterations      int             `json:"iterations"`                                   // Число итераций подгонки
+	Converged       bool            `json:"converged"`                                    // Сошлась ли подгонка
+	SigmaQ          *float64        `json:"sigma_q"`                                      // Неопределённость (1σ) перигелийного расстояния (AU), nil — не оценена
+	SigmaE          *float64        `json:"sigma_e"`                                      // 1σ эксцентриситета
+	SigmaI          *float64        `json:"sigma_i"`                                      // 1σ наклонения (deg)
+	SigmaNode       *float64        `json:"sigma_node"`                                   // 1σ долготы восходящего узла (deg)
+	SigmaArgPeri    *float64        `json:"sigma_arg_peri"`                               // 1σ аргумента перицентра (deg)
+	SigmaT          *float64        `json:"sigma_t"`                                      // 1σ времени прохождения перигелия (сутки)
+	Covariance      [][]float64     `gorm:"type:jsonb;serializer:json" json:"covariance"` // Ковариация 6×6 элементов q, e, i, Node, ArgPeri, T
+	EarthMOID       *float64        `gorm:"column:earth_moid;index" json:"earth_moid"`    // MOID с орбитой Земли (барицентра Земля — Луна) (AU), nil — не рассчитан
+	JupiterMOID     *float64        `gorm:"column:jupiter_moid" json:"jupiter_moid"`      // MOID с орбитой Юпитера (AU), nil — не рассчитан
+	OwnerID         *uint           `gorm:"index" json:"owner_id"`                        // Владелец (пользователь, отправивший расчёт)
+	OrbitJobID      *string         `gorm:"size:64;uniqueIndex" json:"orbit_job_id"`      // Асинхронная задача, создавшая комету; повтор задачи не создаёт дубликат
 	CreatedAt       time.Time       `json:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"-"`
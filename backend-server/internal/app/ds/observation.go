@@ -8,14 +8,27 @@ import (
 
 // Observation хранит отдельное наблюдение кометы
 type Observation struct {
-	ID         uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	CometID    uint           `gorm:"not null;index" json:"comet_id"` // Ссылка на комету
-	RA         float64        `gorm:"not null" json:"ra"`             // Прямое восхождение (deg)
-	Dec        float64        `gorm:"not null" json:"dec"`            // Склонение (deg)
-	ObservedAt time.Time      `gorm:"not null" json:"observed_at"`    // Время наблюдения
-	PhotoURL   string         `gorm:"type:text" json:"photo_url"`     // Ссылка на фото
-	Notes      string         `gorm:"type:text" json:"notes"`         // Опциональные заметки
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	CometID     uint           `gorm:"not null;index" json:"comet_id"`           // Ссылка на комету
+	OwnerID     *uint          `gorm:"index" json:"owner_id"`                    // Пользователь, добавивший наблюдение
+	RA          float64        `gorm:"not null" json:"ra"`                       // Прямое восхождение (deg)
+	Dec         float64        `gorm:"not null" json:"dec"`                      // Склонение (deg)
+	ObservedAt  time.Time      `gorm:"not null" json:"observed_at"`              // Время наблюдения
+	Observatory string         `gorm:"type:varchar(3);index" json:"observatory"` // Код обсерватории MPC, пусто — координаты или геоцентр
+	Latitude    *float64       `json:"latitude"`                                 // Геодезическая широта наблюдателя (deg), если код не задан
+	Longitude   *float64       `json:"longitude"`                                // Долгота наблюдателя к востоку (deg)
+	Altitude    *float64       `json:"altitude"`                                 // Высота над эллипсоидом WGS84 (м)
+	Magnitude   *float64       `json:"magnitude"`                                // Видимый блеск, nil — не измерен
+	Band        string         `gorm:"type:text" json:"band"`                    // Фотометрическая полоса блеска
+	SigmaRA     *float64       `json:"sigma_ra"`                                 // Погрешность RA·cos(Dec) (угл. сек), nil — неизвестна
+	SigmaDec    *float64       `json:"sigma_dec"`                                // Погрешность Dec (угл. сек)
+	PhotoURL    string         `gorm:"type:text" json:"photo_url"`               // Ссылка на фото
+	Notes       string         `gorm:"type:text" json:"notes"`                   // Опциональные заметки
+	ResidualRA  *float64       `json:"residual_ra"`                              // Невязка O−C по RA·cos(Dec) (угл. сек), nil — не рассчитана
+	ResidualDec *float64       `json:"residual_dec"`                             // Невязка O−C по Dec (угл. сек)
+	Rejected    bool           `gorm:"not null;default:false" json:"rejected"`   // Исключено из подгонки как выброс или принудительно
+	Force       string         `gorm:"type:text" json:"force"`                   // Принудительно "include" или "exclude", пусто — решает отбраковка
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -0,0 +1,13 @@
+package ds
+
+import "time"
+
+// Observatory — обсерватория из списка кодов MPC
+type Observatory struct {
+	Code      string    `gorm:"primaryKey;type:varchar(3)" json:"code"` // Код MPC
+	Name      string    `gorm:"type:text;not null" json:"name"`         // Название
+	Longitude *float64  `json:"longitude"`                              // Долгота к востоку от Гринвича (deg), nil — космическая или подвижная
+	RhoCos    *float64  `json:"rho_cos_phi"`                            // ρ·cos φ′ (экваториальные радиусы Земли)
+	RhoSin    *float64  `json:"rho_sin_phi"`                            // ρ·sin φ′ (экваториальные радиусы Земли)
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,131 @@
+// Package ephemeris строит эфемериды кометы по её кеплеровым элементам:
+// геоцентрические координаты, расстояния, элонгацию, фазовый угол и скорость
+// видимого движения на сетке моментов времени. Орбита распространяется в задаче
+// двух тел или численно с возмущениями планет (см. nbody.Model).
+package ephemeris
+
+import (
+	"math"
+
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// rateStep — половина интервала (сутки) для численного дифференцирования координат.
+const rateStep = 1.0 / 48
+
+// Row — строка эфемериды на момент JD (TT). Координаты астрометрические
+// (с учётом светового времени) в экваториальной системе J2000.
+type Row struct {
+	JD         float64
+	RA         float64 // прямое восхождение, град
+	Dec        float64 // склонение, град
+	Delta      float64 // геоцентрическое расстояние Δ, а.е.
+	R          float64 // гелиоцентрическое расстояние r, а.е.
+	Elongation float64 // элонгация от Солнца, град
+	Phase      float64 // фазовый угол Солнце–комета–Земля, град
+	RateRA     float64 // dα/dt·cos δ, угл. сек/ч
+	RateDec    float64 // dδ/dt, угл. сек/ч
+	Rate       float64 // полная скорость видимого движения, угл. сек/ч
+	PA         float64 // позиционный угол направления движения, град
+}
+
+// Ephemeris вычисляет положения кометы по элементам орбиты.
+type Ephemeris struct {
+	propagator orbit.Propagator
+	planets    planets.Source
+}
+
+// New создаёт эфемериду по элементам, углы которых отсчитываются от эклиптики J2000,
+// в модели движения model. Элементы считаются оскулирующими на момент перигелия.
+// Положения Земли и, в модели n_body, возмущающих тел берутся из src.
+func New(el orbit.Elements, model nbody.Model, src planets.Source) (*Ephemeris, error) {
+	s, err := orbit.StateFromElements(el, el.Tp, orbit.MuSun)
+	if err != nil {
+		return nil, err
+	}
+	p, err := nbody.NewPropagator(model, s.ToEquatorial(), el.Tp, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Ephemeris{propagator: p, planets: src}, nil
+}
+
+// At возвращает строку эфемериды на момент jd (TT).
+func (e *Ephemeris) At(jd float64) (Row, error) {
+	earth, err := e.earth(jd)
+	if err != nil {
+		return Row{}, err
+	}
+	helio, rho, err := e.observe(jd, earth)
+	if err != nil {
+		return Row{}, err
+	}
+	ra, dec := orbit.RADec(rho)
+
+	row := Row{
+		JD:         jd,
+		RA:         ra / orbit.Deg,
+		Dec:        dec / orbit.Deg,
+		Delta:      rho.Norm(),
+		R:          helio.Norm(),
+		Elongation: angle(earth.Scale(-1), rho) / orbit.Deg,
+		Phase:      angle(helio.Scale(-1), rho.Scale(-1)) / orbit.Deg,
+	}
+
+	// скорость движения — центральная разность по соседним моментам
+	before, err := e.direction(jd - rateStep)
+	if err != nil {
+		return Row{}, err
+	}
+	after, err := e.direction(jd + rateStep)
+	if err != nil {
+		return Row{}, err
+	}
+	ra0, dec0 := orbit.RADec(before)
+	ra1, dec1 := orbit.RADec(after)
+	hours := 2 * rateStep * 24
+	row.RateRA = orbit.WrapAngle(ra1-ra0) * math.Cos(dec) / orbit.ArcSec / hours
+	row.RateDec = (dec1 - dec0) / orbit.ArcSec / hours
+	row.Rate = math.Hypot(row.RateRA, row.RateDec)
+	row.PA = orbit.NormalizeAngle(math.Atan2(row.RateRA, row.RateDec)) / orbit.Deg
+	return row, nil
+}
+
+// earth возвращает гелиоцентрическое положение Земли на момент jd.
+func (e *Ephemeris) earth(jd float64) (orbit.Vec3, error) {
+	s, err := e.planets.State(planets.BodyEarth, jd)
+	return s.R, err
+}
+
+// direction возвращает геоцентрический вектор на комету на момент jd.
+func (e *Ephemeris) direction(jd float64) (orbit.Vec3, error) {
+	earth, err := e.earth(jd)
+	if err != nil {
+		return orbit.Vec3{}, err
+	}
+	_, rho, err := e.observe(jd, earth)
+	return rho, err
+}
+
+// observe возвращает гелиоцентрическое положение кометы на момент излучения света
+// и геоцентрический вектор на неё для наблюдателя в точке earth на момент jd.
+func (e *Ephemeris) observe(jd float64, earth orbit.Vec3) (helio, rho orbit.Vec3, err error) {
+	lightTime := 0.0
+	for range 3 {
+		s, err := e.propagator.At(jd - lightTime)
+		if err != nil {
+			return orbit.Vec3{}, orbit.Vec3{}, err
+		}
+		helio = s.R
+		rho = helio.Sub(earth)
+		lightTime = rho.Norm() / orbit.SpeedOfLight
+	}
+	return helio, rho, nil
+}
+
+// angle возвращает угол между векторами a и b, рад.
+func angle(a, b orbit.Vec3) float64 {
+	return math.Atan2(a.Cross(b).Norm(), a.Dot(b))
+}
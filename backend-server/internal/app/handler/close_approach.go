@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/approach"
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/jplde"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/planets"
+)
+
+const (
+	defaultApproachYears = 5
+	maxApproachYears     = 100
+	// анонимный поиск ограничен: он дорогой, а маршрут открыт без авторизации
+	maxGuestApproachYears  = 10
+	maxGuestApproachBodies = 3
+	secondsPerDay          = 86400
+)
+
+// closeApproachRow — сближение в ответе API.
+type closeApproachRow struct {
+	Body                string    `json:"body"`
+	Time                time.Time `json:"time"`
+	DistanceAU          float64   `json:"distance_au"`
+	DistanceKm          float64   `json:"distance_km"`
+	DistanceHill        float64   `json:"distance_hill"`
+	RelativeVelocityKmS float64   `json:"relative_velocity_km_s"`
+}
+
+// bodyApproaches — сближения кометы с одним телом.
+type bodyApproaches struct {
+	Body       planets.Body
+	Approaches []approach.Approach // не дальше порога
+	Closest    approach.Approach   // наименьшее расстояние на интервале
+}
+
+// approachLimits — порог сохраняемых сближений: не дальше MaxAU а.е. или MaxHill радиусов
+// сферы Хилла тела, что больше; оба 0 — все минимумы расстояния.
+type approachLimits struct {
+	MaxAU   float64
+	MaxHill float64
+}
+
+// forBody возвращает порог для тела b в а.е. (0 — без порога).
+func (l approachLimits) forBody(b planets.Body) float64 {
+	if l.MaxAU <= 0 && l.MaxHill <= 0 {
+		return 0
+	}
+	return max(l.MaxAU, l.MaxHill*b.HillRadius())
+}
+
+// GetCometCloseApproaches ищет сближения кометы с планетами и Луной по сохранённым элементам
+// орбиты. Параметры запроса: bodies — названия тел через запятую (по умолчанию
+// orbit.approach_bodies), start и stop (UTC; по умолчанию — интервал поиска из настроек
+// orbit.approach_years от момента перигелия), max_distance_au и max_distance_hill — порог
+// в а.е. и в радиусах сферы Хилла тела, действует больший (по умолчанию orbit.approach_max_au
+// и orbit.approach_max_hill, оба 0 — все минимумы расстояния), и model — two_body
+// (по умолчанию) или n_body с возмущениями планет. Без авторизации интервал не длиннее
+// maxGuestApproachYears лет и тел не больше maxGuestApproachBodies.
+func (h *Handler) GetCometCloseApproaches(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	el := cometElements(comet)
+
+	names := h.Config.Orbit.ApproachBodies
+	if v := ctx.Query("bodies"); v != "" {
+		names = strings.Split(v, ",")
+	}
+	bodies, err := approach.ParseBodies(names)
+	if err != nil || len(bodies) == 0 {
+		msg := "bodies must not be empty"
+		if err != nil {
+			msg = err.Error()
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	_, authorized := GetUserIDFromContext(ctx)
+	maxYears := maxApproachYears
+	if !authorized {
+		maxYears = maxGuestApproachYears
+		if len(bodies) > maxGuestApproachBodies {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d bodies without authorization", maxGuestApproachBodies)})
+			return
+		}
+	}
+
+	start, stop := h.approachWindow(el)
+	if v := ctx.Query("start"); v != "" {
+		t, err := orbitclient.ParseTime(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+			return
+		}
+		start = astrotime.TT(t)
+	}
+	if v := ctx.Query("stop"); v != "" {
+		t, err := orbitclient.ParseTime(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid stop: " + err.Error()})
+			return
+		}
+		stop = astrotime.TT(t)
+	}
+	if stop <= start {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "stop must be after start"})
+		return
+	}
+	if ctx.Query("stop") == "" {
+		// интервал по умолчанию укорачивается до допустимого
+		stop = min(stop, start+float64(maxYears)*365.25)
+	}
+	if stop-start > float64(maxYears)*365.25 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("interval must not exceed %d years", maxYears)})
+		return
+	}
+	limits := approachLimits{MaxAU: h.Config.Orbit.ApproachMaxAU, MaxHill: h.Config.Orbit.ApproachMaxHill}
+	if limits.MaxAU, err = queryFloat(ctx, "max_distance_au", limits.MaxAU); err != nil || limits.MaxAU < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "max_distance_au must not be negative"})
+		return
+	}
+	if limits.MaxHill, err = queryFloat(ctx, "max_distance_hill", limits.MaxHill); err != nil || limits.MaxHill < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "max_distance_hill must not be negative"})
+		return
+	}
+	model, ok := queryModel(ctx)
+	if !ok {
+		return
+	}
+
+	found, err := h.findCloseApproaches(el, model, bodies, start, stop, limits)
+	if errors.Is(err, orbit.ErrInvalidElements) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet has no valid orbital elements"})
+		return
+	}
+	if errors.Is(err, jplde.ErrOutOfRange) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to search close approaches")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search close approaches"})
+		return
+	}
+
+	rows := make([]closeApproachRow, 0)
+	closest := make(map[string]closeApproachRow, len(found))
+	for _, f := range found {
+		for _, a := range f.Approaches {
+			rows = append(rows, newCloseApproachRow(f.Body, a))
+		}
+		closest[f.Body.String()] = newCloseApproachRow(f.Body, f.Closest)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Time.Before(rows[j].Time) })
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"comet_id":          comet.ID,
+		"start":             astrotime.UTCFromTT(start),
+		"stop":              astrotime.UTCFromTT(stop),
+		"max_distance_au":   limits.MaxAU,
+		"max_distance_hill": limits.MaxHill,
+		"model":             model,
+		"approaches":        rows,
+		"closest":           closest,
+	})
+}
+
+func newCloseApproachRow(b planets.Body, a approach.Approach) closeApproachRow {
+	return closeApproachRow{
+		Body:                b.String(),
+		Time:                astrotime.UTCFromTT(a.JD),
+		DistanceAU:          a.DistanceAU,
+		DistanceKm:          a.DistanceAU * orbit.KmPerAU,
+		DistanceHill:        a.DistanceAU / b.HillRadius(),
+		RelativeVelocityKmS: a.Velocity * orbit.KmPerAU / secondsPerDay,
+	}
+}
+
+// closeApproaches ищет сближения кометы с телами orbit.approach_bodies после расчёта орбиты
+// на интервале из настроек и переводит их в строки ds.CloseApproach. Если ни один минимум
+// расстояния до Земли не проходит порог, сохраняется наименьшее расстояние до неё на
+// интервале, чтобы каталог по-прежнему можно было сортировать по сближению. Тело, которого
+// нет в эфемериде на интервале поиска, пропускается.
+func (h *Handler) closeApproaches(comet *ds.Comet, model nbody.Model) ([]ds.CloseApproach, error) {
+	bodies, err := approach.ParseBodies(h.Config.Orbit.ApproachBodies)
+	if err != nil {
+		return nil, err
+	}
+	el := cometElements(comet)
+	start, stop := h.approachWindow(el)
+	limits := approachLimits{MaxAU: h.Config.Orbit.ApproachMaxAU, MaxHill: h.Config.Orbit.ApproachMaxHill}
+
+	propagator, err := h.cometPropagator(el, model)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ds.CloseApproach
+	for _, b := range bodies {
+		f, err := h.findBodyApproaches(propagator, b, start, stop, limits)
+		if errors.Is(err, jplde.ErrOutOfRange) {
+			logrus.WithError(err).WithField("body", b.String()).Warn("close approach search skipped")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found := f.Approaches
+		if len(found) == 0 && b == planets.BodyEarth {
+			found = []approach.Approach{f.Closest}
+		}
+		for _, a := range found {
+			row := newCloseApproachRow(b, a)
+			rows = append(rows, ds.CloseApproach{
+				Body:                row.Body,
+				ClosestDate:         row.Time,
+				DistanceAU:          row.DistanceAU,
+				DistanceHill:        &row.DistanceHill,
+				RelativeVelocityKmS: &row.RelativeVelocityKmS,
+			})
+		}
+	}
+	return rows, nil
+}
+
+// findCloseApproaches распространяет орбиту в модели model и ищет сближения с телами bodies
+// на интервале [start, stop] (JD TT).
+func (h *Handler) findCloseApproaches(el orbit.Elements, model nbody.Model, bodies []planets.Body, start, stop float64, limits approachLimits) ([]bodyApproaches, error) {
+	propagator, err := h.cometPropagator(el, model)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]bodyApproaches, 0, len(bodies))
+	for _, b := range bodies {
+		f, err := h.findBodyApproaches(propagator, b, start, stop, limits)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, f)
+	}
+	return found, nil
+}
+
+// findBodyApproaches ищет сближения кометы с телом b на интервале [start, stop] (JD TT).
+func (h *Handler) findBodyApproaches(propagator orbit.Propagator, b planets.Body, start, stop float64, limits approachLimits) (bodyApproaches, error) {
+	target := approach.BodyTarget(h.Planets, b)
+	res, err := approach.Find(propagator, target, start, stop, approach.Options{MaxDistanceAU: limits.forBody(b)})
+	if err != nil {
+		return bodyApproaches{}, err
+	}
+	return bodyApproaches{Body: b, Approaches: res.Approaches, Closest: res.Closest}, nil
+}
+
+// cometPropagator возвращает распространитель орбиты по элементам кометы (оскулирующим
+// на момент перигелия) в модели model.
+func (h *Handler) cometPropagator(el orbit.Elements, model nbody.Model) (orbit.Propagator, error) {
+	s, err := orbit.StateFromElements(el, el.Tp, orbit.MuSun)
+	if err != nil {
+		return nil, err
+	}
+	return nbody.NewPropagator(model, s.ToEquatorial(), el.Tp, h.Planets)
+}
+
+// approachWindow возвращает интервал поиска сближений по умолчанию (JD TT).
+func (h *Handler) approachWindow(el orbit.Elements) (start, stop float64) {
+	years := h.Config.Orbit.ApproachYears
+	if years <= 0 {
+		years = defaultApproachYears
+	}
+	return approach.Window(el, years)
+}
@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/repository"
+	"backend-server/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListComets возвращает страницу каталога комет.
+// Параметры: page, page_size, name (поиск по подстроке), moid_lt (MOID относительно Земли
+// меньше заданного, AU), sort (perihelion | eccentricity | closest_approach) и order (asc | desc).
+func (h *Handler) ListComets(ctx *gin.Context) {
+	filter, ok := parseCometFilter(ctx)
+	if !ok {
+		return
+	}
+	h.writeCometPage(ctx, filter)
+}
+
+// ListProfileComets возвращает кометы, отправленные на расчёт текущим пользователем,
+// вместе с их орбитальными элементами. Параметры те же, что у ListComets.
+func (h *Handler) ListProfileComets(ctx *gin.Context) {
+	userID, ok := GetUserIDFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	filter, ok := parseCometFilter(ctx)
+	if !ok {
+		return
+	}
+	filter.OwnerID = &userID
+	h.writeCometPage(ctx, filter)
+}
+
+// parseCometFilter разбирает параметры пагинации, поиска и сортировки каталога.
+// При ошибке пишет ответ 400 и возвращает false.
+func parseCometFilter(ctx *gin.Context) (repository.CometFilter, bool) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return repository.CometFilter{}, false
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_size"})
+		return repository.CometFilter{}, false
+	}
+
+	sortBy := ctx.Query("sort")
+	switch sortBy {
+	case "", repository.SortByPerihelion, repository.SortByEccentricity, repository.SortByClosestApproach:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort"})
+		return repository.CometFilter{}, false
+	}
+
+	order := strings.ToLower(ctx.DefaultQuery("order", "asc"))
+	if order != "asc" && order != "desc" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid order"})
+		return repository.CometFilter{}, false
+	}
+
+	var moidLT *float64
+	if v := ctx.Query("moid_lt"); v != "" {
+		moid, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(moid > 0) || math.IsInf(moid, 0) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid moid_lt"})
+			return repository.CometFilter{}, false
+		}
+		moidLT = &moid
+	}
+
+	return repository.CometFilter{
+		Name:         ctx.Query("name"),
+		MOIDLessThan: moidLT,
+		SortBy:       sortBy,
+		Desc:         order == "desc",
+		Limit:        pageSize,
+		Offset:       (page - 1) * pageSize,
+	}, true
+}
+
+// writeCometPage выбирает страницу комет по фильтру и пишет её в ответ.
+func (h *Handler) writeCometPage(ctx *gin.Context, filter repository.CometFilter) {
+	comets, total, err := h.Repository.ListComets(filter)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list comets")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list comets"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"items":     comets,
+		"total":     total,
+		"page":      filter.Offset/filter.Limit + 1,
+		"page_size": filter.Limit,
+	})
+}
+
+// GetComet возвращает комету с наблюдениями и сближениями.
+func (h *Handler) GetComet(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, comet)
+}
+
+// UpdateComet обновляет имя и (при необходимости) элементы орбиты кометы. Изменённые
+// элементы больше не результат подгонки: статистика, ковариация и невязки сбрасываются,
+// MOID и сближения пересчитываются. Доступно владельцу кометы и администратору.
+func (h *Handler) UpdateComet(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	if !canModifyComet(ctx, comet) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var body struct {
+		Name    *string    `json:"name"`
+		Q       *float64   `json:"q"`
+		E       *float64   `json:"e"`
+		I       *float64   `json:"i"`
+		Node    *float64   `json:"Node"`
+		ArgPeri *float64   `json:"ArgPeri"`
+		T       *time.Time `json:"T"`
+		Epoch   *time.Time `json:"epoch"`
+	}
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+			return
+		}
+		comet.Name = name
+	}
+	if body.E != nil && *body.E < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "e must not be negative"})
+		return
+	}
+	if body.Q != nil && *body.Q <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q must be positive"})
+		return
+	}
+	setIfPresent(&comet.Q, body.Q)
+	setIfPresent(&comet.E, body.E)
+	comet.A = orbitclient.SemiMajorAxis(comet.Q, comet.E)
+	setIfPresent(&comet.I, body.I)
+	setIfPresent(&comet.Node, body.Node)
+	setIfPresent(&comet.ArgPeri, body.ArgPeri)
+	setIfPresent(&comet.T, body.T)
+	setIfPresent(&comet.Epoch, body.Epoch)
+
+	if body.Q == nil && body.E == nil && body.I == nil && body.Node == nil && body.ArgPeri == nil && body.T == nil && body.Epoch == nil {
+		if err := h.Repository.UpdateComet(comet); err != nil {
+			logrus.WithError(err).Error("failed to update comet")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update comet"})
+			return
+		}
+		ctx.JSON(http.StatusOK, comet)
+		return
+	}
+
+	// Элементы заданы вручную: статистика подгонки, ковариация и невязки к ним не относятся,
+	// а MOID и сближения пересчитываются для новой орбиты
+	clearFit(comet)
+	h.updateMOID(comet)
+	approaches, err := h.closeApproaches(comet, nbody.ModelTwoBody)
+	if err != nil {
+		logrus.WithError(err).Error("failed to search close approaches")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search close approaches"})
+		return
+	}
+	if err := h.Repository.UpdateCometElements(comet, approaches); err != nil {
+		logrus.WithError(err).Error("failed to update comet")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update comet"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comet)
+}
+
+// clearFit сбрасывает результаты подгонки кометы и невязки её наблюдений.
+func clearFit(comet *ds.Comet) {
+	comet.RMS, comet.Chi2 = nil, nil
+	comet.Iterations = 0
+	comet.Converged = false
+	comet.SigmaQ, comet.SigmaE, comet.SigmaI = nil, nil, nil
+	comet.SigmaNode, comet.SigmaArgPeri, comet.SigmaT = nil, nil, nil
+	comet.Covariance = nil
+	for i := range comet.Observations {
+		comet.Observations[i].ResidualRA = nil
+		comet.Observations[i].ResidualDec = nil
+		comet.Observations[i].Rejected = false
+	}
+}
+
+// DeleteComet мягко удаляет комету. Доступно владельцу кометы и администратору.
+func (h *Handler) DeleteComet(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	if !canModifyComet(ctx, comet) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	if err := h.Repository.DeleteComet(comet.ID); err != nil {
+		logrus.WithError(err).Error("failed to delete comet")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete comet"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "comet deleted"})
+}
+
+// loadComet загружает комету по параметру :id и пишет ответ с ошибкой, если это не удалось.
+func (h *Handler) loadComet(ctx *gin.Context) (*ds.Comet, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid comet id"})
+		return nil, false
+	}
+
+	comet, err := h.Repository.GetComet(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "comet not found"})
+		return nil, false
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to load comet")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comet"})
+		return nil, false
+	}
+
+	return comet, true
+}
+
+// canModifyComet проверяет, что текущий пользователь — владелец кометы или администратор.
+func canModifyComet(ctx *gin.Context, comet *ds.Comet) bool {
+	return isOwnerOrAdmin(ctx, comet.OwnerID)
+}
+
+// isOwnerOrAdmin проверяет, что текущий пользователь — ownerID или администратор.
+func isOwnerOrAdmin(ctx *gin.Context, ownerID *uint) bool {
+	if userRole, ok := GetUserRoleFromContext(ctx); ok && userRole == role.Admin {
+		return true
+	}
+	userID, ok := GetUserIDFromContext(ctx)
+	return ok && ownerID != nil && *ownerID == userID
+}
+
+// setIfPresent присваивает значение, если оно передано в запросе.
+func setIfPresent[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/ephemeris"
+	"backend-server/internal/app/jplde"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/planets"
+)
+
+const (
+	defaultEphemerisSpan = 30 * 24 * time.Hour
+	maxEphemerisRows     = 2000
+)
+
+// ephemerisRow — строка эфемериды в ответе API.
+type ephemerisRow struct {
+	Time       time.Time `json:"time"`
+	RA         float64   `json:"ra"`
+	Dec        float64   `json:"dec"`
+	Delta      float64   `json:"delta_au"`
+	R          float64   `json:"r_au"`
+	Elongation float64   `json:"elongation"`
+	Phase      float64   `json:"phase"`
+	RateRA     float64   `json:"rate_ra"`
+	RateDec    float64   `json:"rate_dec"`
+	Rate       float64   `json:"rate"`
+	PA         float64   `json:"position_angle"`
+}
+
+// GetCometEphemeris возвращает таблицу предсказанных геоцентрических положений кометы.
+// Параметры запроса: start и stop (UTC, по умолчанию — 30 суток от текущего момента)
+// step (например, 1d, 6h, 30m; по умолчанию 1d) и model — two_body (по умолчанию) или
+// n_body с возмущениями планет. Координаты — в градусах (J2000), скорости движения —
+// в угловых секундах в час.
+func (h *Handler) GetCometEphemeris(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+
+	start := time.Now().UTC().Truncate(time.Hour)
+	if v := ctx.Query("start"); v != "" {
+		t, err := orbitclient.ParseTime(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+			return
+		}
+		start = t
+	}
+	stop := start.Add(defaultEphemerisSpan)
+	if v := ctx.Query("stop"); v != "" {
+		t, err := orbitclient.ParseTime(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid stop: " + err.Error()})
+			return
+		}
+		stop = t
+	}
+	step := 24 * time.Hour
+	if v := ctx.Query("step"); v != "" {
+		d, err := parseStep(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		step = d
+	}
+	if stop.Before(start) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "stop must not be before start"})
+		return
+	}
+	if rows := stop.Sub(start)/step + 1; rows > maxEphemerisRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("too many rows: %d (max %d), increase step", rows, maxEphemerisRows)})
+		return
+	}
+
+	model, ok := queryModel(ctx)
+	if !ok {
+		return
+	}
+
+	eph, err := ephemeris.New(cometElements(comet), model, h.Planets)
+	if errors.Is(err, orbit.ErrInvalidElements) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet has no valid orbital elements"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to build ephemeris")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build ephemeris"})
+		return
+	}
+
+	rows := make([]ephemerisRow, 0)
+	for t := start; !t.After(stop); t = t.Add(step) {
+		row, err := eph.At(astrotime.TT(t))
+		if h.ephemerisOutOfRange(ctx, err) {
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("failed to propagate comet orbit")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build ephemeris"})
+			return
+		}
+		rows = append(rows, ephemerisRow{
+			Time:       t,
+			RA:         row.RA,
+			Dec:        row.Dec,
+			Delta:      row.Delta,
+			R:          row.R,
+			Elongation: row.Elongation,
+			Phase:      row.Phase,
+			RateRA:     row.RateRA,
+			RateDec:    row.RateDec,
+			Rate:       row.Rate,
+			PA:         row.PA,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"comet_id":  comet.ID,
+		"start":     start,
+		"stop":      stop,
+		"step":      step.String(),
+		"model":     model,
+		"ephemeris": rows,
+	})
+}
+
+// cometElements переводит сохранённые элементы кометы (углы в градусах) в orbit.Elements.
+func cometElements(c *ds.Comet) orbit.Elements {
+	return orbit.ElementsFromDegrees(c.Q, c.E, c.I, c.Node, c.ArgPeri, astrotime.TT(c.T))
+}
+
+// queryModel читает параметр model — модель движения кометы. При ошибке отвечает 400
+// и возвращает false.
+func queryModel(ctx *gin.Context) (nbody.Model, bool) {
+	model, err := nbody.ParseModel(ctx.Query("model"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return model, true
+}
+
+// ephemerisOutOfRange отвечает 422, если момент лежит за пределами охвата эфемериды
+// JPL, по которой считаются положения планет, и тогда возвращает true.
+func (h *Handler) ephemerisOutOfRange(ctx *gin.Context, err error) bool {
+	bounded, ok := h.Planets.(planets.Bounded)
+	if !errors.Is(err, jplde.ErrOutOfRange) || !ok {
+		return false
+	}
+	start, end := bounded.Span()
+	ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf(
+		"planetary ephemeris covers %s – %s only",
+		astrotime.UTCFromTT(start).Format(time.DateOnly), astrotime.UTCFromTT(end).Format(time.DateOnly))})
+	return true
+}
+
+// parseStep разбирает шаг эфемериды: число с суффиксом d, h, m или s; без суффикса — сутки.
+func parseStep(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	unit := 24 * time.Hour
+	switch {
+	case strings.HasSuffix(s, "d"):
+		s = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "h"):
+		s, unit = strings.TrimSuffix(s, "h"), time.Hour
+	case strings.HasSuffix(s, "m"):
+		s, unit = strings.TrimSuffix(s, "m"), time.Minute
+	case strings.HasSuffix(s, "s"):
+		s, unit = strings.TrimSuffix(s, "s"), time.Second
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, errors.New("invalid step: use a positive number with d, h, m or s suffix")
+	}
+	d := time.Duration(v * float64(unit))
+	if d < time.Minute {
+		return 0, errors.New("step must be at least 1m")
+	}
+	return d, nil
+}
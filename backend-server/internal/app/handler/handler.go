@@ -2,6 +2,8 @@ package handler
 
 import (
 	"backend-server/internal/app/config"
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/planets"
 	"backend-server/internal/app/redis"
 	"backend-server/internal/app/repository"
 )
@@ -11,13 +13,20 @@ type Handler struct {
 	Repository *repository.Repository
 	Config     *config.Config
 	Redis      *redis.Client
+	Orbit      orbitclient.OrbitCalculator
+	// Planets — источник положений планет из config.Ephemeris: эфемерида JPL или
+	// встроенная эфемерида (см. jplde.OpenSource)
+	Planets planets.Source
 }
 
-// NewHandler создает новый Handler с подключенным репозиторием и конфигом
-func NewHandler(r *repository.Repository, cfg *config.Config, redisClient *redis.Client) *Handler {
+// NewHandler создает новый Handler с подключенным репозиторием, конфигом, калькулятором орбит
+// и источником положений планет
+func NewHandler(r *repository.Repository, cfg *config.Config, redisClient *redis.Client, orbit orbitclient.OrbitCalculator, src planets.Source) *Handler {
 	return &Handler{
 		Repository: r,
 		Config:     cfg,
 		Redis:      redisClient,
+		Orbit:      orbit,
+		Planets:    src,
 	}
 }
@@ -139,3 +139,57 @@ func (h *Handler) BlockAuthUsers() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// OptionalAuthMiddleware пропускает запросы без токена как анонимные, а при наличии токена
+// проверяет его так же, как AuthMiddleware, и сохраняет user_id и роль в контексте.
+// Недействительный токен отклоняется, а не превращает запрос в анонимный.
+func (h *Handler) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var tokenStr string
+
+		// Получаем токен из cookie или заголовка Authorization
+		if cookie, err := ctx.Cookie(cookieName); err == nil {
+			tokenStr = cookie
+		} else if authHeader := ctx.GetHeader("Authorization"); strings.HasPrefix(authHeader, jwtPrefix) {
+			tokenStr = strings.TrimPrefix(authHeader, jwtPrefix)
+		}
+
+		if tokenStr == "" {
+			// Токена нет — анонимный запрос
+			ctx.Next()
+			return
+		}
+
+		// Проверка токена в блеклисте Redis
+		if h.Redis != nil {
+			if err := h.Redis.CheckJWTInBlacklist(ctx.Request.Context(), tokenStr); err == nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token is blacklisted"})
+				return
+			} else if !errors.Is(err, redis.Nil) {
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "redis error"})
+				return
+			}
+		}
+
+		// Парсинг JWT
+		token, err := jwt.ParseWithClaims(tokenStr, &ds.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(h.Config.JWT.AccessSecret), nil
+		})
+		if err != nil || !token.Valid {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		claims, ok := token.Claims.(*ds.JWTClaims)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
+			return
+		}
+
+		// Сохраняем user_id и роль в контексте
+		ctx.Set("user_id", claims.UserID)
+		ctx.Set("role", claims.Role)
+
+		ctx.Next()
+	}
+}
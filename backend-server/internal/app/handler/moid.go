@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/moid"
+	"backend-server/internal/app/planets"
+)
+
+// updateMOID вычисляет MOID кометы с орбитой Земли и, если включено orbit.jupiter_moid, —
+// Юпитера по сохранённым элементам. Орбиты планет — оскулирующие на эпоху элементов кометы.
+// Если MOID вычислить не удалось (например, эпоха за пределами охвата эфемериды планет),
+// поле сбрасывается в nil.
+func (h *Handler) updateMOID(comet *ds.Comet) {
+	comet.EarthMOID = h.cometMOID(comet, planets.BodyEarth)
+	comet.JupiterMOID = nil
+	if h.Config.Orbit.JupiterMOID {
+		comet.JupiterMOID = h.cometMOID(comet, planets.BodyJupiter)
+	}
+}
+
+// cometMOID вычисляет MOID кометы с оскулирующей орбитой тела b на эпоху элементов кометы
+// по источнику h.Planets. При ошибке она записывается в журнал и возвращается nil.
+func (h *Handler) cometMOID(comet *ds.Comet, b planets.Body) *float64 {
+	res, err := moid.Planet(cometElements(comet), h.Planets, b, astrotime.TT(comet.Epoch))
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"comet": comet.ID, "body": b.String()}).Warn("failed to compute MOID")
+		return nil
+	}
+	return &res.DistanceAU
+}
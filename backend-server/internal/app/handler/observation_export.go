@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/ades"
+	"backend-server/internal/app/ds"
+)
+
+const (
+	// adesMode и adesAstCat подставляются в выгрузку ADES: способ наблюдения и каталог
+	// привязки у сохранённых наблюдений не хранятся.
+	adesMode   = "CCD"
+	adesAstCat = "UNK"
+)
+
+// ExportObservations выгружает наблюдения кометы документом ADES для отправки в MPC.
+// Параметры запроса: format — psv (по умолчанию) или xml; provid — предварительное
+// обозначение кометы (без него наблюдения помечаются обозначением трека вида C0000012);
+// submitter, observers и measurers (имена через запятую; measurers по умолчанию — submitter);
+// telescope_design, telescope_aperture (м) и telescope_detector. Наблюдения группируются
+// в блоки по обсерваториям: подвижный наблюдатель выгружается с кодом 247 и координатами,
+// геоцентрическое наблюдение — с кодом 500.
+func (h *Handler) ExportObservations(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+
+	format := ctx.DefaultQuery("format", "psv")
+	if format != "psv" && format != "xml" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be psv or xml"})
+		return
+	}
+
+	header := ades.Context{
+		Submitter: strings.TrimSpace(ctx.Query("submitter")),
+		Observers: splitNames(ctx.Query("observers")),
+		Measurers: splitNames(ctx.Query("measurers")),
+	}
+	if len(header.Measurers) == 0 && header.Submitter != "" {
+		header.Measurers = []string{header.Submitter}
+	}
+	design, detector := ctx.Query("telescope_design"), ctx.Query("telescope_detector")
+	if v := ctx.Query("telescope_aperture"); v != "" || design != "" || detector != "" {
+		header.Telescope = &ades.Telescope{Design: design, Detector: detector}
+		if v != "" {
+			aperture, err := strconv.ParseFloat(v, 64)
+			if err != nil || aperture <= 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "telescope_aperture must be a positive number"})
+				return
+			}
+			header.Telescope.Aperture = aperture
+		}
+	}
+
+	provID := strings.TrimSpace(ctx.Query("provid"))
+	trkSub := ""
+	if provID == "" {
+		trkSub = fmt.Sprintf("C%07d", comet.ID)
+	}
+
+	// наблюдения уже упорядочены по времени, блоки идут в порядке первого наблюдения обсерватории
+	var blocks []ades.Block
+	index := make(map[string]int)
+	for _, o := range comet.Observations {
+		obs := adesObservation(o)
+		obs.ProvID, obs.TrkSub = provID, trkSub
+		k, ok := index[obs.Stn]
+		if !ok {
+			k = len(blocks)
+			index[obs.Stn] = k
+			c := header
+			c.Observatory = obs.Stn
+			blocks = append(blocks, ades.Block{Context: c})
+		}
+		blocks[k].Observations = append(blocks[k].Observations, obs)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType := "text/plain; charset=utf-8"
+	if format == "xml" {
+		contentType = "application/xml; charset=utf-8"
+		err = ades.WriteXML(&buf, blocks)
+	} else {
+		err = ades.WritePSV(&buf, blocks)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to write ADES document")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write ADES document"})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="comet-%d.%s"`, comet.ID, format))
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// adesObservation переводит сохранённое наблюдение в наблюдение ADES без обозначения объекта.
+func adesObservation(o ds.Observation) ades.Observation {
+	obs := ades.Observation{
+		Mode:    adesMode,
+		Stn:     o.Observatory,
+		ObsTime: o.ObservedAt,
+		RA:      o.RA,
+		Dec:     o.Dec,
+		RmsRA:   o.SigmaRA,
+		RmsDec:  o.SigmaDec,
+		AstCat:  adesAstCat,
+		Mag:     o.Magnitude,
+		Band:    o.Band,
+	}
+	switch {
+	case o.Observatory != "":
+	case o.Latitude != nil && o.Longitude != nil:
+		obs.Stn = ades.StnRoving
+		obs.Roving = &ades.Roving{Latitude: *o.Latitude, Longitude: *o.Longitude}
+		if o.Altitude != nil {
+			obs.Roving.Altitude = *o.Altitude
+		}
+	default:
+		obs.Stn = ades.StnGeocentric
+	}
+	return obs
+}
+
+// splitNames разбирает список имён через запятую, пропуская пустые.
+func splitNames(s string) []string {
+	var names []string
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
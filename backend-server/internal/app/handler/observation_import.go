@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/ades"
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/mpc"
+	"backend-server/internal/app/orbitclient"
+)
+
+const (
+	// maxMPCReportSize ограничивает размер отчёта MPC (около 60 тысяч строк).
+	maxMPCReportSize = 5 << 20
+	// maxADESReportSize ограничивает размер документа ADES: XML занимает около 500 байт на наблюдение.
+	maxADESReportSize = 20 << 20
+)
+
+// ImportObservations добавляет к комете наблюдения из отчёта MPC в 80-колоночном формате.
+// Отчёт передаётся файлом file в multipart/form-data или телом запроса text/plain.
+// При ошибках в отчёте ничего не сохраняется, а в ответе перечисляются строки с ошибками.
+// Наблюдения, которые у кометы уже есть (тот же момент и обсерватория), пропускаются.
+// Орбита не пересчитывается — для этого есть POST /api/comets/:id/refit.
+func (h *Handler) ImportObservations(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	if !canModifyComet(ctx, comet) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var parsed []mpc.Observation
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if parsed, ok = readMPCReport(ctx, file); !ok {
+			return
+		}
+	} else {
+		body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxMPCReportSize)
+		if parsed, ok = parseMPCReport(ctx, body); !ok {
+			return
+		}
+	}
+
+	inputs, lines := mpcInputs(parsed)
+	h.addImportedObservations(ctx, comet, inputs, lines, "invalid MPC report")
+}
+
+// ImportADESObservations добавляет к комете оптические наблюдения из документа ADES в формате
+// XML или PSV (формат определяется по содержимому). Документ передаётся файлом file
+// в multipart/form-data или телом запроса. Погрешности rmsRA и rmsDec сохраняются и при
+// подгонке орбиты служат весами наблюдений. В остальном импорт устроен как ImportObservations.
+func (h *Handler) ImportADESObservations(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	if !canModifyComet(ctx, comet) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var body io.Reader
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if file.Size > maxADESReportSize {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "ADES document is too large"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read ADES document"})
+			return
+		}
+		defer f.Close()
+		body = f
+	} else {
+		body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxADESReportSize)
+	}
+
+	parsed, err := ades.Parse(body)
+	var parseErr ades.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid ADES document", "details": parseErr})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read ADES document: " + err.Error()})
+		return
+	case len(parsed) == 0:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ADES document has no optical observations"})
+		return
+	}
+
+	inputs, lines := adesInputs(parsed)
+	h.addImportedObservations(ctx, comet, inputs, lines, "invalid ADES document")
+}
+
+// addImportedObservations проверяет наблюдения из отчёта и сохраняет те, которых у кометы ещё нет.
+// Ошибки проверки сопоставляются строкам отчёта и возвращаются с сообщением invalid.
+func (h *Handler) addImportedObservations(ctx *gin.Context, comet *ds.Comet, inputs []observationInput, lines []int, invalid string) {
+	observatories, err := h.lookupObservatories(inputs)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load observatories")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load observatories"})
+		return
+	}
+	observations, _, validationErrors := buildObservations(inputs, fitOptions{}, observatories)
+	if len(validationErrors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": invalid, "details": lineErrors(validationErrors, lines)})
+		return
+	}
+
+	// повторный импорт того же отчёта не дублирует наблюдения
+	type key struct {
+		at   int64
+		code string
+	}
+	existing := make(map[key]bool, len(comet.Observations))
+	for _, o := range comet.Observations {
+		existing[key{o.ObservedAt.UnixMicro(), o.Observatory}] = true
+	}
+	var ownerID *uint
+	if userID, ok := GetUserIDFromContext(ctx); ok {
+		ownerID = &userID
+	}
+	fresh := make([]ds.Observation, 0, len(observations))
+	for _, o := range observations {
+		k := key{o.ObservedAt.UnixMicro(), o.Observatory}
+		if existing[k] {
+			continue
+		}
+		existing[k] = true
+		o.CometID = comet.ID
+		o.OwnerID = ownerID
+		fresh = append(fresh, o)
+	}
+
+	if err := h.Repository.AddObservations(fresh); err != nil {
+		logrus.WithError(err).Error("failed to save observations")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save observations"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"imported":     len(fresh),
+		"skipped":      len(observations) - len(fresh),
+		"observations": fresh,
+	})
+}
+
+// readMPCReport читает и разбирает загруженный файл отчёта MPC.
+// При ошибке пишет ответ 400 и возвращает false.
+func readMPCReport(ctx *gin.Context, fh *multipart.FileHeader) ([]mpc.Observation, bool) {
+	if fh.Size > maxMPCReportSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "MPC report is too large"})
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read MPC report"})
+		return nil, false
+	}
+	defer f.Close()
+	return parseMPCReport(ctx, f)
+}
+
+// parseMPCReport разбирает отчёт MPC; ошибки отдельных строк возвращаются в details.
+// При ошибке пишет ответ 400 и возвращает false.
+func parseMPCReport(ctx *gin.Context, r io.Reader) ([]mpc.Observation, bool) {
+	parsed, err := mpc.ParseObservations(r)
+	var parseErr mpc.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid MPC report", "details": parseErr})
+		return nil, false
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read MPC report: " + err.Error()})
+		return nil, false
+	case len(parsed) == 0:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "MPC report has no observations"})
+		return nil, false
+	}
+	return parsed, true
+}
+
+// mpcInputs переводит наблюдения отчёта MPC во входные наблюдения и возвращает номера их строк.
+// Для подвижного наблюдателя вместо кода 247 передаются его координаты.
+func mpcInputs(parsed []mpc.Observation) ([]observationInput, []int) {
+	inputs := make([]observationInput, len(parsed))
+	lines := make([]int, len(parsed))
+	for i, o := range parsed {
+		inputs[i] = observationInput{
+			RA:          o.RA,
+			Dec:         o.Dec,
+			Time:        orbitclient.FormatTime(o.Time),
+			Magnitude:   o.Magnitude,
+			Band:        o.Band,
+			Observatory: o.Observatory,
+		}
+		if r := o.Roving; r != nil {
+			inputs[i].Observatory = ""
+			inputs[i].Latitude, inputs[i].Longitude, inputs[i].Altitude = &r.Latitude, &r.Longitude, &r.Altitude
+		}
+		lines[i] = o.Line
+	}
+	return inputs, lines
+}
+
+// adesInputs переводит наблюдения документа ADES во входные наблюдения и возвращает номера
+// их строк. Геоцентрические наблюдения (код 500) передаются без кода, подвижные (247) — координатами.
+func adesInputs(parsed []ades.Observation) ([]observationInput, []int) {
+	inputs := make([]observationInput, len(parsed))
+	lines := make([]int, len(parsed))
+	for i, o := range parsed {
+		inputs[i] = observationInput{
+			RA:          o.RA,
+			Dec:         o.Dec,
+			Time:        orbitclient.FormatTime(o.ObsTime),
+			Magnitude:   o.Mag,
+			Band:        o.Band,
+			Observatory: o.Stn,
+			SigmaRA:     o.RmsRA,
+			SigmaDec:    o.RmsDec,
+		}
+		switch {
+		case o.Roving != nil:
+			inputs[i].Observatory = ""
+			inputs[i].Latitude, inputs[i].Longitude, inputs[i].Altitude = &o.Roving.Latitude, &o.Roving.Longitude, &o.Roving.Altitude
+		case o.Stn == ades.StnGeocentric:
+			inputs[i].Observatory = ""
+		}
+		lines[i] = o.Line
+	}
+	return inputs, lines
+}
+
+// lineErrors сопоставляет ошибки проверки наблюдений строкам отчёта.
+func lineErrors(errs []observationError, lines []int) []mpc.LineError {
+	out := make([]mpc.LineError, len(errs))
+	for i, e := range errs {
+		out[i] = mpc.LineError{Line: lines[e.Index], Error: e.Field + ": " + e.Error}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-server/internal/app/obsinput"
+	"backend-server/internal/app/orbitclient"
+)
+
+// inputValue keeps a JSON number or string as text so that it can be normalised with
+// a per-field error instead of failing the whole body decode
+type inputValue string
+
+func (v *inputValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = inputValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return errors.New("must be a number or a string")
+	}
+	*v = inputValue(n)
+	return nil
+}
+
+// rawObservation is an observation as typed by the user: RA in degrees, hours or sexagesimal,
+// Dec in degrees or DMS, time as ISO 8601, JD or MJD in the UTC or TT scale (see obsinput).
+// The remaining fields are taken as they are.
+type rawObservation struct {
+	observationInput
+	RA        inputValue `json:"ra"`
+	Dec       inputValue `json:"dec"`
+	Time      inputValue `json:"time"`
+	TimeScale string     `json:"time_scale"` // utc (default) or tt
+}
+
+// normalizeSubmitted normalises submitted observations. On failure it writes a 400 response
+// listing every invalid field and returns false.
+func normalizeSubmitted(c *gin.Context, raw []rawObservation) ([]observationInput, bool) {
+	inputs, validationErrors := normalizeObservations(raw)
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observations", "details": validationErrors})
+		return nil, false
+	}
+	return inputs, true
+}
+
+// normalizeObservations converts raw observations into canonical inputs: RA and Dec in degrees
+// and time as a UTC string. Every invalid field is reported, not just the first one.
+func normalizeObservations(raw []rawObservation) ([]observationInput, []observationError) {
+	inputs := make([]observationInput, len(raw))
+	var errs []observationError
+	fail := func(i int, field string, err error) {
+		errs = append(errs, observationError{Index: i, Field: field, Error: err.Error()})
+	}
+	for i, r := range raw {
+		in := r.observationInput
+		var err error
+		if in.RA, err = obsinput.ParseRA(string(r.RA)); err != nil {
+			fail(i, "ra", err)
+		}
+		if in.Dec, err = obsinput.ParseDec(string(r.Dec)); err != nil {
+			fail(i, "dec", err)
+		}
+		scale, err := obsinput.ParseScale(r.TimeScale)
+		if err != nil {
+			fail(i, "time_scale", err)
+		} else if t, err := obsinput.ParseTime(string(r.Time), scale); err != nil {
+			fail(i, "time", err)
+		} else {
+			in.Time = orbitclient.FormatTime(t)
+		}
+		inputs[i] = in
+	}
+	return inputs, errs
+}
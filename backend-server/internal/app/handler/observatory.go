@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListObservatories возвращает страницу справочника обсерваторий MPC.
+// Параметры: search (код или подстрока названия), page и page_size.
+func (h *Handler) ListObservatories(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_size"})
+		return
+	}
+
+	observatories, total, err := h.Repository.ListObservatories(ctx.Query("search"), pageSize, (page-1)*pageSize)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list observatories")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list observatories"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"items":     observatories,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
@@ -1,94 +1,595 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/observer"
 	"backend-server/internal/app/orbitclient"
 
 	"github.com/sirupsen/logrus"
 )
 
-// CalculateOrbitHandler accepts observations JSON and forwards to python orbit service
+// defaultRejectSigma is the outlier rejection threshold used when the client does not set one
+const defaultRejectSigma = 3
+
+// observationInput is a single observation as submitted by the client. The observing site
+// is either an MPC observatory code or explicit geodetic coordinates; without both the
+// observation is treated as geocentric.
+type observationInput struct {
+	RA          float64  `json:"ra"`
+	Dec         float64  `json:"dec"`
+	Time        string   `json:"time"`
+	Notes       string   `json:"notes"`
+	Force       string   `json:"force"`
+	Magnitude   *float64 `json:"magnitude,omitempty"`
+	Band        string   `json:"band,omitempty"`
+	Observatory string   `json:"observatory,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`  // deg
+	Longitude   *float64 `json:"longitude,omitempty"` // deg east
+	Altitude    *float64 `json:"altitude,omitempty"`  // m above the WGS84 ellipsoid
+	SigmaRA     *float64 `json:"sigma_ra,omitempty"`  // arcsec, uncertainty of RA·cos(Dec)
+	SigmaDec    *float64 `json:"sigma_dec,omitempty"` // arcsec
+}
+
+// fitOptions controls outlier handling of an orbit fit and the motion model of its
+// close-approach search; nil RejectSigma means defaultRejectSigma
+type fitOptions struct {
+	Loss        string   `json:"loss"`
+	RejectSigma *float64 `json:"reject_sigma"`
+	Model       string   `json:"model"` // two_body (default) or n_body
+}
+
+// observationError describes a validation failure of one submitted observation
+type observationError struct {
+	Index int    `json:"index"`
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// orbitSubmission is a validated set of observations submitted for orbit determination
+type orbitSubmission struct {
+	Name              string
+	Inputs            []observationInput
+	Fit               fitOptions
+	Photo             *multipart.FileHeader
+	ObservationPhotos map[int]*multipart.FileHeader
+	Observatories     map[string]ds.Observatory
+
+	// Observations and Request are the inputs converted for storage and for the orbit backend
+	Observations []ds.Observation
+	Request      *orbitclient.OrbitRequest
+
+	// Lines holds the report line of every input when observations come from an MPC file
+	Lines []int
+}
+
+// CalculateOrbitHandler accepts observations JSON and computes the orbit with the configured backend
 func (h *Handler) CalculateOrbitHandler(c *gin.Context) {
-	// Support both JSON body and multipart/form-data (with photo and name)
-	var obsReq []orbitclient.ObservationReq
+	sub, ok := h.parseOrbitSubmission(c)
+	if !ok {
+		return
+	}
 
-	contentType := c.ContentType()
-	var cometName string
-	var photoHeader *multipart.FileHeader
+	// Владелец кометы — текущий пользователь; у анонимного расчёта владельца нет
+	var ownerID *uint
+	if userID, ok := GetUserIDFromContext(c); ok {
+		ownerID = &userID
+	}
 
-	if strings.HasPrefix(contentType, "multipart/") {
-		// multipart: observations as JSON string in form field, plus name and photo file
-		cometName = c.PostForm("name")
-		observationsStr := c.PostForm("observations")
-		if observationsStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "observations form field is required"})
+	comet := newComet(sub.Name, ownerID, sub.Observations)
+	res, err := h.saveCometOrbit(c.Request.Context(), comet, sub.Request)
+	if err != nil {
+		var oErr *orbitError
+		if errors.As(err, &oErr) {
+			logrus.WithError(err).Error("failed to calculate orbit")
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 			return
 		}
-		if err := json.Unmarshal([]byte(observationsStr), &obsReq); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observations JSON: " + err.Error()})
-			return
+		logrus.WithError(err).Error("failed to save comet orbit")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save comet orbit"})
+		return
+	}
+
+	// Если пришла фотография — загрузим в Minio и обновим запись
+	if sub.Photo != nil {
+		_, err := h.Repository.UploadCometImage(comet.ID, sub.Photo)
+		if err != nil {
+			logrus.WithError(err).Error("failed to upload comet image")
+			// не фатализируем запрос — логируем и продолжаем
+		}
+	}
+
+	// Фотографии наблюдений загружаются после сохранения, когда известны их ID
+	for i, file := range sub.ObservationPhotos {
+		if _, err := h.Repository.UploadObservationPhoto(comet.Observations[i].ID, file); err != nil {
+			logrus.WithError(err).WithField("observation", i).Error("failed to upload observation photo")
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// refitRequest is the body of RefitComet: fit options and forced decisions for stored observations
+type refitRequest struct {
+	fitOptions
+	Observations []struct {
+		ID    uint   `json:"id" binding:"required"`
+		Force string `json:"force"`
+	} `json:"observations"`
+}
+
+// RefitComet recomputes the orbit of a stored comet from its observations. Observations listed
+// in the body get the given force ("include", "exclude" or "" to let rejection decide); the
+// others keep their stored force.
+func (h *Handler) RefitComet(c *gin.Context) {
+	comet, ok := h.loadComet(c)
+	if !ok {
+		return
+	}
+	if !canModifyComet(c, comet) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var body refitRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := body.fitOptions.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	byID := make(map[uint]*ds.Observation, len(comet.Observations))
+	for i := range comet.Observations {
+		byID[comet.Observations[i].ID] = &comet.Observations[i]
+	}
+	var validationErrors []observationError
+	for i, o := range body.Observations {
+		obs, ok := byID[o.ID]
+		switch {
+		case !ok:
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "id", Error: fmt.Sprintf("observation %d does not belong to the comet", o.ID)})
+		case !validForce(o.Force):
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "force", Error: fmt.Sprintf("unknown force %q", o.Force)})
+		default:
+			obs.Force = o.Force
+		}
+	}
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observations", "details": validationErrors})
+		return
+	}
+
+	codes := make([]string, 0, len(comet.Observations))
+	for _, o := range comet.Observations {
+		codes = append(codes, o.Observatory)
+	}
+	observatories, err := h.Repository.GetObservatories(distinctCodes(codes))
+	if err != nil {
+		logrus.WithError(err).Error("failed to load observatories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load observatories"})
+		return
+	}
+
+	req := &orbitclient.OrbitRequest{Observations: make([]orbitclient.ObservationReq, len(comet.Observations))}
+	body.fitOptions.apply(req)
+	for i, o := range comet.Observations {
+		site, _, err := observerOf(o.Observatory, o.Latitude, o.Longitude, o.Altitude, observatories)
+		if err != nil {
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "observatory", Error: err.Error()})
+			continue
+		}
+		req.Observations[i] = orbitclient.ObservationReq{
+			RA:       o.RA,
+			Dec:      o.Dec,
+			Time:     orbitclient.FormatTime(o.ObservedAt),
+			Force:    o.Force,
+			Observer: site,
+			SigmaRA:  o.SigmaRA,
+			SigmaDec: o.SigmaDec,
+		}
+	}
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "stored observations have unknown observing sites", "details": validationErrors})
+		return
+	}
+
+	res, err := h.Orbit.CalculateOrbit(c.Request.Context(), req)
+	if err != nil {
+		logrus.WithError(err).Error("failed to calculate orbit")
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	approaches, err := h.applyOrbit(comet, res, nbody.Model(req.Model))
+	if err != nil {
+		logrus.WithError(err).Error("failed to calculate orbit")
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Repository.UpdateCometOrbit(comet, approaches); err != nil {
+		logrus.WithError(err).Error("failed to save comet orbit")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save comet orbit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comet)
+}
+
+// parseOrbitSubmission reads observations from a JSON or multipart body, resolves their
+// observatory codes and validates them. On failure it writes a 400 response and returns false.
+func (h *Handler) parseOrbitSubmission(c *gin.Context) (*orbitSubmission, bool) {
+	// Support both JSON body and multipart/form-data (with photo and name)
+	sub := &orbitSubmission{}
+	var ok bool
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		// multipart: observations as JSON string in form field, plus name and photo file
+		sub.Name = c.PostForm("name")
+		sub.Fit.Loss = c.PostForm("loss")
+		sub.Fit.Model = c.PostForm("model")
+		if v := c.PostForm("reject_sigma"); v != "" {
+			sigma, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reject_sigma"})
+				return nil, false
+			}
+			sub.Fit.RejectSigma = &sigma
+		}
+		// observations come either as a JSON form field or as an MPC 80-column report file
+		if report, err := c.FormFile("mpc"); err == nil {
+			parsed, ok := readMPCReport(c, report)
+			if !ok {
+				return nil, false
+			}
+			sub.Inputs, sub.Lines = mpcInputs(parsed)
+		} else {
+			observationsStr := c.PostForm("observations")
+			if observationsStr == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "observations form field or mpc file is required"})
+				return nil, false
+			}
+			var raw []rawObservation
+			if err := json.Unmarshal([]byte(observationsStr), &raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observations JSON: " + err.Error()})
+				return nil, false
+			}
+			if sub.Inputs, ok = normalizeSubmitted(c, raw); !ok {
+				return nil, false
+			}
 		}
 		file, _ := c.FormFile("photo")
-		photoHeader = file
+		sub.Photo = file
+
+		// per-observation photos come as observation_photo_<index> files
+		sub.ObservationPhotos = make(map[int]*multipart.FileHeader)
+		for i := range sub.Inputs {
+			if file, err := c.FormFile(fmt.Sprintf("observation_photo_%d", i)); err == nil {
+				sub.ObservationPhotos[i] = file
+			}
+		}
 	} else {
 		// assume application/json
 		var body struct {
-			Name         string `json:"name"`
-			Observations []struct {
-				RA   float64 `json:"ra" binding:"required"`
-				Dec  float64 `json:"dec" binding:"required"`
-				Time string  `json:"time" binding:"required"`
-			} `json:"observations" binding:"required"`
+			fitOptions
+			Name         string           `json:"name"`
+			Observations []rawObservation `json:"observations" binding:"required"`
 		}
 		if err := c.ShouldBindJSON(&body); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+			return nil, false
 		}
-		cometName = body.Name
-		for _, o := range body.Observations {
-			obsReq = append(obsReq, orbitclient.ObservationReq{RA: o.RA, Dec: o.Dec, Time: o.Time})
+		sub.Name = body.Name
+		sub.Fit = body.fitOptions
+		if sub.Inputs, ok = normalizeSubmitted(c, body.Observations); !ok {
+			return nil, false
 		}
 	}
 
-	if len(obsReq) < 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "need at least 5 observations"})
-		return
+	if err := sub.Fit.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
 	}
 
-	// Создаём запись кометы в базе (с именем, если указано)
-	cometNameTrim := strings.TrimSpace(cometName)
-	if cometNameTrim == "" {
-		cometNameTrim = "Unnamed comet"
+	if len(sub.Inputs) < 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "need at least 5 observations"})
+		return nil, false
 	}
-	comet, err := h.Repository.CreateComet(cometNameTrim)
+
+	observatories, err := h.lookupObservatories(sub.Inputs)
 	if err != nil {
-		logrus.WithError(err).Error("failed to create comet record")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create comet record"})
-		return
+		logrus.WithError(err).Error("failed to load observatories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load observatories"})
+		return nil, false
 	}
+	sub.Observatories = observatories
 
-	// Если пришла фотография — загрузим в Minio и обновим запись
-	if photoHeader != nil {
-		_, err := h.Repository.UploadCometImage(comet.ID, photoHeader)
+	// Все времена наблюдений проверяются до записи чего-либо в базу
+	var validationErrors []observationError
+	sub.Observations, sub.Request, validationErrors = buildObservations(sub.Inputs, sub.Fit, sub.Observatories)
+	if len(validationErrors) > 0 {
+		if sub.Lines != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid MPC report", "details": lineErrors(validationErrors, sub.Lines)})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observations", "details": validationErrors})
+		return nil, false
+	}
+
+	return sub, true
+}
+
+// validate checks the loss function name, the rejection threshold and the model
+func (f fitOptions) validate() error {
+	switch f.Loss {
+	case "", orbitclient.LossLinear, orbitclient.LossHuber, orbitclient.LossCauchy:
+	default:
+		return fmt.Errorf("unknown loss %q", f.Loss)
+	}
+	if f.RejectSigma != nil && *f.RejectSigma < 0 {
+		return errors.New("reject_sigma must not be negative")
+	}
+	if _, err := nbody.ParseModel(f.Model); err != nil {
+		return err
+	}
+	return nil
+}
+
+// apply copies the options onto an orbit request
+func (f fitOptions) apply(req *orbitclient.OrbitRequest) {
+	req.Loss = f.Loss
+	if model, _ := nbody.ParseModel(f.Model); model != nbody.ModelTwoBody {
+		req.Model = string(model)
+	}
+	req.RejectSigma = defaultRejectSigma
+	if f.RejectSigma != nil {
+		req.RejectSigma = *f.RejectSigma
+	}
+}
+
+// validForce reports whether force is an accepted value of observation force
+func validForce(force string) bool {
+	return force == "" || force == orbitclient.ForceInclude || force == orbitclient.ForceExclude
+}
+
+// lookupObservatories loads the observatories referenced by the submitted observations
+func (h *Handler) lookupObservatories(inputs []observationInput) (map[string]ds.Observatory, error) {
+	codes := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		codes = append(codes, in.Observatory)
+	}
+	return h.Repository.GetObservatories(distinctCodes(codes))
+}
+
+// distinctCodes returns the non-empty normalized observatory codes without duplicates
+func distinctCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	var out []string
+	for _, code := range codes {
+		code = normalizeObservatoryCode(code)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+// normalizeObservatoryCode trims an MPC observatory code; letter codes are upper case
+func normalizeObservatoryCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// observerOf resolves the observing site from an observatory code or geodetic coordinates.
+// It returns nil for a geocentric observation and the name of the offending field on error.
+func observerOf(code string, lat, lon, alt *float64, observatories map[string]ds.Observatory) (*orbitclient.Observer, string, error) {
+	code = normalizeObservatoryCode(code)
+	switch {
+	case code != "" && (lat != nil || lon != nil):
+		return nil, "observatory", errors.New("give either an observatory code or coordinates, not both")
+	case code != "":
+		o, ok := observatories[code]
+		if !ok {
+			return nil, "observatory", fmt.Errorf("unknown observatory code %q", code)
+		}
+		if o.Longitude == nil || o.RhoCos == nil || o.RhoSin == nil {
+			return nil, "observatory", fmt.Errorf("observatory %s has no fixed position on Earth", code)
+		}
+		return &orbitclient.Observer{Longitude: *o.Longitude, RhoCos: *o.RhoCos, RhoSin: *o.RhoSin}, "", nil
+	case lat == nil && lon == nil:
+		return nil, "", nil
+	case lat == nil || *lat < -90 || *lat > 90:
+		return nil, "latitude", errors.New("latitude must be between -90 and 90")
+	case lon == nil || *lon < -180 || *lon > 360:
+		return nil, "longitude", errors.New("longitude must be between -180 and 360")
+	}
+	altitude := 0.0
+	if alt != nil {
+		altitude = *alt
+	}
+	site := observer.FromGeodetic(*lat, *lon, altitude)
+	return &orbitclient.Observer{Longitude: site.Longitude, RhoCos: site.RhoCos, RhoSin: site.RhoSin}, "", nil
+}
+
+// buildObservations converts submitted observations into ds rows and the orbit backend request.
+// observatories must hold the observatories referenced by the inputs.
+func buildObservations(inputs []observationInput, fit fitOptions, observatories map[string]ds.Observatory) ([]ds.Observation, *orbitclient.OrbitRequest, []observationError) {
+	req := &orbitclient.OrbitRequest{Observations: make([]orbitclient.ObservationReq, 0, len(inputs))}
+	fit.apply(req)
+
+	observations := make([]ds.Observation, 0, len(inputs))
+	var validationErrors []observationError
+	for i, in := range inputs {
+		if !validForce(in.Force) {
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "force", Error: fmt.Sprintf("unknown force %q", in.Force)})
+			continue
+		}
+		observedAt, err := orbitclient.ParseTime(in.Time)
 		if err != nil {
-			logrus.WithError(err).Error("failed to upload comet image")
-			// не фатализируем запрос — логируем и продолжаем
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "time", Error: err.Error()})
+			continue
+		}
+		if in.SigmaRA != nil && *in.SigmaRA <= 0 {
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "sigma_ra", Error: "must be positive"})
+			continue
+		}
+		if in.SigmaDec != nil && *in.SigmaDec <= 0 {
+			validationErrors = append(validationErrors, observationError{Index: i, Field: "sigma_dec", Error: "must be positive"})
+			continue
 		}
+		site, field, err := observerOf(in.Observatory, in.Latitude, in.Longitude, in.Altitude, observatories)
+		if err != nil {
+			validationErrors = append(validationErrors, observationError{Index: i, Field: field, Error: err.Error()})
+			continue
+		}
+		req.Observations = append(req.Observations, orbitclient.ObservationReq{
+			RA:       in.RA,
+			Dec:      in.Dec,
+			Time:     in.Time,
+			Force:    in.Force,
+			Observer: site,
+			SigmaRA:  in.SigmaRA,
+			SigmaDec: in.SigmaDec,
+		})
+		observations = append(observations, ds.Observation{
+			RA:          in.RA,
+			Dec:         in.Dec,
+			ObservedAt:  observedAt,
+			Notes:       in.Notes,
+			Force:       in.Force,
+			Magnitude:   in.Magnitude,
+			Band:        in.Band,
+			Observatory: normalizeObservatoryCode(in.Observatory),
+			Latitude:    in.Latitude,
+			Longitude:   in.Longitude,
+			Altitude:    in.Altitude,
+			SigmaRA:     in.SigmaRA,
+			SigmaDec:    in.SigmaDec,
+		})
+	}
+	return observations, req, validationErrors
+}
+
+// newComet returns an unsaved comet with the given observations; an empty name becomes "Unnamed comet"
+func newComet(name string, ownerID *uint, observations []ds.Observation) *ds.Comet {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Unnamed comet"
 	}
+	return &ds.Comet{Name: name, OwnerID: ownerID, Observations: observations}
+}
 
-	// Теперь вызываем внешний python сервис для расчёта
-	res, err := orbitclient.CalculateOrbit(obsReq)
+// saveCometOrbit computes the orbit of a new comet and stores it with its observations and
+// close approaches. Orbit computation failures are returned as *orbitError.
+func (h *Handler) saveCometOrbit(ctx context.Context, comet *ds.Comet, req *orbitclient.OrbitRequest) (*orbitclient.OrbitResponse, error) {
+	res, err := h.calculateOrbit(ctx, req)
 	if err != nil {
-		logrus.WithError(err).Error("failed to calculate orbit via python service")
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
-		return
+		return nil, err
+	}
+	if err := h.storeCometOrbit(comet, res, nbody.Model(req.Model)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// calculateOrbit asks the orbit backend for a fit, wrapping its failures in *orbitError
+func (h *Handler) calculateOrbit(ctx context.Context, req *orbitclient.OrbitRequest) (*orbitclient.OrbitResponse, error) {
+	res, err := h.Orbit.CalculateOrbit(ctx, req)
+	if err != nil {
+		return nil, &orbitError{err: err, permanent: errors.Is(err, orbitclient.ErrFitFailed)}
 	}
+	return res, nil
+}
 
-	c.JSON(http.StatusOK, res)
+// storeCometOrbit applies a computed orbit to a new comet and stores it with its observations
+// and close approaches. The MOID and close-approach search run before the database is
+// touched, so the insert transaction stays short.
+func (h *Handler) storeCometOrbit(comet *ds.Comet, res *orbitclient.OrbitResponse, model nbody.Model) error {
+	approaches, err := h.applyOrbit(comet, res, model)
+	if err != nil {
+		return err
+	}
+
+	// Комета, её наблюдения и сближения сохраняются в одной транзакции только после
+	// успешного расчёта: при ошибке в базе ничего не остаётся
+	return h.Repository.CreateCometWithOrbit(comet, approaches)
+}
+
+// orbitError marks failures of the orbit computation itself, as opposed to database errors.
+// A permanent failure comes from the data rather than the environment: retrying the same
+// request gives the same result.
+type orbitError struct {
+	err       error
+	permanent bool
+}
+
+func (e *orbitError) Error() string { return e.err.Error() }
+
+func (e *orbitError) Unwrap() error { return e.err }
+
+// applyOrbit copies the fitted elements with their uncertainties, fit quality and residuals
+// onto the comet and its observations, computes its MOIDs and searches the new orbit for
+// close approaches in the given model, whichever backend fitted it
+func (h *Handler) applyOrbit(comet *ds.Comet, res *orbitclient.OrbitResponse, model nbody.Model) ([]ds.CloseApproach, error) {
+	tp, err := orbitclient.ParseTime(res.TimeOfPerihelion)
+	if err != nil {
+		return nil, &orbitError{err: fmt.Errorf("time_of_perihelion: %w", err), permanent: true}
+	}
+
+	comet.Q = res.PerihelionDistance
+	comet.A = orbitclient.SemiMajorAxis(res.PerihelionDistance, res.Eccentricity)
+	comet.E = res.Eccentricity
+	comet.I = res.Inclination
+	comet.Node = res.LongitudeOfAscendingNode
+	comet.ArgPeri = res.ArgumentOfPerihelion
+	comet.T = tp
+	// элементы приводятся к моменту прохождения перигелия
+	comet.Epoch = tp
+	comet.RMS = &res.RMS
+	comet.Chi2 = res.NormalizedChi2
+	comet.Iterations = res.Iterations
+	comet.Converged = res.Converged
+	comet.Covariance = res.Covariance
+	if s := res.Sigma; s != nil {
+		comet.SigmaQ = &s.PerihelionDistance
+		comet.SigmaE = &s.Eccentricity
+		comet.SigmaI = &s.Inclination
+		comet.SigmaNode = &s.LongitudeOfAscendingNode
+		comet.SigmaArgPeri = &s.ArgumentOfPerihelion
+		comet.SigmaT = &s.TimeOfPerihelion
+	} else {
+		comet.SigmaQ, comet.SigmaE, comet.SigmaI = nil, nil, nil
+		comet.SigmaNode, comet.SigmaArgPeri, comet.SigmaT = nil, nil, nil
+	}
+
+	// невязки приходят в порядке наблюдений запроса, в том же порядке созданы строки
+	if len(res.Residuals) != len(comet.Observations) {
+		return nil, &orbitError{err: fmt.Errorf("orbit backend returned %d residuals for %d observations", len(res.Residuals), len(comet.Observations)), permanent: true}
+	}
+	for i, r := range res.Residuals {
+		comet.Observations[i].ResidualRA = &r.RA
+		comet.Observations[i].ResidualDec = &r.Dec
+		comet.Observations[i].Rejected = r.Rejected
+	}
+
+	h.updateMOID(comet)
+	approaches, err := h.closeApproaches(comet, model)
+	if err != nil {
+		return nil, &orbitError{err: fmt.Errorf("close approach search: %w", err), permanent: true}
+	}
+	return approaches, nil
 }
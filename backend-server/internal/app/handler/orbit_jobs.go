@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/redis"
+)
+
+// orbitJobPayload is what an orbit job stores in Redis to be computed by a worker
+type orbitJobPayload struct {
+	Name         string           `json:"name"`
+	Fit          fitOptions       `json:"fit"`
+	Observations []jobObservation `json:"observations"`
+	ImageURL     string           `json:"image_url,omitempty"`
+}
+
+// jobObservation is a submitted observation with its photo already uploaded to Minio
+type jobObservation struct {
+	observationInput
+	PhotoURL string `json:"photo_url,omitempty"`
+}
+
+// orbitJobResponse is the public view of an orbit job
+type orbitJobResponse struct {
+	ID          string                     `json:"id"`
+	Status      redis.OrbitJobStatus       `json:"status"`
+	Attempts    int                        `json:"attempts"`
+	MaxAttempts int                        `json:"max_attempts"`
+	CometID     uint                       `json:"comet_id,omitempty"`
+	Result      *orbitclient.OrbitResponse `json:"result,omitempty"`
+	Error       string                     `json:"error,omitempty"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+}
+
+// orbitJobEvent is published to Redis on every job status change and computation step
+type orbitJobEvent struct {
+	Type     string                     `json:"type"` // "status" or "progress"
+	Job      *orbitJobResponse          `json:"job,omitempty"`
+	Progress *orbitclient.ProgressEvent `json:"progress,omitempty"`
+}
+
+// orbitJobKeepAlive is the interval of SSE comments that keep idle proxies from closing the stream
+const orbitJobKeepAlive = 15 * time.Second
+
+// CreateOrbitJob validates observations, uploads attached photos and queues the orbit computation.
+// It answers 202 with the job ID right away; the result is polled with GetOrbitJob.
+func (h *Handler) CreateOrbitJob(c *gin.Context) {
+	sub, ok := h.parseOrbitSubmission(c)
+	if !ok {
+		return
+	}
+
+	jobID := uuid.NewString()
+	payload := orbitJobPayload{
+		Name:         sub.Name,
+		Fit:          sub.Fit,
+		Observations: make([]jobObservation, len(sub.Inputs)),
+	}
+	for i, in := range sub.Inputs {
+		payload.Observations[i].observationInput = in
+	}
+
+	// Фотографии загружаются сразу: файлы запроса недоступны воркеру
+	if sub.Photo != nil {
+		url, err := h.Repository.UploadJobFile(jobID, "photo", sub.Photo)
+		if err != nil {
+			logrus.WithError(err).Error("failed to upload comet image")
+		}
+		payload.ImageURL = url
+	}
+	for i, file := range sub.ObservationPhotos {
+		url, err := h.Repository.UploadJobFile(jobID, fmt.Sprintf("observation-%d", i), file)
+		if err != nil {
+			logrus.WithError(err).WithField("observation", i).Error("failed to upload observation photo")
+		}
+		payload.Observations[i].PhotoURL = url
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create orbit job"})
+		return
+	}
+
+	job := &redis.OrbitJob{
+		ID:          jobID,
+		MaxAttempts: h.Config.Orbit.JobAttempts,
+		Payload:     b,
+	}
+	if userID, ok := GetUserIDFromContext(c); ok {
+		job.OwnerID = &userID
+	}
+
+	if err := h.Redis.EnqueueOrbitJob(c.Request.Context(), job); err != nil {
+		logrus.WithError(err).Error("failed to enqueue orbit job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create orbit job"})
+		return
+	}
+
+	c.Header("Location", "/api/orbit/jobs/"+jobID)
+	c.JSON(http.StatusAccepted, gin.H{"id": jobID, "status": job.Status})
+}
+
+// GetOrbitJob reports the status of an orbit job and its result once it has succeeded
+func (h *Handler) GetOrbitJob(c *gin.Context) {
+	job, ok := h.loadOrbitJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newOrbitJobResponse(job))
+}
+
+// StreamOrbitJobEvents streams job status changes and computation progress as Server-Sent Events.
+// The first event is the current status; the stream ends once the job succeeds or fails.
+func (h *Handler) StreamOrbitJobEvents(c *gin.Context) {
+	job, ok := h.loadOrbitJob(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, unsubscribe, err := h.Redis.SubscribeOrbitJobEvents(ctx, job.ID)
+	if err != nil {
+		logrus.WithError(err).Error("failed to subscribe to orbit job events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe to orbit job events"})
+		return
+	}
+	defer unsubscribe()
+
+	// статус перечитывается после подписки, чтобы не пропустить изменение между ними
+	job, err = h.Redis.GetOrbitJob(ctx, job.ID)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load orbit job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load orbit job"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx не должен буферизовать поток
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("status", newOrbitJobResponse(job))
+	c.Writer.Flush()
+	if isFinished(job.Status) {
+		return
+	}
+
+	keepAlive := time.NewTicker(orbitJobKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case msg, ok := <-events:
+			if !ok {
+				return false
+			}
+			var ev orbitJobEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				logrus.WithError(err).Warn("invalid orbit job event")
+				return true
+			}
+			switch {
+			case ev.Type == "status" && ev.Job != nil:
+				c.SSEvent("status", ev.Job)
+				return !isFinished(ev.Job.Status)
+			case ev.Type == "progress" && ev.Progress != nil:
+				c.SSEvent("progress", ev.Progress)
+			}
+			return true
+		}
+	})
+}
+
+// isFinished reports whether the job reached a final status
+func isFinished(status redis.OrbitJobStatus) bool {
+	return status == redis.OrbitJobSucceeded || status == redis.OrbitJobFailed
+}
+
+// newOrbitJobResponse builds the public view of a job
+func newOrbitJobResponse(job *redis.OrbitJob) *orbitJobResponse {
+	resp := &orbitJobResponse{
+		ID:          job.ID,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		CometID:     job.CometID,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+	if job.Status == redis.OrbitJobSucceeded && len(job.Result) > 0 {
+		var res orbitclient.OrbitResponse
+		if err := json.Unmarshal(job.Result, &res); err == nil {
+			resp.Result = &res
+		}
+	}
+	return resp
+}
+
+// loadOrbitJob reads the job from the :id path parameter; only its owner or an admin may see it.
+// On failure it writes the response and returns false.
+func (h *Handler) loadOrbitJob(c *gin.Context) (*redis.OrbitJob, bool) {
+	job, err := h.Redis.GetOrbitJob(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, redis.ErrOrbitJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "orbit job not found"})
+		return nil, false
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to load orbit job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load orbit job"})
+		return nil, false
+	}
+
+	if !isOwnerOrAdmin(c, job.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "orbit job not found"})
+		return nil, false
+	}
+
+	return job, true
+}
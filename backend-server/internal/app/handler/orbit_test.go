@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-server/internal/app/config"
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/orbitclient"
+)
+
+// newTestHandler returns a handler backed by fake without a database: the requests below
+// carry coordinates instead of observatory codes, and fail before anything is stored
+func newTestHandler(fake *orbitclient.FakeCalculator) *Handler {
+	return &Handler{Config: &config.Config{}, Orbit: fake}
+}
+
+func postOrbit(t *testing.T, h *Handler, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/orbit/calculate", h.CalculateOrbitHandler)
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/orbit/calculate", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// testObservations returns n observations a day apart typed as a user would: RA in
+// sexagesimal hours, Dec in DMS and the site as geodetic coordinates
+func testObservations(n int) []map[string]any {
+	obs := make([]map[string]any, n)
+	for i := range obs {
+		obs[i] = map[string]any{
+			"ra":        fmt.Sprintf("12 %02d 00", 10+i),
+			"dec":       "+05 30 00",
+			"time":      fmt.Sprintf("2024-03-%02dT00:00:00Z", 1+i),
+			"latitude":  43.65,
+			"longitude": 41.43,
+		}
+	}
+	return obs
+}
+
+func TestCalculateOrbitSendsNormalizedObservations(t *testing.T) {
+	fake := orbitclient.NewFakeCalculator(nil)
+	fake.Err = fmt.Errorf("%w: no initial orbit", orbitclient.ErrFitFailed)
+	h := newTestHandler(fake)
+
+	w := postOrbit(t, h, map[string]any{"name": "C/2024 T1", "loss": "huber", "observations": testObservations(5)})
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadGateway, w.Body)
+	}
+
+	reqs := fake.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("backend received %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.Loss != orbitclient.LossHuber {
+		t.Errorf("loss = %q, want %q", req.Loss, orbitclient.LossHuber)
+	}
+	if len(req.Observations) != 5 {
+		t.Fatalf("backend received %d observations, want 5", len(req.Observations))
+	}
+	for i, o := range req.Observations {
+		wantRA := (12 + float64(10+i)/60) * 15
+		if diff := o.RA - wantRA; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("observation %d: ra = %v, want %v", i, o.RA, wantRA)
+		}
+		if diff := o.Dec - 5.5; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("observation %d: dec = %v, want 5.5", i, o.Dec)
+		}
+		if o.Observer == nil {
+			t.Errorf("observation %d: no observer site", i)
+		}
+	}
+}
+
+func TestCalculateOrbitRejectsInvalidInputWithoutCallingBackend(t *testing.T) {
+	fake := orbitclient.NewFakeCalculator(nil)
+	h := newTestHandler(fake)
+
+	obs := testObservations(5)
+	obs[2]["ra"] = "25h"
+	w := postOrbit(t, h, map[string]any{"observations": obs})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+	if n := len(fake.Requests()); n != 0 {
+		t.Errorf("backend received %d requests, want 0", n)
+	}
+}
+
+func TestSaveCometOrbitRejectsResidualMismatch(t *testing.T) {
+	fake := orbitclient.NewFakeCalculator(&orbitclient.OrbitResponse{
+		PerihelionDistance: 1.2,
+		Eccentricity:       0.9,
+		TimeOfPerihelion:   "2024-05-01T00:00:00Z",
+		Residuals:          make([]orbitclient.Residual, 2),
+	})
+	h := newTestHandler(fake)
+
+	comet := newComet(" ", nil, make([]ds.Observation, 3))
+	if comet.Name != "Unnamed comet" {
+		t.Errorf("name = %q, want %q", comet.Name, "Unnamed comet")
+	}
+	_, err := h.saveCometOrbit(context.Background(), comet, &orbitclient.OrbitRequest{})
+
+	var oErr *orbitError
+	if !errors.As(err, &oErr) {
+		t.Fatalf("err = %v, want *orbitError", err)
+	}
+	if !strings.Contains(err.Error(), "2 residuals for 3 observations") {
+		t.Errorf("err = %v", err)
+	}
+	if !permanentJobError(err) {
+		t.Error("residual mismatch must not be retried")
+	}
+}
+
+func TestSaveCometOrbitRetriesOnlyTransientFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"fit failed", fmt.Errorf("%w: singular normal matrix", orbitclient.ErrFitFailed), true},
+		{"backend unavailable", fmt.Errorf("%w: connection refused", orbitclient.ErrUnavailable), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := orbitclient.NewFakeCalculator(nil)
+			fake.Err = tc.err
+			h := newTestHandler(fake)
+
+			_, err := h.saveCometOrbit(context.Background(), newComet("C/2024 T1", nil, nil), &orbitclient.OrbitRequest{})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			if got := permanentJobError(err); got != tc.permanent {
+				t.Errorf("permanentJobError = %v, want %v", got, tc.permanent)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/risk"
+)
+
+const (
+	defaultRiskClones = 1000
+	maxRiskClones     = 5000
+	maxRiskYears      = 50
+)
+
+// riskStats — распределение величины по клонам.
+type riskStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std"`
+	P05    float64 `json:"p05"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+}
+
+// riskTimeStats — распределение момента сближения; разброс в сутках.
+type riskTimeStats struct {
+	Min        time.Time `json:"min"`
+	Max        time.Time `json:"max"`
+	Mean       time.Time `json:"mean"`
+	StdDevDays float64   `json:"std_days"`
+	P05        time.Time `json:"p05"`
+	P50        time.Time `json:"p50"`
+	P95        time.Time `json:"p95"`
+}
+
+// riskResponse — ответ оценки риска сближения.
+type riskResponse struct {
+	CometID     uint    `json:"comet_id"`
+	ThresholdAU float64 `json:"threshold_au"`
+	Model       string  `json:"model"`
+	Clones      int     `json:"clones"`
+	Invalid     int     `json:"invalid"`
+	Within      int     `json:"within_threshold"`
+	Fraction    float64 `json:"fraction_within"`
+	Nominal     struct {
+		Time       time.Time `json:"time"`
+		DistanceAU float64   `json:"distance_au"`
+	} `json:"nominal"`
+	Distance *riskStats     `json:"distance_au,omitempty"`
+	Time     *riskTimeStats `json:"time,omitempty"`
+}
+
+// AssessCometRisk оценивает методом Монте-Карло, насколько неопределённо сближение кометы
+// с Землёй: из ковариации орбиты выбираются клоны, и для каждого ищется минимальное расстояние.
+// Параметры запроса: clones, threshold_au, years (длительность поиска от перигелия), seed
+// и model — two_body (по умолчанию) или n_body с возмущениями планет.
+func (h *Handler) AssessCometRisk(ctx *gin.Context) {
+	comet, ok := h.loadComet(ctx)
+	if !ok {
+		return
+	}
+	if len(comet.Covariance) != 6 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet orbit has no covariance; refit it with more observations"})
+		return
+	}
+
+	opts := risk.Options{
+		Clones:  defaultRiskClones,
+		Workers: h.Config.Orbit.RiskWorkers,
+		Planets: h.Planets,
+	}
+	var err error
+	if opts.Clones, err = queryInt(ctx, "clones", defaultRiskClones); err != nil || opts.Clones < 1 || opts.Clones > maxRiskClones {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "clones must be between 1 and " + strconv.Itoa(maxRiskClones)})
+		return
+	}
+	if opts.ThresholdAU, err = queryFloat(ctx, "threshold_au", 0.05); err != nil || opts.ThresholdAU <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "threshold_au must be positive"})
+		return
+	}
+	if opts.Years, err = queryFloat(ctx, "years", 5); err != nil || opts.Years <= 0 || opts.Years > maxRiskYears {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "years must be between 0 and " + strconv.Itoa(maxRiskYears)})
+		return
+	}
+	seed, err := queryInt(ctx, "seed", 0)
+	if err != nil || seed < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid seed"})
+		return
+	}
+	opts.Seed = uint64(seed)
+	if opts.Model, ok = queryModel(ctx); !ok {
+		return
+	}
+
+	nominal := risk.Elements{comet.Q, comet.E, comet.I, comet.Node, comet.ArgPeri, astrotime.TT(comet.T)}
+	var cov [6][6]float64
+	for i, row := range comet.Covariance {
+		if len(row) != 6 {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet covariance is malformed"})
+			return
+		}
+		copy(cov[i][:], row)
+	}
+
+	res, err := risk.Assess(ctx.Request.Context(), nominal, cov, opts)
+	if errors.Is(err, risk.ErrNoCovariance) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet covariance is not positive definite"})
+		return
+	}
+	if errors.Is(err, orbit.ErrInvalidElements) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "comet has no valid orbital elements"})
+		return
+	}
+	if h.ephemerisOutOfRange(ctx, err) {
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("failed to assess close approach risk")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assess close approach risk"})
+		return
+	}
+
+	resp := riskResponse{
+		CometID:     comet.ID,
+		ThresholdAU: opts.ThresholdAU,
+		Model:       string(opts.Model),
+		Clones:      res.Clones,
+		Invalid:     res.Invalid,
+		Within:      res.Within,
+		Fraction:    res.Fraction,
+	}
+	resp.Nominal.Time = astrotime.UTCFromTT(res.Nominal.JD)
+	resp.Nominal.DistanceAU = res.Nominal.DistanceAU
+	if res.Clones > 0 {
+		d := res.Distance
+		resp.Distance = &riskStats{Min: d.Min, Max: d.Max, Mean: d.Mean, StdDev: d.StdDev, P05: d.P05, P50: d.P50, P95: d.P95}
+		t := res.Time
+		resp.Time = &riskTimeStats{
+			Min:        astrotime.UTCFromTT(t.Min),
+			Max:        astrotime.UTCFromTT(t.Max),
+			Mean:       astrotime.UTCFromTT(t.Mean),
+			StdDevDays: t.StdDev,
+			P05:        astrotime.UTCFromTT(t.P05),
+			P50:        astrotime.UTCFromTT(t.P50),
+			P95:        astrotime.UTCFromTT(t.P95),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// queryInt читает целый параметр запроса; если он не задан, возвращает def.
+func queryInt(ctx *gin.Context, name string, def int) (int, error) {
+	v := ctx.Query(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// queryFloat читает вещественный параметр запроса; если он не задан, возвращает def.
+func queryFloat(ctx *gin.Context, name string, def float64) (float64, error) {
+	v := ctx.Query(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
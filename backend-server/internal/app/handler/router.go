@@ -9,12 +9,30 @@ import (
 // RegisterHandler регистрирует все маршруты для обработки HTTP-запросов
 func (h *Handler) RegisterHandler(router *gin.Engine) {
 
+	// Доступ для всех
+	public := router.Group("/api")
+	{
+		public.GET("/comets", h.ListComets)
+		public.GET("/comets/:id", h.GetComet)
+		public.GET("/comets/:id/ephemeris", h.GetCometEphemeris)
+		public.GET("/comets/:id/observations/export", h.ExportObservations)
+		public.GET("/observatories", h.ListObservatories)
+	}
+
+	// Доступ для всех; авторизованный пользователь становится владельцем кометы
+	// и получает более широкие лимиты поиска сближений
+	optional := router.Group("/api")
+	optional.Use(h.OptionalAuthMiddleware())
+	{
+		optional.POST("/orbit/calculate", h.CalculateOrbitHandler)
+		optional.GET("/comets/:id/close-approaches", h.GetCometCloseApproaches)
+	}
+
 	// Доступ только для гостей
 	guest := router.Group("/api")
 
 	guest.Use(h.BlockAuthUsers())
 	{
-		guest.POST("/orbit/calculate", h.CalculateOrbitHandler)
 		guest.POST("/users/registration", h.Registration)
 		guest.POST("/users/login", h.Login)
 	}
@@ -25,7 +43,19 @@ func (h *Handler) RegisterHandler(router *gin.Engine) {
 
 		usermoder.GET("/users/profile", h.GetProfile)
 		usermoder.PUT("/users/profile/updating", h.UpdateProfile)
+		usermoder.GET("/users/profile/comets", h.ListProfileComets)
 		usermoder.POST("/users/logout", h.Logout)
 
+		usermoder.POST("/orbit/jobs", h.CreateOrbitJob)
+		usermoder.GET("/orbit/jobs/:id", h.GetOrbitJob)
+		usermoder.GET("/orbit/jobs/:id/events", h.StreamOrbitJobEvents)
+
+		usermoder.PUT("/comets/:id", h.UpdateComet)
+		usermoder.DELETE("/comets/:id", h.DeleteComet)
+		usermoder.POST("/comets/:id/refit", h.RefitComet)
+		usermoder.GET("/comets/:id/risk", h.AssessCometRisk)
+		usermoder.POST("/comets/:id/observations/import", h.ImportObservations)
+		usermoder.POST("/comets/:id/observations/import/ades", h.ImportADESObservations)
+
 	}
 }
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"backend-server/internal/app/ds"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbitclient"
+	"backend-server/internal/app/redis"
+)
+
+const (
+	// orbitJobLease — на сколько воркер берёт задачу; аренда продлевается каждые orbitJobHeartbeat.
+	// Задачи с истёкшей арендой (воркер упал, бэкенд перезапущен) возвращаются в очередь.
+	orbitJobLease     = 2 * time.Minute
+	orbitJobHeartbeat = 30 * time.Second
+	// orbitJobPoll — сколько воркер ждёт задачу в блокирующем чтении очереди
+	orbitJobPoll = 5 * time.Second
+)
+
+// errInvalidJob — данные задачи некорректны; повтор даст тот же результат
+var errInvalidJob = errors.New("invalid orbit job")
+
+// StartOrbitWorkers запускает пул воркеров асинхронных расчётов орбит и фоновый возврат
+// в очередь задач, брошенных упавшими воркерами. Воркеры останавливаются при отмене ctx.
+func (h *Handler) StartOrbitWorkers(ctx context.Context) {
+	workers := max(h.Config.Orbit.Workers, 1)
+
+	go h.recoverOrbitJobs(ctx)
+	for i := range workers {
+		go h.runOrbitWorker(ctx, i)
+	}
+
+	logrus.WithField("workers", workers).Info("orbit workers started")
+}
+
+// recoverOrbitJobs сразу при старте и затем раз в период аренды возвращает в очередь
+// задачи, которые остались в обработке без живой аренды.
+func (h *Handler) recoverOrbitJobs(ctx context.Context) {
+	ticker := time.NewTicker(orbitJobLease)
+	defer ticker.Stop()
+
+	for {
+		n, err := h.Redis.RecoverOrbitJobs(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("failed to recover orbit jobs")
+		} else if n > 0 {
+			logrus.WithField("jobs", n).Warn("requeued abandoned orbit jobs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOrbitWorker выбирает задачи из очереди и выполняет их по одной.
+func (h *Handler) runOrbitWorker(ctx context.Context, n int) {
+	for ctx.Err() == nil {
+		job, err := h.Redis.ClaimOrbitJob(ctx, orbitJobPoll, orbitJobLease)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.WithError(err).WithField("worker", n).Error("failed to claim orbit job")
+			time.Sleep(time.Second)
+			continue
+		}
+		if job == nil {
+			continue
+		}
+
+		h.processOrbitJob(ctx, job)
+	}
+}
+
+// processOrbitJob выполняет задачу и сохраняет её итог. Неудачная попытка повторяется,
+// пока не исчерпано MaxAttempts; ошибки, которые не зависят от окружения (некорректные
+// данные, расчёт не сходится), завершают задачу сразу.
+func (h *Handler) processOrbitJob(ctx context.Context, job *redis.OrbitJob) {
+	log := logrus.WithField("job", job.ID)
+
+	job.Attempts++
+	if job.Attempts > job.MaxAttempts {
+		job.Status = redis.OrbitJobFailed
+		if job.Error == "" {
+			job.Error = "orbit job was interrupted too many times"
+		}
+		h.finishOrbitJob(ctx, job)
+		return
+	}
+
+	job.Status = redis.OrbitJobRunning
+	job.Error = ""
+	if err := h.Redis.SaveOrbitJob(ctx, job); err != nil {
+		log.WithError(err).Error("failed to mark orbit job running")
+	}
+	h.publishOrbitJobStatus(ctx, job)
+
+	// Аренда продлевается, пока идёт расчёт
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(orbitJobHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if err := h.Redis.ExtendOrbitJobLease(jobCtx, job.ID, orbitJobLease); err != nil {
+					log.WithError(err).Warn("failed to extend orbit job lease")
+				}
+			}
+		}
+	}()
+
+	comet, result, err := h.computeOrbitJob(jobCtx, job, func(ev orbitclient.ProgressEvent) {
+		h.publishOrbitJobEvent(jobCtx, job.ID, orbitJobEvent{Type: "progress", Progress: &ev})
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// воркер остановлен: попытка не засчитывается, задачу подхватит другой воркер
+			job.Attempts--
+			if err := h.Redis.RequeueOrbitJob(context.Background(), job); err != nil {
+				log.WithError(err).Error("failed to requeue orbit job")
+			}
+			return
+		}
+
+		job.Error = err.Error()
+		job.Result = nil
+		if job.Attempts < job.MaxAttempts && !permanentJobError(err) {
+			log.WithError(err).WithField("attempt", job.Attempts).Warn("orbit job failed, retrying")
+			if err := h.Redis.RequeueOrbitJob(ctx, job); err != nil {
+				log.WithError(err).Error("failed to requeue orbit job")
+			}
+			h.publishOrbitJobStatus(ctx, job)
+			return
+		}
+
+		log.WithError(err).Error("orbit job failed")
+		job.Status = redis.OrbitJobFailed
+		h.finishOrbitJob(ctx, job)
+		return
+	}
+
+	job.Status = redis.OrbitJobSucceeded
+	job.CometID = comet.ID
+	job.Result = result
+	h.finishOrbitJob(ctx, job)
+}
+
+// permanentJobError сообщает, что повтор задачи с той же ошибкой бессмыслен.
+func permanentJobError(err error) bool {
+	if errors.Is(err, errInvalidJob) {
+		return true
+	}
+	var oErr *orbitError
+	return errors.As(err, &oErr) && oErr.permanent
+}
+
+// finishOrbitJob сохраняет окончательный статус задачи и убирает её из обработки.
+func (h *Handler) finishOrbitJob(ctx context.Context, job *redis.OrbitJob) {
+	if err := h.Redis.SaveOrbitJob(ctx, job); err != nil {
+		logrus.WithError(err).WithField("job", job.ID).Error("failed to save orbit job")
+	}
+	if err := h.Redis.FinishOrbitJob(ctx, job.ID); err != nil {
+		logrus.WithError(err).WithField("job", job.ID).Error("failed to finish orbit job")
+	}
+	h.publishOrbitJobStatus(ctx, job)
+}
+
+// publishOrbitJobStatus сообщает подписчикам новый статус задачи.
+func (h *Handler) publishOrbitJobStatus(ctx context.Context, job *redis.OrbitJob) {
+	h.publishOrbitJobEvent(ctx, job.ID, orbitJobEvent{Type: "status", Job: newOrbitJobResponse(job)})
+}
+
+// publishOrbitJobEvent рассылает событие задачи через Redis pub/sub.
+func (h *Handler) publishOrbitJobEvent(ctx context.Context, id string, ev orbitJobEvent) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		logrus.WithError(err).WithField("job", id).Error("failed to marshal orbit job event")
+		return
+	}
+	if err := h.Redis.PublishOrbitJobEvent(ctx, id, b); err != nil {
+		logrus.WithError(err).WithField("job", id).Warn("failed to publish orbit job event")
+	}
+}
+
+// computeOrbitJob рассчитывает орбиту по данным задачи и сохраняет комету.
+// Возвращает комету и ответ бэкенда в JSON. Ход расчёта передаётся в progress.
+// Если комета уже сохранена прошлой попыткой (воркер упал до записи итога), она
+// возвращается без повторного расчёта вместе с ответом, сохранённым в задаче до неё.
+func (h *Handler) computeOrbitJob(ctx context.Context, job *redis.OrbitJob, progress func(orbitclient.ProgressEvent)) (*ds.Comet, json.RawMessage, error) {
+	comet, err := h.Repository.GetCometByOrbitJob(job.ID)
+	if err == nil {
+		return comet, job.Result, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("find comet of job: %w", err)
+	}
+
+	var payload orbitJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, nil, fmt.Errorf("%w: payload: %v", errInvalidJob, err)
+	}
+
+	inputs := make([]observationInput, len(payload.Observations))
+	for i, o := range payload.Observations {
+		inputs[i] = o.observationInput
+	}
+	observatories, err := h.lookupObservatories(inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load observatories: %w", err)
+	}
+	observations, req, validationErrors := buildObservations(inputs, payload.Fit, observatories)
+	if len(validationErrors) > 0 {
+		return nil, nil, fmt.Errorf("%w: invalid observations in payload", errInvalidJob)
+	}
+	for i := range observations {
+		observations[i].PhotoURL = payload.Observations[i].PhotoURL
+	}
+
+	req.Progress = progress
+	res, err := h.calculateOrbit(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ответ сохраняется в задаче до записи кометы: если воркер упадёт между ними,
+	// повторная попытка найдёт комету и вернёт этот ответ
+	result, err := json.Marshal(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	job.Result = result
+	if err := h.Redis.SaveOrbitJob(ctx, job); err != nil {
+		return nil, nil, fmt.Errorf("save orbit job result: %w", err)
+	}
+
+	comet = newComet(payload.Name, job.OwnerID, observations)
+	comet.OrbitJobID = &job.ID
+	if err := h.storeCometOrbit(comet, res, nbody.Model(req.Model)); err != nil {
+		return nil, nil, err
+	}
+
+	if payload.ImageURL != "" {
+		if err := h.Repository.UpdateCometImageURL(comet.ID, payload.ImageURL); err != nil {
+			logrus.WithError(err).WithField("job", job.ID).Error("failed to set comet image")
+		}
+	}
+
+	return comet, result, nil
+}
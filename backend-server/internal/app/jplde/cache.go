@@ -0,0 +1,94 @@
+package jplde
+
+import (
+	"container/list"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+)
+
+// cacheKey — набор коэффициентов: запись record сегмента segment (у файла DE сегмент один).
+type cacheKey struct {
+	segment, record int
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	values []float64
+}
+
+// coefficients читает наборы коэффициентов из файла и хранит последние использованные
+// в LRU-кэше.
+type coefficients struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+
+	mu    sync.Mutex
+	size  int
+	items map[cacheKey]*list.Element
+	lru   *list.List // от недавно использованных к давно использованным
+}
+
+func newCoefficients(r io.ReaderAt, order binary.ByteOrder, size int) *coefficients {
+	return &coefficients{r: r, order: order, size: size, items: make(map[cacheKey]*list.Element), lru: list.New()}
+}
+
+// load возвращает n чисел float64 со смещения offset (байты), сохранённые в кэше под ключом key.
+// Возвращаемый срез нельзя изменять.
+func (c *coefficients) load(key cacheKey, offset int64, n int) ([]float64, error) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.lru.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*cacheEntry).values, nil
+	}
+	c.mu.Unlock()
+
+	// файл читается без блокировки: два одновременных промаха прочитают запись дважды
+	values, err := readFloats(c.r, c.order, offset, n)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.lru.MoveToFront(el)
+		return el.Value.(*cacheEntry).values, nil
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, values: values})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+	return values, nil
+}
+
+// readFloats читает n чисел float64 со смещения offset.
+func readFloats(r io.ReaderAt, order binary.ByteOrder, offset int64, n int) ([]float64, error) {
+	buf := make([]byte, 8*n)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Float64frombits(order.Uint64(buf[8*i:]))
+	}
+	return values, nil
+}
+
+// chebyshev вычисляет Σ cⱼ·Tⱼ(x) и производную суммы по x.
+func chebyshev(c []float64, x float64) (value, derivative float64) {
+	t0, t1 := 1.0, x   // Tⱼ₋₂, Tⱼ₋₁
+	u0, u1 := 0.0, 1.0 // Uⱼ₋₂, Uⱼ₋₁; T′ⱼ = j·Uⱼ₋₁
+	value = c[0]
+	for j := 1; j < len(c); j++ {
+		value += c[j] * t1
+		derivative += c[j] * float64(j) * u1
+		t0, t1 = t1, 2*x*t1-t0
+		u0, u1 = u1, 2*x*u1-u0
+	}
+	return value, derivative
+}
@@ -0,0 +1,149 @@
+package jplde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Двоичный файл DE: первая запись — заголовок, вторая — значения констант, далее записи
+// с коэффициентами всех тел на интервал SS[2] суток. Длина записи в заголовке не хранится
+// и определяется по началу первой записи данных.
+const (
+	deOffsetSS    = 2652 // SS[3] float64: начало, конец охвата и длина записи, JD
+	deOffsetEMRAT = 2688 // отношение масс Земли и Луны, float64
+	deOffsetIPT   = 2696 // IPT[12][3] int32: смещение, число коэффициентов и подынтервалов рядов
+	deOffsetNUMDE = 2840 // номер эфемериды, int32
+	deOffsetLPT   = 2844 // ряд либрации Луны, int32[3]
+	deHeaderSize  = 2856
+	maxDECoef     = 4096 // наибольшая длина записи, слов
+)
+
+// Ряды файла DE: планеты (барицентры систем, Земля — барицентр Земля — Луна),
+// Луна относительно Земли и Солнце. Все, кроме Луны, — относительно барицентра Солнечной системы.
+const (
+	deEMB  = 2
+	deMoon = 9
+	deSun  = 10
+	deBody = 11 // число рядов тел; 12-й — нутация
+)
+
+type deKernel struct {
+	start, end, step float64
+	records          int
+	emrat            float64
+	ipt              [deBody][3]int
+	ncoef            int // длина записи, слов
+	coef             *coefficients
+}
+
+func openDE(r io.ReaderAt, cacheSize int) (*deKernel, error) {
+	header := make([]byte, deHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if numde := order.Uint32(header[deOffsetNUMDE:]); numde < 100 || numde > 10000 {
+		order = binary.BigEndian
+		if numde = order.Uint32(header[deOffsetNUMDE:]); numde < 100 || numde > 10000 {
+			return nil, errors.New("unknown file format: neither an SPK kernel nor a JPL DE binary file")
+		}
+	}
+	float := func(off int) float64 { return math.Float64frombits(order.Uint64(header[off:])) }
+	integer := func(off int) int { return int(int32(order.Uint32(header[off:]))) }
+
+	k := &deKernel{
+		start: float(deOffsetSS),
+		end:   float(deOffsetSS + 8),
+		step:  float(deOffsetSS + 16),
+		emrat: float(deOffsetEMRAT),
+	}
+	if !(k.step > 0) || !(k.end > k.start) || !(k.emrat > 0) {
+		return nil, errors.New("corrupt DE header")
+	}
+	k.records = int(math.Round((k.end - k.start) / k.step))
+
+	// наименьшая длина записи по таблице рядов: 3 координаты у тел и либрации, 2 у нутации
+	minCoef := 2
+	series := func(off, components int) [3]int {
+		s := [3]int{integer(off), integer(off + 4), integer(off + 8)}
+		minCoef = max(minCoef, s[0]-1+s[1]*s[2]*components)
+		return s
+	}
+	for i := range deBody {
+		k.ipt[i] = series(deOffsetIPT+12*i, 3)
+		if k.ipt[i][0] < 3 || k.ipt[i][1] < 2 || k.ipt[i][2] < 1 {
+			return nil, fmt.Errorf("corrupt DE header: series %d", i)
+		}
+	}
+	series(deOffsetIPT+12*deBody, 2)
+	series(deOffsetLPT, 3)
+
+	// первая запись данных (третья в файле) начинается с моментов start и start + step
+	for n := minCoef; n <= maxDECoef; n++ {
+		v, err := readFloats(r, order, int64(2*n*8), 2)
+		if err != nil {
+			break
+		}
+		if v[0] == k.start && v[1] == k.start+k.step {
+			k.ncoef = n
+			break
+		}
+	}
+	if k.ncoef == 0 {
+		return nil, errors.New("cannot determine the DE record length")
+	}
+	k.coef = newCoefficients(r, order, cacheSize)
+	return k, nil
+}
+
+func (k *deKernel) span() (start, end float64) { return k.start, k.end }
+
+func (k *deKernel) state(t Target, jd float64) (orbit.State, error) {
+	switch {
+	case t >= Mercury && t <= Pluto:
+		return k.evaluate(int(t)-1, jd)
+	case t == Sun:
+		return k.evaluate(deSun, jd)
+	case t == Earth || t == Moon:
+		emb, err := k.evaluate(deEMB, jd)
+		if err != nil {
+			return orbit.State{}, err
+		}
+		moon, err := k.evaluate(deMoon, jd)
+		if err != nil {
+			return orbit.State{}, err
+		}
+		// барицентр Земля — Луна делит отрезок Земля — Луна в отношении масс
+		f := -1 / (1 + k.emrat)
+		if t == Moon {
+			f = k.emrat / (1 + k.emrat)
+		}
+		return orbit.State{R: emb.R.Add(moon.R.Scale(f)), V: emb.V.Add(moon.V.Scale(f))}, nil
+	}
+	return orbit.State{}, fmt.Errorf("%w: %d", ErrNoTarget, t)
+}
+
+// evaluate возвращает положение (км) и скорость (км/сут) по ряду series на момент jd.
+func (k *deKernel) evaluate(series int, jd float64) (orbit.State, error) {
+	rec := min(max(int((jd-k.start)/k.step), 0), k.records-1)
+	values, err := k.coef.load(cacheKey{record: rec}, int64(2+rec)*int64(k.ncoef)*8, k.ncoef)
+	if err != nil {
+		return orbit.State{}, fmt.Errorf("jplde: read record %d: %w", rec, err)
+	}
+	offset, n, subs := k.ipt[series][0]-1, k.ipt[series][1], k.ipt[series][2]
+	length := k.step / float64(subs)
+	sub := min(max(int((jd-values[0])/length), 0), subs-1)
+	x := 2*(jd-values[0]-float64(sub)*length)/length - 1
+	base := offset + sub*3*n
+	var st orbit.State
+	for i := range 3 {
+		p, d := chebyshev(values[base+i*n:base+(i+1)*n], x)
+		st.R[i], st.V[i] = p, d*2/length
+	}
+	return st, nil
+}
@@ -0,0 +1,144 @@
+// Package jplde читает численные эфемериды JPL: ядра SPK (формат DAF) с сегментами
+// типа 2 — например, de440s.bsp — и двоичные файлы DE (linux_p1550p2650.440 и подобные).
+// Формат определяется по содержимому файла.
+//
+// Положения и скорости возвращаются барицентрическими, в а.е. и а.е./сут, в системе
+// ICRF (совпадает с экватором J2000 в пределах точности этого приложения). Аргумент
+// времени — юлианская дата TDB; разница TT и TDB (меньше 2 мс) не учитывается.
+//
+// Коэффициенты читаются с диска по мере надобности и хранятся в LRU-кэше, поэтому
+// соседние по времени запросы не обращаются к файлу.
+package jplde
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Target — тело эфемериды, обозначенное кодом NAIF.
+type Target int
+
+// Коды NAIF тел. Для Марса и внешних планет эфемериды DE дают барицентры систем
+// планета — спутники; для Меркурия и Венеры барицентр совпадает с планетой.
+const (
+	SolarSystemBarycenter Target = 0
+	Mercury               Target = 1
+	Venus                 Target = 2
+	EarthMoonBarycenter   Target = 3
+	Mars                  Target = 4
+	Jupiter               Target = 5
+	Saturn                Target = 6
+	Uranus                Target = 7
+	Neptune               Target = 8
+	Pluto                 Target = 9
+	Sun                   Target = 10
+	Moon                  Target = 301
+	Earth                 Target = 399
+)
+
+// DefaultCacheSize — число наборов коэффициентов в кэше по умолчанию.
+const DefaultCacheSize = 256
+
+// secondsPerDay — число секунд в сутках.
+const secondsPerDay = 86400
+
+var (
+	// ErrOutOfRange возвращается для момента за пределами охвата эфемериды.
+	ErrOutOfRange = errors.New("jplde: epoch is outside the ephemeris span")
+	// ErrNoTarget возвращается, если эфемерида не содержит тела.
+	ErrNoTarget = errors.New("jplde: target is not in the ephemeris")
+)
+
+// kernel — разобранный файл эфемериды.
+type kernel interface {
+	// span возвращает охват, JD (TDB).
+	span() (start, end float64)
+	// state возвращает барицентрическое состояние тела, км и км/сут.
+	state(t Target, jd float64) (orbit.State, error)
+}
+
+// Ephemeris — открытый файл эфемериды JPL. Методы безопасны для одновременного вызова.
+type Ephemeris struct {
+	file   *os.File
+	kernel kernel
+}
+
+// Open открывает файл эфемериды path. cacheSize — число наборов коэффициентов (записей
+// файла DE или записей сегментов SPK) в кэше; 0 означает DefaultCacheSize.
+func Open(path string, cacheSize int) (*Ephemeris, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := openKernel(f, cacheSize)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("jplde: %s: %w", path, err)
+	}
+	return &Ephemeris{file: f, kernel: k}, nil
+}
+
+func openKernel(r io.ReaderAt, cacheSize int) (kernel, error) {
+	magic := make([]byte, 8)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(magic, []byte("DAF/SPK")) || bytes.HasPrefix(magic, []byte("NAIF/DAF")) {
+		return openSPK(r, cacheSize)
+	}
+	return openDE(r, cacheSize)
+}
+
+// Close закрывает файл эфемериды.
+func (e *Ephemeris) Close() error { return e.file.Close() }
+
+// Span возвращает охват эфемериды, JD (TDB).
+func (e *Ephemeris) Span() (start, end float64) { return e.kernel.span() }
+
+// State возвращает барицентрические положение (а.е.) и скорость (а.е./сут) тела t
+// на момент jd (TDB).
+func (e *Ephemeris) State(t Target, jd float64) (orbit.State, error) {
+	return e.Relative(t, SolarSystemBarycenter, jd)
+}
+
+// Position возвращает барицентрическое положение тела t (а.е.) на момент jd (TDB).
+func (e *Ephemeris) Position(t Target, jd float64) (orbit.Vec3, error) {
+	s, err := e.State(t, jd)
+	return s.R, err
+}
+
+// Relative возвращает положение (а.е.) и скорость (а.е./сут) тела t относительно тела
+// center на момент jd (TDB), например гелиоцентрическое состояние при center = Sun.
+func (e *Ephemeris) Relative(t, center Target, jd float64) (orbit.State, error) {
+	start, end := e.kernel.span()
+	if !(jd >= start && jd <= end) {
+		return orbit.State{}, fmt.Errorf("%w: JD %.1f, covered %.1f–%.1f", ErrOutOfRange, jd, start, end)
+	}
+	s, err := e.barycentric(t, jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	c, err := e.barycentric(center, jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	return orbit.State{
+		R: s.R.Sub(c.R).Scale(1 / orbit.KmPerAU),
+		V: s.V.Sub(c.V).Scale(1 / orbit.KmPerAU),
+	}, nil
+}
+
+func (e *Ephemeris) barycentric(t Target, jd float64) (orbit.State, error) {
+	if t == SolarSystemBarycenter {
+		return orbit.State{}, nil
+	}
+	return e.kernel.state(t, jd)
+}
@@ -0,0 +1,374 @@
+package jplde
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Файлы для тестов строятся здесь же с известными коэффициентами: у каждого ряда четыре
+// коэффициента на координату, и ожидаемые значения считаются по явным многочленам
+// Чебышёва T₀…T₃, а не функцией пакета.
+
+const fixtureCoef = 4
+
+// cheb4 возвращает Σ cⱼ·Tⱼ(x) и производную по x.
+func cheb4(c [fixtureCoef]float64, x float64) (value, derivative float64) {
+	value = c[0] + c[1]*x + c[2]*(2*x*x-1) + c[3]*(4*x*x*x-3*x)
+	derivative = c[1] + 4*c[2]*x + c[3]*(12*x*x-3)
+	return value, derivative
+}
+
+// fixtureCoefficients — коэффициенты координаты comp ряда series на подынтервале sub
+// записи rec, км: все ряды, записи и подынтервалы различаются.
+func fixtureCoefficients(series, rec, sub, comp int) [fixtureCoef]float64 {
+	s, r, u, c := float64(series+1), float64(rec+1), float64(sub+1), float64(comp+1)
+	return [fixtureCoef]float64{1e7 * s * c, 1e5 * r * c, -2e4 * u * s, 3e3 * (s - c)}
+}
+
+// deFixture — параметры двоичного файла DE.
+type deFixture struct {
+	start, step float64
+	records     int
+	emrat       float64
+	subs        [deBody]int // число подынтервалов ряда в записи
+}
+
+const deFixtureCoef = 400 // длина записи, слов: заголовок занимает первую запись целиком
+
+var testDE = deFixture{
+	start:   2451536.5,
+	step:    32,
+	records: 3,
+	emrat:   81.3,
+	subs:    [deBody]int{4, 2, 2, 1, 1, 1, 1, 1, 1, 8, 2},
+}
+
+func (f deFixture) ipt() [deBody][3]int {
+	var ipt [deBody][3]int
+	offset := 3
+	for i, subs := range f.subs {
+		ipt[i] = [3]int{offset, fixtureCoef, subs}
+		offset += 3 * fixtureCoef * subs
+	}
+	return ipt
+}
+
+func (f deFixture) build(order binary.ByteOrder) []byte {
+	buf := make([]byte, (2+f.records)*deFixtureCoef*8)
+	putFloat := func(off int, v float64) { order.PutUint64(buf[off:], math.Float64bits(v)) }
+	putInt := func(off, v int) { order.PutUint32(buf[off:], uint32(int32(v))) }
+
+	// заголовок по описанию формата JPL: TTL 3×84 байта, CNAM 400×6 байт, затем
+	// SS[3], NCON, AU, EMRAT, IPT[12][3], NUMDE и LPT[3]
+	putFloat(2652, f.start)
+	putFloat(2660, f.start+float64(f.records)*f.step)
+	putFloat(2668, f.step)
+	putInt(2676, 400)
+	putFloat(2680, orbit.KmPerAU)
+	putFloat(2688, f.emrat)
+	ipt := f.ipt()
+	for i, s := range ipt {
+		for j, v := range s {
+			putInt(2696+12*i+4*j, v)
+		}
+	}
+	putInt(2840, 440)
+
+	for rec := range f.records {
+		base := (2 + rec) * deFixtureCoef * 8
+		putFloat(base, f.start+float64(rec)*f.step)
+		putFloat(base+8, f.start+float64(rec+1)*f.step)
+		for series, s := range ipt {
+			for sub := range s[2] {
+				for comp := range 3 {
+					c := fixtureCoefficients(series, rec, sub, comp)
+					for j, v := range c {
+						putFloat(base+8*(s[0]-1+(sub*3+comp)*fixtureCoef+j), v)
+					}
+				}
+			}
+		}
+	}
+	return buf
+}
+
+// series возвращает ожидаемое состояние ряда series на момент jd, км и км/сут.
+func (f deFixture) series(series int, jd float64) orbit.State {
+	rec := min(int((jd-f.start)/f.step), f.records-1)
+	t0 := f.start + float64(rec)*f.step
+	length := f.step / float64(f.subs[series])
+	sub := min(int((jd-t0)/length), f.subs[series]-1)
+	x := 2*(jd-t0-float64(sub)*length)/length - 1
+	var s orbit.State
+	for comp := range 3 {
+		v, d := cheb4(fixtureCoefficients(series, rec, sub, comp), x)
+		s.R[comp], s.V[comp] = v, d*2/length
+	}
+	return s
+}
+
+// spkFixture — сегмент типа 2 ядра SPK.
+type spkFixture struct {
+	target, center Target
+	init, intlen   float64 // секунды от J2000
+	n              int
+}
+
+var testSPK = []spkFixture{
+	{Sun, SolarSystemBarycenter, -86400 * 64, 86400 * 32, 4},
+	{EarthMoonBarycenter, SolarSystemBarycenter, -86400 * 64, 86400 * 16, 8},
+	{Earth, EarthMoonBarycenter, -86400 * 64, 86400 * 4, 32},
+}
+
+const spkRecordSize = 2 + 3*fixtureCoef
+
+// buildSPK строит ядро SPK: файловая запись, запись сводок, запись имён и данные сегментов.
+func buildSPK(order binary.ByteOrder, locfmt string, segments []spkFixture) []byte {
+	words := 3 * dafRecordSize / 8
+	for _, s := range segments {
+		words += s.n*spkRecordSize + 4
+	}
+	buf := make([]byte, words*8)
+	putFloat := func(word int, v float64) { order.PutUint64(buf[8*word:], math.Float64bits(v)) }
+
+	copy(buf, "DAF/SPK ")
+	order.PutUint32(buf[8:], 2)
+	order.PutUint32(buf[12:], 6)
+	order.PutUint32(buf[76:], 2) // первая запись сводок
+	copy(buf[88:], locfmt)
+
+	summary := dafRecordSize / 8
+	putFloat(summary+2, float64(len(segments)))
+	word := 3 * dafRecordSize / 8
+	for i, s := range segments {
+		begin := word
+		for rec := range s.n {
+			putFloat(word, s.init+(float64(rec)+0.5)*s.intlen)
+			putFloat(word+1, s.intlen/2)
+			for comp := range 3 {
+				for j, v := range fixtureCoefficients(int(s.target), rec, 0, comp) {
+					putFloat(word+2+comp*fixtureCoef+j, v)
+				}
+			}
+			word += spkRecordSize
+		}
+		putFloat(word, s.init)
+		putFloat(word+1, s.intlen)
+		putFloat(word+2, spkRecordSize)
+		putFloat(word+3, float64(s.n))
+		word += 4
+
+		base := summary + 3 + i*5
+		putFloat(base, s.init)
+		putFloat(base+1, s.init+float64(s.n)*s.intlen)
+		ints := buf[8*(base+2):]
+		for j, v := range []int{int(s.target), int(s.center), spkFrameJ2000, spkType2, begin + 1, word} {
+			order.PutUint32(ints[4*j:], uint32(int32(v)))
+		}
+	}
+	return buf
+}
+
+// state возвращает ожидаемое состояние сегмента на момент jd относительно его центра, км и км/сут.
+func (s spkFixture) state(jd float64) orbit.State {
+	et := (jd - j2000) * secondsPerDay
+	rec := min(int((et-s.init)/s.intlen), s.n-1)
+	mid, radius := s.init+(float64(rec)+0.5)*s.intlen, s.intlen/2
+	x := (et - mid) / radius
+	var st orbit.State
+	for comp := range 3 {
+		v, d := cheb4(fixtureCoefficients(int(s.target), rec, 0, comp), x)
+		st.R[comp], st.V[comp] = v, d/radius*secondsPerDay
+	}
+	return st
+}
+
+func writeFixture(t *testing.T, data []byte) *Ephemeris {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixture.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	eph, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eph.Close() })
+	return eph
+}
+
+// checkState сравнивает состояние в а.е. с ожидаемым в км и км/сут.
+func checkState(t *testing.T, name string, got, wantKm orbit.State) {
+	t.Helper()
+	want := orbit.State{R: wantKm.R.Scale(1 / orbit.KmPerAU), V: wantKm.V.Scale(1 / orbit.KmPerAU)}
+	if d := got.R.Sub(want.R).Norm(); d > 1e-12*want.R.Norm() {
+		t.Errorf("%s: r = %v, want %v", name, got.R, want.R)
+	}
+	if d := got.V.Sub(want.V).Norm(); d > 1e-12*want.V.Norm() {
+		t.Errorf("%s: v = %v, want %v", name, got.V, want.V)
+	}
+}
+
+func TestDEFile(t *testing.T) {
+	f := testDE
+	end := f.start + float64(f.records)*f.step
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			eph := writeFixture(t, f.build(order))
+			if s, e := eph.Span(); s != f.start || e != end {
+				t.Fatalf("span = %v–%v, want %v–%v", s, e, f.start, end)
+			}
+			if k := eph.kernel.(*deKernel); k.ncoef != deFixtureCoef {
+				t.Errorf("record length = %d words, want %d", k.ncoef, deFixtureCoef)
+			}
+
+			// начало охвата, граница подынтервалов Луны, граница записей, конец охвата
+			for _, jd := range []float64{f.start, f.start + 4, f.start + 13.7, f.start + 32, f.start + 70.25, end} {
+				emb, moon, sun := f.series(deEMB, jd), f.series(deMoon, jd), f.series(deSun, jd)
+
+				got, err := eph.Relative(Jupiter, Sun, jd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				jupiter := f.series(4, jd)
+				checkState(t, "jupiter", got, orbit.State{R: jupiter.R.Sub(sun.R), V: jupiter.V.Sub(sun.V)})
+
+				got, err = eph.Relative(Moon, Earth, jd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkState(t, "moon from earth", got, moon)
+
+				// Земля смещена от барицентра Земля — Луна на −Луна/(1 + EMRAT)
+				got, err = eph.Relative(Earth, EarthMoonBarycenter, jd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				k := -1 / (1 + f.emrat)
+				checkState(t, "earth from EMB", got, orbit.State{R: moon.R.Scale(k), V: moon.V.Scale(k)})
+
+				got, err = eph.State(Earth, jd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkState(t, "earth", got, orbit.State{R: emb.R.Add(moon.R.Scale(k)), V: emb.V.Add(moon.V.Scale(k))})
+			}
+
+			for _, jd := range []float64{f.start - 0.5, end + 0.5, math.NaN()} {
+				if _, err := eph.Relative(Mars, Sun, jd); !errors.Is(err, ErrOutOfRange) {
+					t.Errorf("JD %v: err = %v, want ErrOutOfRange", jd, err)
+				}
+			}
+			if _, err := eph.State(Target(499), f.start); !errors.Is(err, ErrNoTarget) {
+				t.Errorf("err = %v, want ErrNoTarget", err)
+			}
+		})
+	}
+}
+
+func TestSPKFile(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		order  binary.ByteOrder
+		locfmt string
+	}{
+		{"little endian", binary.LittleEndian, "LTL-IEEE"},
+		{"big endian", binary.BigEndian, "BIG-IEEE"},
+		{"no LOCFMT", binary.BigEndian, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			eph := writeFixture(t, buildSPK(tc.order, tc.locfmt, testSPK))
+			first := j2000 - 64
+			last := first + 128
+			if s, e := eph.Span(); s != first || e != last {
+				t.Fatalf("span = %v–%v, want %v–%v", s, e, first, last)
+			}
+
+			sun, emb, earth := testSPK[0], testSPK[1], testSPK[2]
+			for _, jd := range []float64{first, first + 3.3, j2000, j2000 + 16, last} {
+				got, err := eph.Relative(Earth, Sun, jd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e, b, s := earth.state(jd), emb.state(jd), sun.state(jd)
+				checkState(t, "earth", got, orbit.State{R: e.R.Add(b.R).Sub(s.R), V: e.V.Add(b.V).Sub(s.V)})
+			}
+
+			if _, err := eph.Relative(Earth, Sun, last+1); !errors.Is(err, ErrOutOfRange) {
+				t.Errorf("err = %v, want ErrOutOfRange", err)
+			}
+			if _, err := eph.State(Mars, j2000); !errors.Is(err, ErrNoTarget) {
+				t.Errorf("err = %v, want ErrNoTarget", err)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsUnknownFormat(t *testing.T) {
+	if _, err := openKernel(bytes.NewReader(make([]byte, 4*dafRecordSize)), 1); err == nil {
+		t.Error("want error for a file of zeros")
+	}
+	spk := buildSPK(binary.LittleEndian, "LTL-IEEE", testSPK)
+	binary.LittleEndian.PutUint32(spk[12:], 5) // NI
+	if _, err := openKernel(bytes.NewReader(spk), 1); err == nil {
+		t.Error("want error for NI = 5")
+	}
+}
+
+// countingReader считает обращения к файлу.
+type countingReader struct {
+	r     *bytes.Reader
+	reads int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestCoefficientsCacheEviction(t *testing.T) {
+	data := make([]byte, 8*8)
+	for i := range 8 {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(float64(i)))
+	}
+	r := &countingReader{r: bytes.NewReader(data)}
+	c := newCoefficients(r, binary.LittleEndian, 2)
+
+	load := func(rec int) {
+		t.Helper()
+		v, err := c.load(cacheKey{record: rec}, int64(8*rec), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v[0] != float64(rec) {
+			t.Errorf("record %d: got %v", rec, v[0])
+		}
+	}
+	for _, step := range []struct {
+		rec   int
+		reads int // обращений к файлу после загрузки
+	}{
+		{0, 1},
+		{1, 2},
+		{0, 2}, // попадание: 0 становится самой свежей
+		{2, 3}, // вытесняет 1, давно не использованную
+		{0, 3},
+		{1, 4},
+		{2, 5}, // 2 вытеснена загрузкой 1
+	} {
+		load(step.rec)
+		if r.reads != step.reads {
+			t.Fatalf("after loading record %d: %d reads, want %d", step.rec, r.reads, step.reads)
+		}
+	}
+	if n := c.lru.Len(); n != 2 {
+		t.Errorf("cache holds %d entries, want 2", n)
+	}
+}
@@ -0,0 +1,60 @@
+package jplde
+
+import (
+	"fmt"
+
+	"backend-server/internal/app/config"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// bodyTargets — тела эфемериды JPL, соответствующие телам planets.
+var bodyTargets = map[planets.Body]Target{
+	planets.BodySun:     Sun,
+	planets.BodyMercury: Mercury,
+	planets.BodyVenus:   Venus,
+	planets.BodyEarth:   Earth,
+	planets.BodyMoon:    Moon,
+	planets.BodyMars:    Mars,
+	planets.BodyJupiter: Jupiter,
+	planets.BodySaturn:  Saturn,
+	planets.BodyUranus:  Uranus,
+	planets.BodyNeptune: Neptune,
+}
+
+// BodyTarget возвращает код NAIF тела planets.
+func BodyTarget(b planets.Body) (Target, bool) {
+	t, ok := bodyTargets[b]
+	return t, ok
+}
+
+// OpenSource возвращает источник положений планет по настройкам cfg: эфемериду JPL из
+// cfg.Path, а если путь не задан — встроенную эфемериду (planets.DefaultSource).
+// Открытая эфемерида возвращается вторым значением, чтобы вызывающий закрыл её;
+// без файла это nil.
+func OpenSource(cfg config.EphemerisConfig) (planets.Source, *Ephemeris, error) {
+	if cfg.Path == "" {
+		return planets.DefaultSource(), nil, nil
+	}
+	eph, err := Open(cfg.Path, cfg.CacheSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return eph.Planets(), eph, nil
+}
+
+// Planets возвращает эфемериду как planets.Source: гелиоцентрические состояния тел.
+func (e *Ephemeris) Planets() planets.Source { return heliocentric{e} }
+
+type heliocentric struct{ e *Ephemeris }
+
+// Span реализует planets.Bounded.
+func (h heliocentric) Span() (start, end float64) { return h.e.Span() }
+
+func (h heliocentric) State(b planets.Body, jd float64) (orbit.State, error) {
+	t, ok := bodyTargets[b]
+	if !ok {
+		return orbit.State{}, fmt.Errorf("%w: %s", ErrNoTarget, b)
+	}
+	return h.e.Relative(t, Sun, jd)
+}
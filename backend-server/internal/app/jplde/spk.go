@@ -0,0 +1,177 @@
+package jplde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Ядро SPK хранится в формате DAF: записи по 1024 байта, адреса — номера 8-байтовых слов
+// с единицы. Первая запись — файловая, затем цепочка записей со сводками сегментов.
+// Сегмент типа 2 содержит записи одинаковой длительности с чебышёвскими коэффициентами
+// положения (км); в конце сегмента — INIT, INTLEN, RSIZE, N.
+const (
+	dafRecordSize = 1024
+	j2000         = 2451545.0
+	spkType2      = 2
+	spkFrameJ2000 = 1
+	maxChain      = 8 // наибольшая глубина цепочки центров до барицентра
+)
+
+// spkSegment — сегмент типа 2: положение target относительно center.
+type spkSegment struct {
+	target, center Target
+	start, end     float64 // охват, секунды TDB от J2000
+	init, intlen   float64 // начало первой записи и длительность записи, с
+	rsize, n       int     // длина записи (слов) и число записей
+	offset         int64   // смещение первой записи, байты
+}
+
+type spkKernel struct {
+	segments []spkSegment
+	byTarget map[Target][]int // индексы сегментов тела; более поздние имеют приоритет
+	first    float64          // охват, JD
+	last     float64
+	coef     *coefficients
+}
+
+func openSPK(r io.ReaderAt, cacheSize int) (*spkKernel, error) {
+	rec := make([]byte, dafRecordSize)
+	if _, err := r.ReadAt(rec, 0); err != nil {
+		return nil, fmt.Errorf("read file record: %w", err)
+	}
+	var order binary.ByteOrder
+	switch string(rec[88:96]) {
+	case "LTL-IEEE":
+		order = binary.LittleEndian
+	case "BIG-IEEE":
+		order = binary.BigEndian
+	default:
+		// старые файлы без LOCFMT: порядок байтов угадывается по ND = 2
+		order = binary.LittleEndian
+		if order.Uint32(rec[8:]) != 2 {
+			order = binary.BigEndian
+		}
+	}
+	nd, ni := int(order.Uint32(rec[8:])), int(order.Uint32(rec[12:]))
+	if nd != 2 || ni != 6 {
+		return nil, fmt.Errorf("not an SPK file: ND=%d, NI=%d", nd, ni)
+	}
+	summarySize := nd + (ni+1)/2 // в словах
+
+	k := &spkKernel{byTarget: make(map[Target][]int), coef: newCoefficients(r, order, cacheSize)}
+	next := int(order.Uint32(rec[76:]))
+	for visited := 0; next > 0; visited++ {
+		if visited > 1<<16 {
+			return nil, errors.New("summary records form a loop")
+		}
+		if _, err := r.ReadAt(rec, int64(next-1)*dafRecordSize); err != nil {
+			return nil, fmt.Errorf("read summary record %d: %w", next, err)
+		}
+		word := func(i int) float64 { return math.Float64frombits(order.Uint64(rec[8*i:])) }
+		next = int(word(0))
+		count := int(word(2))
+		if count < 0 || 3+count*summarySize > dafRecordSize/8 {
+			return nil, fmt.Errorf("corrupt summary record: %d summaries", count)
+		}
+		for i := range count {
+			base := 3 + i*summarySize
+			ints := rec[8*(base+nd):]
+			integer := func(j int) int { return int(int32(order.Uint32(ints[4*j:]))) }
+			seg := spkSegment{
+				target: Target(integer(0)),
+				center: Target(integer(1)),
+				start:  word(base),
+				end:    word(base + 1),
+			}
+			frame, kind, begin, end := integer(2), integer(3), integer(4), integer(5)
+			if kind != spkType2 || frame != spkFrameJ2000 {
+				continue // другие типы и системы координат в эфемеридах DE не встречаются
+			}
+			trailer, err := readFloats(r, order, int64(end-4)*8, 4)
+			if err != nil {
+				return nil, fmt.Errorf("read segment of target %d: %w", seg.target, err)
+			}
+			seg.init, seg.intlen = trailer[0], trailer[1]
+			seg.rsize, seg.n = int(trailer[2]), int(trailer[3])
+			seg.offset = int64(begin-1) * 8
+			if seg.intlen <= 0 || seg.n <= 0 || seg.rsize < 5 || (seg.rsize-2)%3 != 0 ||
+				begin-1+seg.rsize*seg.n > end-4 {
+				return nil, fmt.Errorf("corrupt segment of target %d", seg.target)
+			}
+			k.byTarget[seg.target] = append(k.byTarget[seg.target], len(k.segments))
+			k.segments = append(k.segments, seg)
+		}
+	}
+	if len(k.segments) == 0 {
+		return nil, errors.New("no type 2 segments")
+	}
+
+	// охват — общая часть охватов всех тел
+	k.first, k.last = math.Inf(-1), math.Inf(1)
+	for _, idx := range k.byTarget {
+		start, end := math.Inf(1), math.Inf(-1)
+		for _, i := range idx {
+			start, end = math.Min(start, k.segments[i].start), math.Max(end, k.segments[i].end)
+		}
+		k.first, k.last = math.Max(k.first, start), math.Min(k.last, end)
+	}
+	k.first, k.last = j2000+k.first/secondsPerDay, j2000+k.last/secondsPerDay
+	return k, nil
+}
+
+func (k *spkKernel) span() (start, end float64) { return k.first, k.last }
+
+func (k *spkKernel) state(t Target, jd float64) (orbit.State, error) {
+	et := (jd - j2000) * secondsPerDay
+	var total orbit.State
+	for range maxChain {
+		if t == SolarSystemBarycenter {
+			return total, nil
+		}
+		idx, ok := k.byTarget[t]
+		if !ok {
+			return orbit.State{}, fmt.Errorf("%w: %d", ErrNoTarget, t)
+		}
+		seg := -1
+		for i := len(idx) - 1; i >= 0; i-- {
+			if s := &k.segments[idx[i]]; et >= s.start && et <= s.end {
+				seg = idx[i]
+				break
+			}
+		}
+		if seg < 0 {
+			return orbit.State{}, fmt.Errorf("%w: target %d at JD %.1f", ErrOutOfRange, t, jd)
+		}
+		s, err := k.evaluate(seg, et)
+		if err != nil {
+			return orbit.State{}, err
+		}
+		total.R, total.V = total.R.Add(s.R), total.V.Add(s.V)
+		t = k.segments[seg].center
+	}
+	return orbit.State{}, fmt.Errorf("jplde: chain of centers of target %d is too long", t)
+}
+
+// evaluate возвращает положение (км) и скорость (км/сут) по сегменту seg на момент et.
+func (k *spkKernel) evaluate(seg int, et float64) (orbit.State, error) {
+	s := &k.segments[seg]
+	rec := min(max(int((et-s.init)/s.intlen), 0), s.n-1)
+	values, err := k.coef.load(cacheKey{segment: seg, record: rec}, s.offset+int64(rec*s.rsize)*8, s.rsize)
+	if err != nil {
+		return orbit.State{}, fmt.Errorf("jplde: read segment of target %d: %w", s.target, err)
+	}
+	mid, radius := values[0], values[1]
+	n := (s.rsize - 2) / 3
+	x := (et - mid) / radius
+	var st orbit.State
+	for i := range 3 {
+		p, d := chebyshev(values[2+i*n:2+(i+1)*n], x)
+		st.R[i], st.V[i] = p, d/radius*secondsPerDay
+	}
+	return st, nil
+}
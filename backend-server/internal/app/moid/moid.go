@@ -0,0 +1,241 @@
+// Package moid вычисляет MOID (Minimum Orbit Intersection Distance) — наименьшее
+// расстояние между точками двух кеплеровых орбит независимо от положения тел на них.
+//
+// Расстояние от точки орбиты кометы до эллипса планеты D(ν) — функция истинной аномалии
+// кометы ν. Внутренняя задача — ближайшая точка эллипса — решается по эксцентрической
+// аномалии планеты: сетка из innerNodes узлов и уточнение каждого локального минимума
+// методом Брента. Внешняя функция D(ν) так же вычисляется на сетке из outerNodes узлов,
+// и каждый её локальный минимум уточняется методом Брента; MOID — наименьший из них.
+// У эллипса планеты не больше двух минимумов расстояния до точки, у D(ν) — не больше
+// четырёх, и сетки достаточно густы, чтобы разделить их. Вычисление не зависит от
+// случайных чисел и порядка вызовов, поэтому одни и те же элементы дают один и тот же
+// результат; точность — около 1e-10 а.е.
+package moid
+
+import (
+	"errors"
+	"math"
+
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+const (
+	outerNodes = 720 // узлов сетки по истинной аномалии кометы
+	innerNodes = 36  // узлов сетки по эксцентрической аномалии планеты
+
+	// tolerance — относительная точность аргумента при уточнении минимума методом Брента.
+	tolerance     = 1e-10
+	maxIterations = 200
+	golden        = 0.3819660112501051 // (3 − √5)/2
+)
+
+// ErrOpenOrbit возвращается, если орбита планеты не эллиптическая.
+var ErrOpenOrbit = errors.New("moid: planet orbit must be an ellipse")
+
+// Result — MOID и ближайшие точки орбит.
+type Result struct {
+	DistanceAU float64    // MOID, а.е.
+	Comet      orbit.Vec3 // ближайшая точка орбиты кометы, а.е.
+	Planet     orbit.Vec3 // ближайшая точка орбиты планеты, а.е.
+}
+
+// conic — орбита в виде перифокального базиса: P — на перигелий, Q — на 90° по движению.
+type conic struct {
+	el   orbit.Elements
+	p, q orbit.Vec3
+}
+
+func newConic(el orbit.Elements) conic {
+	rot := orbit.RotZ(el.ArgPeri).Mul(orbit.RotX(el.I)).Mul(orbit.RotZ(el.Node)).Transpose()
+	return conic{el: el, p: rot.Apply(orbit.Vec3{1, 0, 0}), q: rot.Apply(orbit.Vec3{0, 1, 0})}
+}
+
+// atTrue возвращает точку орбиты с истинной аномалией nu.
+func (c conic) atTrue(nu float64) orbit.Vec3 {
+	s, co := math.Sincos(nu)
+	r := c.el.Q * (1 + c.el.E) / (1 + c.el.E*co)
+	return c.p.Scale(r * co).Add(c.q.Scale(r * s))
+}
+
+// atEccentric возвращает точку эллипса с эксцентрической аномалией ea.
+func (c conic) atEccentric(ea float64) orbit.Vec3 {
+	a := c.el.A()
+	b := a * math.Sqrt(1-c.el.E*c.el.E)
+	s, co := math.Sincos(ea)
+	return c.p.Scale(a * (co - c.el.E)).Add(c.q.Scale(b * s))
+}
+
+// Compute вычисляет MOID орбиты кометы (любой конической) и эллиптической орбиты планеты.
+// Углы элементов обеих орбит должны отсчитываться в одной системе координат.
+func Compute(comet, planet orbit.Elements) (Result, error) {
+	if err := comet.Validate(); err != nil {
+		return Result{}, err
+	}
+	if err := planet.Validate(); err != nil {
+		return Result{}, err
+	}
+	if planet.E >= 1 {
+		return Result{}, ErrOpenOrbit
+	}
+	c, p := newConic(comet), newConic(planet)
+
+	// квадрат расстояния от точки орбиты кометы до эллипса планеты
+	distance2 := func(nu float64) float64 {
+		_, d2 := p.nearest(c.atTrue(nu))
+		return d2
+	}
+
+	// Точки кометы дальше от Солнца, чем афелий планеты плюс расстояние от перигелия
+	// кометы до эллипса, не могут дать минимум; для незамкнутой орбиты это ограничивает
+	// интервал аномалий, для вытянутого эллипса — сгущает сетку у перигелия.
+	rmax := planet.A()*(1+planet.E) + math.Sqrt(distance2(0))
+	lo, hi, cyclic := -math.Pi, math.Pi, true
+	if comet.E >= 1 || comet.A()*(1+comet.E) > rmax {
+		cosMax := (comet.Q*(1+comet.E)/rmax - 1) / comet.E
+		hi = math.Acos(math.Max(-1, math.Min(1, cosMax)))
+		lo, cyclic = -hi, false
+	}
+
+	step := (hi - lo) / outerNodes
+	nodes := outerNodes + 1
+	if cyclic {
+		nodes = outerNodes
+	}
+	values := make([]float64, nodes)
+	for k := range values {
+		values[k] = distance2(lo + float64(k)*step)
+	}
+
+	best := math.Inf(1)
+	bestNu := 0.0
+	for k := range values {
+		prev, next := k-1, k+1
+		switch {
+		case cyclic:
+			prev, next = (k+nodes-1)%nodes, (k+1)%nodes
+		case k == 0 || k == nodes-1:
+			// края интервала дальше rmax от Солнца и минимумом быть не могут
+			continue
+		}
+		if values[k] > values[prev] || values[k] > values[next] {
+			continue
+		}
+		nu := lo + float64(k)*step
+		x, fx := minimize(distance2, nu-step, nu+step, nu, values[k])
+		if fx < best {
+			best, bestNu = fx, x
+		}
+	}
+
+	point := c.atTrue(bestNu)
+	ea, d2 := p.nearest(point)
+	return Result{DistanceAU: math.Sqrt(d2), Comet: point, Planet: p.atEccentric(ea)}, nil
+}
+
+// nearest возвращает эксцентрическую аномалию ближайшей к point точки эллипса и квадрат
+// расстояния до неё.
+func (c conic) nearest(point orbit.Vec3) (ea, distance2 float64) {
+	f := func(x float64) float64 {
+		d := c.atEccentric(x).Sub(point)
+		return d.Dot(d)
+	}
+	const step = 2 * math.Pi / innerNodes
+	var values [innerNodes]float64
+	for k := range values {
+		values[k] = f(float64(k) * step)
+	}
+	distance2 = math.Inf(1)
+	for k := range values {
+		v := values[k]
+		if v > values[(k+innerNodes-1)%innerNodes] || v > values[(k+1)%innerNodes] {
+			continue
+		}
+		x := float64(k) * step
+		if x, fx := minimize(f, x-step, x+step, x, v); fx < distance2 {
+			ea, distance2 = x, fx
+		}
+	}
+	return ea, distance2
+}
+
+// minimize уточняет методом Брента (золотое сечение с параболической интерполяцией)
+// минимум f на отрезке [a, b], внутри которого лежит точка x со значением fx, не большим
+// значений на концах.
+func minimize(f func(float64) float64, a, b, x, fx float64) (float64, float64) {
+	w, v := x, x
+	fw, fv := fx, fx
+	var d, e float64
+	for range maxIterations {
+		mid := (a + b) / 2
+		tol1 := tolerance*math.Abs(x) + 1e-15
+		tol2 := 2 * tol1
+		if math.Abs(x-mid) <= tol2-(b-a)/2 {
+			break
+		}
+		parabolic := false
+		if math.Abs(e) > tol1 {
+			// парабола через x, w, v
+			r := (x - w) * (fx - fv)
+			q := (x - v) * (fx - fw)
+			p := (x-v)*q - (x-w)*r
+			q = 2 * (q - r)
+			if q > 0 {
+				p = -p
+			}
+			q = math.Abs(q)
+			if math.Abs(p) < math.Abs(q*e/2) && p > q*(a-x) && p < q*(b-x) {
+				e, d = d, p/q
+				if u := x + d; u-a < tol2 || b-u < tol2 {
+					d = math.Copysign(tol1, mid-x)
+				}
+				parabolic = true
+			}
+		}
+		if !parabolic {
+			if x >= mid {
+				e = a - x
+			} else {
+				e = b - x
+			}
+			d = golden * e
+		}
+		u := x + d
+		if math.Abs(d) < tol1 {
+			u = x + math.Copysign(tol1, d)
+		}
+		fu := f(u)
+		if fu <= fx {
+			if u >= x {
+				a = x
+			} else {
+				b = x
+			}
+			v, w, x = w, x, u
+			fv, fw, fx = fw, fx, fu
+			continue
+		}
+		if u < x {
+			a = u
+		} else {
+			b = u
+		}
+		if fu <= fw || w == x {
+			v, w = w, u
+			fv, fw = fw, fu
+		} else if fu <= fv || v == x || v == w {
+			v, fv = u, fu
+		}
+	}
+	return x, fx
+}
+
+// Planet вычисляет MOID орбиты кометы (элементы относительно эклиптики J2000) и
+// оскулирующей орбиты тела b на момент jd (TT) по источнику src (см. planets.Osculating).
+func Planet(comet orbit.Elements, src planets.Source, b planets.Body, jd float64) (Result, error) {
+	el, err := planets.Osculating(src, b, jd)
+	if err != nil {
+		return Result{}, err
+	}
+	return Compute(comet, el)
+}
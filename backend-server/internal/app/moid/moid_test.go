@@ -0,0 +1,210 @@
+package moid
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Эталоны — конфигурации с MOID, известным в замкнутом виде, и перебор по истинным
+// аномалиям обеих орбит, где точки орбит вычисляются по формулам учебника, а не кодом пакета.
+
+const benchmarkTolerance = 1e-6
+
+func TestComputeClosedForm(t *testing.T) {
+	circle := orbit.ElementsFromDegrees(1, 0, 0, 0, 0, 0)
+	tests := []struct {
+		name   string
+		comet  orbit.Elements
+		planet orbit.Elements
+		want   float64
+	}{
+		{
+			// компланарные окружности: MOID — разность радиусов
+			name:   "coplanar circles",
+			comet:  orbit.ElementsFromDegrees(5.2, 0, 0, 40, 0, 0),
+			planet: circle,
+			want:   4.2,
+		},
+		{
+			// компланарный эллипс вне окружности: ближе всего перигелий
+			name:   "coplanar ellipse outside",
+			comet:  orbit.ElementsFromDegrees(1.3, 0.6, 0, 75, 30, 0),
+			planet: circle,
+			want:   0.3,
+		},
+		{
+			// полярная орбита с перигелием в узле на 1e-4 а.е. внутри орбиты планеты:
+			// точка орбиты планеты лежит на оси орбиты кометы снаружи вершины, и
+			// ближайшая к ней точка — перигелий
+			name:   "near-intersecting polar ellipse",
+			comet:  orbit.ElementsFromDegrees(1-1e-4, 0.7, 90, 120, 0, 0),
+			planet: circle,
+			want:   1e-4,
+		},
+		{
+			name:   "near-intersecting polar parabola",
+			comet:  orbit.ElementsFromDegrees(1-2.5e-5, 1, 90, 300, 0, 0),
+			planet: circle,
+			want:   2.5e-5,
+		},
+		{
+			name:   "polar hyperbola",
+			comet:  orbit.ElementsFromDegrees(0.4, 1.8, 90, 10, 180, 0),
+			planet: circle,
+			want:   0.6,
+		},
+		{
+			// ретроградная орбита в плоскости планеты: MOID не зависит от направления движения
+			name:   "retrograde coplanar",
+			comet:  orbit.ElementsFromDegrees(0.5, 0.2, 180, 0, 0, 0),
+			planet: circle,
+			want:   1 - 0.5*1.2/0.8,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Compute(tc.comet, tc.planet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := math.Abs(res.DistanceAU - tc.want); d > benchmarkTolerance {
+				t.Errorf("MOID = %.9f, want %.9f (off by %.2e)", res.DistanceAU, tc.want, d)
+			}
+			if d := res.Comet.Sub(res.Planet).Norm(); math.Abs(d-res.DistanceAU) > 1e-12 {
+				t.Errorf("closest points are %.9f apart, MOID %.9f", d, res.DistanceAU)
+			}
+		})
+	}
+}
+
+// position возвращает точку орбиты el с истинной аномалией nu:
+// r = q(1 + e)/(1 + e cos ν), u = ω + ν,
+// x = r(cos Ω cos u − sin Ω sin u cos i), y = r(sin Ω cos u + cos Ω sin u cos i), z = r sin u sin i.
+func position(el orbit.Elements, nu float64) orbit.Vec3 {
+	r := el.Q * (1 + el.E) / (1 + el.E*math.Cos(nu))
+	su, cu := math.Sincos(el.ArgPeri + nu)
+	sn, cn := math.Sincos(el.Node)
+	si, ci := math.Sincos(el.I)
+	return orbit.Vec3{r * (cn*cu - sn*su*ci), r * (sn*cu + cn*su*ci), r * su * si}
+}
+
+// bruteForce ищет MOID перебором по сетке истинных аномалий обеих орбит с уточнением
+// покоординатным спуском — алгоритм, не связанный с Compute.
+func bruteForce(comet, planet orbit.Elements) float64 {
+	limit := math.Pi
+	if comet.E >= 1 {
+		limit = math.Acos(-1/comet.E) - 1e-3
+	}
+	d := func(nu, nup float64) float64 { return position(comet, nu).Sub(position(planet, nup)).Norm() }
+
+	const n = 720
+	type start struct{ nu, nup, d float64 }
+	var starts []start
+	for i := range n {
+		nu := -limit + 2*limit*float64(i)/n
+		for j := range n {
+			nup := 2 * math.Pi * float64(j) / n
+			starts = append(starts, start{nu, nup, d(nu, nup)})
+		}
+	}
+	// уточняются 20 лучших узлов сетки
+	for k := range 20 {
+		for m := k + 1; m < len(starts); m++ {
+			if starts[m].d < starts[k].d {
+				starts[k], starts[m] = starts[m], starts[k]
+			}
+		}
+	}
+	best := math.Inf(1)
+	for _, s := range starts[:20] {
+		nu, nup, h := s.nu, s.nup, 2*math.Pi/n
+		for h > 1e-13 {
+			moved := false
+			for _, step := range [][2]float64{{h, 0}, {-h, 0}, {0, h}, {0, -h}} {
+				if v := d(nu+step[0], nup+step[1]); v < d(nu, nup) && math.Abs(nu+step[0]) <= limit {
+					nu, nup, moved = nu+step[0], nup+step[1], true
+				}
+			}
+			if !moved {
+				h /= 2
+			}
+		}
+		best = min(best, d(nu, nup))
+	}
+	return best
+}
+
+func TestComputeMatchesBruteForce(t *testing.T) {
+	earth := orbit.ElementsFromDegrees(0.98329, 0.01671, 0.00005, 348.739, 114.208, 0)
+	jupiter := orbit.ElementsFromDegrees(4.95156, 0.04839, 1.30439, 100.473, 273.867, 0)
+	tests := []struct {
+		name          string
+		comet, planet orbit.Elements
+	}{
+		{"1P/Halley and Earth", orbit.ElementsFromDegrees(0.58598, 0.96714, 162.2627, 58.4201, 111.3325, 0), earth},
+		{"2P/Encke and Earth", orbit.ElementsFromDegrees(0.33588, 0.84833, 11.7807, 334.5682, 186.5455, 0), earth},
+		{"C/1995 O1 and Earth (high inclination)", orbit.ElementsFromDegrees(0.91414, 0.99509, 89.4298, 282.4707, 130.5887, 0), earth},
+		{"55P/Tempel-Tuttle and Earth (near-intersecting)", orbit.ElementsFromDegrees(0.97597, 0.90555, 162.4866, 235.2709, 172.5001, 0), earth},
+		{"C/2019 Q4 and Jupiter (hyperbolic)", orbit.ElementsFromDegrees(2.00652, 3.35637, 44.0526, 308.1499, 209.1242, 0), jupiter},
+		{"1P/Halley and Jupiter", orbit.ElementsFromDegrees(0.58598, 0.96714, 162.2627, 58.4201, 111.3325, 0), jupiter},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Compute(tc.comet, tc.planet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := bruteForce(tc.comet, tc.planet)
+			if d := math.Abs(res.DistanceAU - want); d > benchmarkTolerance {
+				t.Errorf("MOID = %.9f, brute force %.9f (off by %.2e)", res.DistanceAU, want, d)
+			}
+		})
+	}
+}
+
+// Одни и те же элементы дают тот же результат до бита независимо от порядка вызовов
+// и от того, вычисляются ли они одновременно.
+func TestComputeDeterministic(t *testing.T) {
+	earth := orbit.ElementsFromDegrees(0.98329, 0.01671, 0.00005, 348.739, 114.208, 0)
+	comets := []orbit.Elements{
+		orbit.ElementsFromDegrees(0.58598, 0.96714, 162.2627, 58.4201, 111.3325, 0),
+		orbit.ElementsFromDegrees(0.33588, 0.84833, 11.7807, 334.5682, 186.5455, 0),
+		orbit.ElementsFromDegrees(0.97597, 0.90555, 162.4866, 235.2709, 172.5001, 0),
+		orbit.ElementsFromDegrees(2.00652, 3.35637, 44.0526, 308.1499, 209.1242, 0),
+	}
+	want := make([]Result, len(comets))
+	for i, c := range comets {
+		res, err := Compute(c, earth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = res
+	}
+
+	for i := len(comets) - 1; i >= 0; i-- {
+		if res, _ := Compute(comets[i], earth); res != want[i] {
+			t.Errorf("comet %d: repeated call gave %+v, want %+v", i, res, want[i])
+		}
+	}
+	got := make([]Result, len(comets))
+	var wg sync.WaitGroup
+	for i, c := range comets {
+		wg.Go(func() { got[i], _ = Compute(c, earth) })
+	}
+	wg.Wait()
+	for i := range comets {
+		if got[i] != want[i] {
+			t.Errorf("comet %d: concurrent call gave %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestComputeOpenPlanetOrbit(t *testing.T) {
+	_, err := Compute(orbit.ElementsFromDegrees(1, 0.5, 0, 0, 0, 0), orbit.ElementsFromDegrees(1, 1, 0, 0, 0, 0))
+	if err != ErrOpenOrbit {
+		t.Errorf("err = %v, want ErrOpenOrbit", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package mpc разбирает форматы Центра малых планет (MPC).
+package mpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"backend-server/internal/app/observer"
+)
+
+// Observatory — строка списка кодов обсерваторий MPC (ObsCodes).
+type Observatory struct {
+	Code string
+	Name string
+	Site *observer.Site // nil для космических и подвижных обсерваторий без постоянных параллакса
+}
+
+// ParseObsCodes разбирает список обсерваторий MPC в текстовом виде или в виде
+// страницы ObsCodes.html. Колонки (с единицы): 1–3 код, 5–13 долгота, 14–21 ρ·cos φ′,
+// 22–30 ρ·sin φ′, с 31 — название. Заголовок и HTML-разметка пропускаются.
+func ParseObsCodes(r io.Reader) ([]Observatory, error) {
+	var out []Observatory
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if !isObsCodeLine(text) {
+			continue
+		}
+		o, err := parseObsCodeLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, o)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// isObsCodeLine отличает строки обсерваторий от заголовка и разметки:
+// строка начинается с трёхсимвольного кода из цифр и латинских букв.
+func isObsCodeLine(s string) bool {
+	if len(s) < 4 || s[3] != ' ' || strings.HasPrefix(s, "Code") {
+		return false
+	}
+	for i := range 3 {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func parseObsCodeLine(s string) (Observatory, error) {
+	o := Observatory{
+		Code: s[:3],
+		Name: strings.TrimSpace(column(s, 30, len(s))),
+	}
+	lon := strings.TrimSpace(column(s, 4, 13))
+	cos := strings.TrimSpace(column(s, 13, 21))
+	sin := strings.TrimSpace(column(s, 21, 30))
+	if lon == "" && cos == "" && sin == "" {
+		return o, nil
+	}
+
+	var site observer.Site
+	var err error
+	if site.Longitude, err = strconv.ParseFloat(lon, 64); err != nil {
+		return o, fmt.Errorf("observatory %s: invalid longitude %q", o.Code, lon)
+	}
+	if site.RhoCos, err = strconv.ParseFloat(cos, 64); err != nil {
+		return o, fmt.Errorf("observatory %s: invalid rho cos phi %q", o.Code, cos)
+	}
+	if site.RhoSin, err = strconv.ParseFloat(sin, 64); err != nil {
+		return o, fmt.Errorf("observatory %s: invalid rho sin phi %q", o.Code, sin)
+	}
+	o.Site = &site
+	return o, nil
+}
+
+// column возвращает s[from:to], обрезанную по длине строки.
+func column(s string, from, to int) string {
+	if from >= len(s) {
+		return ""
+	}
+	return s[from:min(to, len(s))]
+}
@@ -0,0 +1,266 @@
+package mpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Observation — оптическое наблюдение из отчёта в 80-колоночном формате MPC.
+type Observation struct {
+	Line        int       // номер строки (для роверов — первой из двух)
+	Designation string    // номер или обозначение объекта, колонки 1–12
+	Note        byte      // тип наблюдения (колонка 15): ' ', 'C', 'P', 'V' и т. д.
+	Time        time.Time // момент наблюдения, UTC
+	RA          float64   // прямое восхождение, градусы
+	Dec         float64   // склонение, градусы
+	Magnitude   *float64  // блеск, nil — не измерен
+	Band        string    // фотометрическая полоса
+	Observatory string    // код обсерватории
+
+	// Положение подвижного наблюдателя (код 247) из второй строки: широта, долгота
+	// к востоку (градусы) и высота (м)
+	Roving *Roving
+}
+
+// Roving — координаты подвижного наблюдателя.
+type Roving struct {
+	Latitude, Longitude, Altitude float64
+}
+
+// LineError — ошибка разбора одной строки отчёта.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ParseError объединяет ошибки разбора отдельных строк.
+type ParseError []LineError
+
+func (e ParseError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("line %d: %s", e[0].Line, e[0].Error)
+	}
+	return fmt.Sprintf("line %d: %s (and %d more errors)", e[0].Line, e[0].Error, len(e)-1)
+}
+
+// Типы наблюдений (колонка 15): для роверов и спутников за строкой следует вторая строка
+// с положением наблюдателя, X помечает удалённые наблюдения.
+const (
+	noteRoving     = 'V'
+	noteRoving2    = 'v'
+	noteSatellite  = 'S'
+	noteSatellite2 = 's'
+	noteDeleted    = 'X'
+	noteDeleted2   = 'x'
+)
+
+// ParseObservations разбирает оптические наблюдения в 80-колоночном формате MPC.
+// Пустые строки и заголовок отчёта (строки COD, OBS, ACK и т. п.) пропускаются.
+// Некорректные строки не прерывают разбор: их ошибки возвращаются как ParseError
+// вместе с успешно разобранными наблюдениями.
+func ParseObservations(r io.Reader) ([]Observation, error) {
+	var (
+		out     []Observation
+		errs    ParseError
+		pending *Observation // наблюдение подвижного наблюдателя, ждущее вторую строку
+	)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(text) == "" || isHeaderLine(text) {
+			continue
+		}
+		if len(text) < 80 {
+			errs = append(errs, LineError{Line: line, Error: fmt.Sprintf("record is %d characters long, expected 80", len(text))})
+			continue
+		}
+		if len(text) > 80 {
+			text = text[:80]
+		}
+
+		switch note := text[14]; {
+		case note == noteRoving2:
+			if pending == nil {
+				errs = append(errs, LineError{Line: line, Error: "roving observer line without a preceding observation"})
+				continue
+			}
+			rv, err := parseRovingLine(text)
+			if err != nil {
+				errs = append(errs, LineError{Line: line, Error: err.Error()})
+			} else {
+				pending.Roving = rv
+				out = append(out, *pending)
+			}
+			pending = nil
+			continue
+		case note == noteSatellite:
+			errs = append(errs, LineError{Line: line, Error: "satellite observations are not supported"})
+			continue
+		case note == noteSatellite2 || note == noteDeleted || note == noteDeleted2:
+			// вторая строка спутникового наблюдения уже учтена, удалённые наблюдения пропускаются
+			continue
+		}
+
+		if pending != nil {
+			errs = append(errs, LineError{Line: pending.Line, Error: "roving observation without the observer position line"})
+			pending = nil
+		}
+		o, err := parseObservationLine(text)
+		if err != nil {
+			errs = append(errs, LineError{Line: line, Error: err.Error()})
+			continue
+		}
+		o.Line = line
+		if o.Note == noteRoving {
+			pending = &o
+			continue
+		}
+		out = append(out, o)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		errs = append(errs, LineError{Line: pending.Line, Error: "roving observation without the observer position line"})
+	}
+	if len(errs) > 0 {
+		return out, errs
+	}
+	return out, nil
+}
+
+// isHeaderLine распознаёт строки заголовка отчёта MPC (COD, CON, OBS, MEA, TEL, ACK, AC2, NET, COM, BND, NUM).
+func isHeaderLine(s string) bool {
+	if len(s) < 4 || s[3] != ' ' {
+		return false
+	}
+	switch s[:3] {
+	case "COD", "CON", "OBS", "MEA", "TEL", "ACK", "AC2", "NET", "COM", "BND", "NUM":
+		return true
+	}
+	return false
+}
+
+// parseObservationLine разбирает строку наблюдения. Колонки (с единицы): 1–12 обозначение,
+// 15 тип наблюдения, 16–32 дата «YYYY MM DD.dddddd», 33–44 RA «HH MM SS.sss»,
+// 45–56 Dec «sDD MM SS.ss», 66–70 блеск, 71 полоса, 78–80 код обсерватории.
+func parseObservationLine(s string) (Observation, error) {
+	o := Observation{
+		Designation: strings.TrimSpace(s[:12]),
+		Note:        s[14],
+		Band:        strings.TrimSpace(s[70:71]),
+		Observatory: strings.TrimSpace(s[77:80]),
+	}
+	if len(o.Observatory) != 3 {
+		return o, fmt.Errorf("invalid observatory code %q", s[77:80])
+	}
+
+	var err error
+	if o.Time, err = parseDate(s[15:32]); err != nil {
+		return o, err
+	}
+	if o.RA, err = parseSexagesimal(s[32:44], false); err != nil {
+		return o, fmt.Errorf("invalid RA %q: %w", strings.TrimSpace(s[32:44]), err)
+	}
+	o.RA *= 15
+	if o.RA >= 360 {
+		return o, fmt.Errorf("RA %q is out of range", strings.TrimSpace(s[32:44]))
+	}
+	if o.Dec, err = parseSexagesimal(s[44:56], true); err != nil {
+		return o, fmt.Errorf("invalid Dec %q: %w", strings.TrimSpace(s[44:56]), err)
+	}
+	if math.Abs(o.Dec) > 90 {
+		return o, fmt.Errorf("Dec %q is out of range", strings.TrimSpace(s[44:56]))
+	}
+	if mag := strings.TrimSpace(s[65:70]); mag != "" {
+		m, err := strconv.ParseFloat(mag, 64)
+		if err != nil {
+			return o, fmt.Errorf("invalid magnitude %q", mag)
+		}
+		o.Magnitude = &m
+	}
+	return o, nil
+}
+
+// parseDate разбирает дату с дробной частью суток «YYYY MM DD.dddddd» (UTC).
+func parseDate(s string) (time.Time, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", strings.TrimSpace(s))
+	}
+	year, err1 := strconv.Atoi(fields[0])
+	month, err2 := strconv.Atoi(fields[1])
+	day, err3 := strconv.ParseFloat(fields[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil || month < 1 || month > 12 || day < 1 || day >= 32 {
+		return time.Time{}, fmt.Errorf("invalid date %q", strings.TrimSpace(s))
+	}
+	whole := math.Floor(day)
+	t := time.Date(year, time.Month(month), int(whole), 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) {
+		return time.Time{}, fmt.Errorf("invalid date %q", strings.TrimSpace(s))
+	}
+	frac := time.Duration(math.Round((day - whole) * 86400e6))
+	return t.Add(frac * time.Microsecond), nil
+}
+
+// parseSexagesimal разбирает «DD MM SS.ss» (часы или градусы) в доли единицы.
+// Допускаются опущенные секунды или минуты (например, «12 34.5»). signed разрешает знак.
+func parseSexagesimal(s string, signed bool) (float64, error) {
+	s = strings.TrimSpace(s)
+	sign := 1.0
+	if signed && s != "" && (s[0] == '+' || s[0] == '-') {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0, errors.New("expected up to three fields")
+	}
+	value, scale := 0.0, 1.0
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid field %q", f)
+		}
+		if i > 0 && v >= 60 {
+			return 0, fmt.Errorf("field %q must be below 60", f)
+		}
+		// дробная часть допустима только у последнего поля
+		if i < len(fields)-1 && v != math.Trunc(v) {
+			return 0, fmt.Errorf("only the last field may be fractional")
+		}
+		value += v / scale
+		scale *= 60
+	}
+	return sign * value, nil
+}
+
+// parseRovingLine разбирает вторую строку подвижного наблюдателя: колонки 35–44 долгота
+// к востоку, 45–55 широта со знаком (градусы), 57–61 высота (м).
+func parseRovingLine(s string) (*Roving, error) {
+	lon, err := strconv.ParseFloat(strings.TrimSpace(s[34:44]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid roving longitude %q", strings.TrimSpace(s[34:44]))
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(s[44:55]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid roving latitude %q", strings.TrimSpace(s[44:55]))
+	}
+	alt := 0.0
+	if v := strings.TrimSpace(s[56:61]); v != "" {
+		if alt, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("invalid roving altitude %q", v)
+		}
+	}
+	return &Roving{Latitude: lat, Longitude: lon, Altitude: alt}, nil
+}
@@ -0,0 +1,251 @@
+// Package nbody распространяет орбиту кометы численным интегрированием с учётом
+// притяжения Солнца, восьми планет и Луны. Положения возмущающих тел берутся из
+// источника planets.Source (встроенная эфемерида или эфемерида JPL), интегратор —
+// Рунге — Кутта — Фельберга 4(5) с автоматическим выбором шага.
+//
+// Уравнения движения записаны в гелиоцентрической системе (экватор J2000):
+//
+//	r̈ = −μ☉·r/|r|³ + Σⱼ μⱼ·[(rⱼ − r)/|rⱼ − r|³ − rⱼ/|rⱼ|³],
+//
+// второй член суммы — косвенное ускорение Солнца, притягиваемого планетами.
+package nbody
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// Model — модель движения кометы.
+type Model string
+
+const (
+	ModelTwoBody Model = "two_body" // задача двух тел, решение уравнения Кеплера
+	ModelNBody   Model = "n_body"   // численное интегрирование с возмущениями планет и Луны
+)
+
+// DefaultTolerance — относительная точность шага интегрирования по умолчанию.
+const DefaultTolerance = 1e-12
+
+// maxSteps ограничивает число шагов в одну сторону от начальной эпохи.
+const maxSteps = 1_000_000
+
+// ErrStepLimit возвращается, если интегрирование требует слишком много шагов —
+// обычно при прохождении вплотную к планете.
+var ErrStepLimit = errors.New("nbody: step limit exceeded")
+
+// ParseModel разбирает название модели; пустая строка означает задачу двух тел.
+func ParseModel(s string) (Model, error) {
+	switch m := Model(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModelTwoBody, nil
+	case ModelTwoBody, ModelNBody:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown model %q: use %s or %s", s, ModelTwoBody, ModelNBody)
+}
+
+// NewPropagator возвращает распространитель орбиты в модели model по гелиоцентрическому
+// экваториальному состоянию s на момент epoch (TT). В модели n_body положения
+// возмущающих тел берутся из src.
+func NewPropagator(model Model, s orbit.State, epoch float64, src planets.Source) (orbit.Propagator, error) {
+	switch model {
+	case "", ModelTwoBody:
+		return orbit.Kepler{State: s, Epoch: epoch, Mu: orbit.MuSun}, nil
+	case ModelNBody:
+		return New(src, s, epoch), nil
+	}
+	return nil, fmt.Errorf("nbody: unknown model %q", model)
+}
+
+// Integrator интегрирует движение кометы от начального состояния в обе стороны.
+// Принятые шаги запоминаются, поэтому запросы в любом порядке не повторяют интегрирование,
+// а результат не зависит от их порядка. Не предназначен для одновременного использования
+// из нескольких горутин.
+type Integrator struct {
+	// Tolerance — допустимая относительная ошибка шага (DefaultTolerance).
+	Tolerance float64
+
+	src               planets.Source
+	start, end        float64   // охват src, JD (TT); ±Inf, если он не ограничен
+	gm                []float64 // гравитационные параметры planets.Perturbers
+	forward, backward track
+}
+
+// track — принятые шаги интегрирования в одну сторону от начальной эпохи.
+type track struct {
+	dir    float64 // +1 вперёд, −1 назад
+	t      []float64
+	states []orbit.State
+	h      float64 // следующий пробный шаг, со знаком
+}
+
+// positioner реализуют источники, вычисляющие одно положение дешевле полного состояния
+// (planets.DefaultSource, planets.Analytic).
+type positioner interface {
+	Position(b planets.Body, jd float64) (orbit.Vec3, error)
+}
+
+// New возвращает интегратор с источником положений возмущающих тел src и начальным
+// состоянием s на момент epoch (TT).
+func New(src planets.Source, s orbit.State, epoch float64) *Integrator {
+	in := &Integrator{
+		Tolerance: DefaultTolerance,
+		src:       src,
+		start:     math.Inf(-1),
+		end:       math.Inf(1),
+		gm:        make([]float64, len(planets.Perturbers)),
+	}
+	if b, ok := src.(planets.Bounded); ok {
+		in.start, in.end = b.Span()
+	}
+	for i, b := range planets.Perturbers {
+		in.gm[i] = b.GM()
+	}
+	// начальный шаг — малая доля характерного времени движения на расстоянии |r|
+	r := s.R.Norm()
+	h := 0.01 * math.Sqrt(r*r*r/orbit.MuSun)
+	in.forward = track{dir: 1, t: []float64{epoch}, states: []orbit.State{s}, h: h}
+	in.backward = track{dir: -1, t: []float64{epoch}, states: []orbit.State{s}, h: -h}
+	return in
+}
+
+// At реализует orbit.Propagator.
+func (in *Integrator) At(jd float64) (orbit.State, error) {
+	if math.IsNaN(jd) || math.IsInf(jd, 0) {
+		return orbit.State{}, fmt.Errorf("nbody: invalid epoch %v", jd)
+	}
+	tr := &in.forward
+	if jd < tr.t[0] {
+		tr = &in.backward
+	}
+	for tr.dir*(jd-tr.t[len(tr.t)-1]) > 0 {
+		if err := in.advance(tr); err != nil {
+			return orbit.State{}, err
+		}
+	}
+
+	// последний принятый узел, не прошедший jd; от него — один укороченный шаг
+	k := sort.Search(len(tr.t), func(i int) bool { return tr.dir*(tr.t[i]-jd) > 0 }) - 1
+	if tr.t[k] == jd {
+		return tr.states[k], nil
+	}
+	s, _, err := in.step(tr.t[k], tr.states[k], jd-tr.t[k])
+	return s, err
+}
+
+// advance делает один принятый шаг в конце трека.
+func (in *Integrator) advance(tr *track) error {
+	if len(tr.t) > maxSteps {
+		return ErrStepLimit
+	}
+	t, s := tr.t[len(tr.t)-1], tr.states[len(tr.states)-1]
+	start, end := in.start, in.end
+	for {
+		h := tr.h
+		// шаги не выходят за охват источника, чтобы узлы до его границы оставались доступны
+		if tr.dir > 0 && t < end && t+h > end {
+			h = end - t
+		}
+		if tr.dir < 0 && t > start && t+h < start {
+			h = start - t
+		}
+		next, errNorm, err := in.step(t, s, h)
+		if err != nil {
+			return err
+		}
+		factor := 5.0
+		if errNorm > 0 {
+			factor = math.Min(5, math.Max(0.2, 0.9*math.Pow(errNorm, -0.2)))
+		}
+		if errNorm <= 1 {
+			tr.t = append(tr.t, t+h)
+			tr.states = append(tr.states, next)
+			if h == tr.h {
+				tr.h *= factor
+			}
+			return nil
+		}
+		tr.h = h * factor
+		if math.Abs(tr.h) < 1e-9 {
+			return ErrStepLimit
+		}
+	}
+}
+
+// Коэффициенты метода Рунге — Кутта — Фельберга 4(5).
+var (
+	rkfC = [6]float64{0, 1.0 / 4, 3.0 / 8, 12.0 / 13, 1, 1.0 / 2}
+	rkfA = [6][5]float64{
+		{},
+		{1.0 / 4},
+		{3.0 / 32, 9.0 / 32},
+		{1932.0 / 2197, -7200.0 / 2197, 7296.0 / 2197},
+		{439.0 / 216, -8, 3680.0 / 513, -845.0 / 4104},
+		{-8.0 / 27, 2, -3544.0 / 2565, 1859.0 / 4104, -11.0 / 40},
+	}
+	rkfB5 = [6]float64{16.0 / 135, 0, 6656.0 / 12825, 28561.0 / 56430, -9.0 / 50, 2.0 / 55}
+	rkfB4 = [6]float64{25.0 / 216, 0, 1408.0 / 2565, 2197.0 / 4104, -1.0 / 5, 0}
+)
+
+// step делает шаг h из состояния s на момент t и возвращает решение пятого порядка
+// и оценку ошибки в долях допустимой.
+func (in *Integrator) step(t float64, s orbit.State, h float64) (orbit.State, float64, error) {
+	var kr, kv [6]orbit.Vec3 // производные положения и скорости на стадиях
+	for i := range 6 {
+		r, v := s.R, s.V
+		for j := range i {
+			r = r.Add(kr[j].Scale(h * rkfA[i][j]))
+			v = v.Add(kv[j].Scale(h * rkfA[i][j]))
+		}
+		a, err := in.acceleration(t+rkfC[i]*h, r)
+		if err != nil {
+			return orbit.State{}, 0, err
+		}
+		kr[i], kv[i] = v, a
+	}
+
+	next := s
+	var errR, errV orbit.Vec3
+	for i := range 6 {
+		next.R = next.R.Add(kr[i].Scale(h * rkfB5[i]))
+		next.V = next.V.Add(kv[i].Scale(h * rkfB5[i]))
+		errR = errR.Add(kr[i].Scale(h * (rkfB5[i] - rkfB4[i])))
+		errV = errV.Add(kv[i].Scale(h * (rkfB5[i] - rkfB4[i])))
+	}
+	errNorm := math.Max(
+		errR.Norm()/(in.Tolerance*next.R.Norm()),
+		errV.Norm()/(in.Tolerance*next.V.Norm()),
+	)
+	return next, errNorm, nil
+}
+
+// acceleration возвращает гелиоцентрическое ускорение кометы в точке r на момент jd.
+func (in *Integrator) acceleration(jd float64, r orbit.Vec3) (orbit.Vec3, error) {
+	d := r.Norm()
+	a := r.Scale(-orbit.MuSun / (d * d * d))
+	for i, b := range planets.Perturbers {
+		rb, err := in.position(b, jd)
+		if err != nil {
+			return orbit.Vec3{}, err
+		}
+		rel := rb.Sub(r)
+		dr, db := rel.Norm(), rb.Norm()
+		a = a.Add(rel.Scale(in.gm[i] / (dr * dr * dr))).Sub(rb.Scale(in.gm[i] / (db * db * db)))
+	}
+	return a, nil
+}
+
+// position возвращает гелиоцентрическое положение тела b на момент jd.
+func (in *Integrator) position(b planets.Body, jd float64) (orbit.Vec3, error) {
+	if p, ok := in.src.(positioner); ok {
+		return p.Position(b, jd)
+	}
+	s, err := in.src.State(b, jd)
+	return s.R, err
+}
@@ -0,0 +1,51 @@
+// Package observer вычисляет положение наземного наблюдателя относительно центра Земли,
+// чтобы учитывать суточный параллакс при расчёте видимых положений.
+package observer
+
+import (
+	"math"
+	"time"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbit"
+)
+
+const (
+	// EarthRadiusKm — экваториальный радиус Земли (WGS84), км.
+	EarthRadiusKm = 6378.137
+	// earthFlattening — сжатие эллипсоида WGS84.
+	earthFlattening = 1 / 298.257223563
+)
+
+// Site — положение наблюдателя в параллактических постоянных MPC.
+type Site struct {
+	Longitude float64 // долгота к востоку от Гринвича, градусы
+	RhoCos    float64 // ρ·cos φ′ в экваториальных радиусах Земли
+	RhoSin    float64 // ρ·sin φ′ в экваториальных радиусах Земли
+}
+
+// FromGeodetic возвращает положение наблюдателя по геодезическим широте и долготе
+// (градусы, долгота к востоку) и высоте над эллипсоидом WGS84 (метры).
+func FromGeodetic(lat, lon, alt float64) Site {
+	sl, cl := math.Sincos(lat * orbit.Deg)
+	b := 1 - earthFlattening
+	c := 1 / math.Sqrt(cl*cl+b*b*sl*sl)
+	h := alt / 1000 / EarthRadiusKm
+	return Site{
+		Longitude: lon,
+		RhoCos:    (c + h) * cl,
+		RhoSin:    (b*b*c + h) * sl,
+	}
+}
+
+// Geocentric возвращает геоцентрический вектор наблюдателя (а.е.) в экваториальной
+// системе J2000 на момент t (UTC). Нутация не учитывается: это ошибка порядка 0.5 км,
+// много меньше самого параллакса.
+func (s Site) Geocentric(t time.Time) orbit.Vec3 {
+	theta := astrotime.GMST(t) + s.Longitude*orbit.Deg
+	st, ct := math.Sincos(theta)
+	r := EarthRadiusKm / orbit.KmPerAU
+	ofDate := orbit.Vec3{r * s.RhoCos * ct, r * s.RhoCos * st, r * s.RhoSin}
+	c := astrotime.CenturiesSinceJ2000(astrotime.TT(t))
+	return orbit.PrecessionMatrix(c).Transpose().Apply(ofDate)
+}
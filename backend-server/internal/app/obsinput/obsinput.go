@@ -0,0 +1,254 @@
+// Package obsinput приводит координаты и моменты наблюдений в том виде, в каком их вводят
+// пользователи, к каноническому: прямое восхождение и склонение — в градусах, момент — в UTC.
+//
+// Прямое восхождение принимается в градусах (187.5, 187.5°), в часах (12.5h) или
+// шестидесятеричным (12h34m56.7s, 12:34:56.7, 12 34 56.7 — часы; 187d30m — градусы).
+// Склонение — в градусах, в том числе шестидесятеричным (-12:34:56, -12°34′56″).
+// Момент — в ISO 8601, юлианской (JD 2460000.5) или модифицированной юлианской
+// (MJD 60000) дате в шкале UTC или TT.
+package obsinput
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbitclient"
+)
+
+// Scale — шкала времени, в которой задан момент наблюдения.
+type Scale string
+
+const (
+	ScaleUTC Scale = "utc" // всемирное координированное время
+	ScaleTT  Scale = "tt"  // земное время, TT = UTC + (TAI − UTC) + 32.184 с
+)
+
+// minJD — наименьшее число, которое без префикса считается юлианской датой, а не MJD.
+const minJD = 1e6
+
+// ErrEmpty возвращается для пустого значения.
+var ErrEmpty = errors.New("is required")
+
+// ParseScale разбирает название шкалы времени. Для пустой строки возвращается пустая
+// шкала — не указана.
+func ParseScale(s string) (Scale, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return "", nil
+	case string(ScaleUTC):
+		return ScaleUTC, nil
+	case string(ScaleTT):
+		return ScaleTT, nil
+	}
+	return "", fmt.Errorf("unknown time scale %q: use utc or tt", s)
+}
+
+// ParseRA разбирает прямое восхождение и возвращает его в градусах, от 0 до 360.
+// Шестидесятеричное значение без единиц (12:34:56.7, 12 34 56.7) считается заданным в часах.
+func ParseRA(s string) (float64, error) {
+	v, err := parseSexagesimal(s)
+	if err != nil {
+		return 0, err
+	}
+	if v.neg {
+		return 0, errors.New("must not be negative")
+	}
+	deg := v.value()
+	if v.unit == 'h' || (v.unit == 0 && len(v.parts) > 1) {
+		if deg >= 24 {
+			return 0, fmt.Errorf("%g hours is out of range [0, 24)", deg)
+		}
+		deg *= 15
+	}
+	if deg >= 360 {
+		return 0, fmt.Errorf("%g° is out of range [0, 360)", deg)
+	}
+	return deg, nil
+}
+
+// ParseDec разбирает склонение и возвращает его в градусах, от −90 до 90.
+func ParseDec(s string) (float64, error) {
+	v, err := parseSexagesimal(s)
+	if err != nil {
+		return 0, err
+	}
+	if v.unit == 'h' {
+		return 0, errors.New("declination cannot be given in hours")
+	}
+	deg := v.value()
+	if v.neg {
+		deg = -deg
+	}
+	if deg < -90 || deg > 90 {
+		return 0, fmt.Errorf("%g° is out of range [-90, 90]", deg)
+	}
+	return deg, nil
+}
+
+// ParseTime разбирает момент наблюдения и возвращает его в UTC. Шкалу можно указать
+// и в конце строки («2020-07-23T03:12:45 TT»); она должна совпадать со scale, если
+// scale не пустая; если шкала не указана нигде, момент считается заданным в UTC. Число
+// без префикса JD или MJD считается юлианской датой, если оно не меньше миллиона,
+// и модифицированной юлианской датой иначе.
+func ParseTime(s string, scale Scale) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, ErrEmpty
+	}
+	if fields := strings.Fields(s); len(fields) > 1 {
+		if suffix, err := ParseScale(fields[len(fields)-1]); err == nil {
+			if scale != "" && suffix != scale {
+				return time.Time{}, fmt.Errorf("time scale %s in the value conflicts with %s", strings.ToUpper(string(suffix)), strings.ToUpper(string(scale)))
+			}
+			scale = suffix
+			s = strings.TrimSpace(strings.Join(fields[:len(fields)-1], " "))
+		}
+	}
+
+	jd, isJD, err := parseJulianDate(s)
+	switch {
+	case err != nil:
+		return time.Time{}, err
+	case isJD && scale == ScaleTT:
+		return astrotime.UTCFromTT(jd), nil
+	case isJD:
+		return astrotime.FromJulianDate(jd), nil
+	}
+
+	// дата ISO 8601 переводится без юлианской даты, чтобы не терять микросекунды
+	t, err := orbitclient.ParseTime(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unsupported time format %q: use ISO 8601, JD or MJD", s)
+	}
+	if scale == ScaleTT {
+		// TT − UTC берётся на момент UTC, который сначала оценивается по показаниям TT
+		utc := t.Add(-seconds(astrotime.TTMinusUTC(t)))
+		return t.Add(-seconds(astrotime.TTMinusUTC(utc))), nil
+	}
+	return t, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// parseJulianDate разбирает юлианскую дату с префиксом JD или MJD либо просто число.
+// isJD = false, если s не похоже на юлианскую дату.
+func parseJulianDate(s string) (jd float64, isJD bool, err error) {
+	upper := strings.ToUpper(s)
+	offset, prefixed := 0.0, false
+	switch {
+	case strings.HasPrefix(upper, "MJD"):
+		s, offset, prefixed = s[3:], astrotime.MJDOffset, true
+	case strings.HasPrefix(upper, "JD"):
+		s, prefixed = s[2:], true
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	switch {
+	case err != nil && prefixed:
+		return 0, false, fmt.Errorf("invalid Julian date %q", s)
+	case err != nil:
+		return 0, false, nil
+	case math.IsNaN(v) || math.IsInf(v, 0):
+		return 0, false, fmt.Errorf("invalid Julian date %q", s)
+	case !prefixed && v < minJD:
+		offset = astrotime.MJDOffset
+	}
+	return v + offset, true, nil
+}
+
+// sexagesimal — разобранное значение вида «±a[u] b[m] c[s]».
+type sexagesimal struct {
+	neg   bool
+	unit  byte      // единица первой части: 'h', 'd' или 0, если не указана
+	parts []float64 // от одной до трёх частей: единицы, минуты, секунды
+}
+
+func (v sexagesimal) value() float64 {
+	x := 0.0
+	for i, p := range v.parts {
+		x += p / math.Pow(60, float64(i))
+	}
+	return x
+}
+
+// symbols приводит обозначения единиц к буквам h, d, m, s.
+var symbols = strings.NewReplacer(
+	"°", "d", "º", "d", "ʰ", "h", "ᵐ", "m", "ˢ", "s",
+	"′", "m", "'", "m", "″", "s", `"`, "s", "−", "-",
+)
+
+// parseSexagesimal разбирает десятичное или шестидесятеричное значение. Части разделяются
+// двоеточиями, пробелами или буквами единиц (h или d, m, s); минуты и секунды меньше 60,
+// дробной может быть только последняя часть.
+func parseSexagesimal(s string) (sexagesimal, error) {
+	var v sexagesimal
+	s = symbols.Replace(strings.ToLower(strings.TrimSpace(s)))
+	if s == "" {
+		return v, ErrEmpty
+	}
+	switch s[0] {
+	case '-':
+		v.neg, s = true, s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	var units []byte // единицы частей в порядке следования, 0 — не указана
+	for s != "" {
+		s = strings.TrimLeft(s, ": \t")
+		if s == "" {
+			break
+		}
+		end := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return v, fmt.Errorf("unexpected %q", s)
+		}
+		p, err := strconv.ParseFloat(s[:end], 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid number %q", s[:end])
+		}
+		v.parts = append(v.parts, p)
+		s = s[end:]
+
+		unit := byte(0)
+		if s != "" && strings.IndexByte("hdms", s[0]) >= 0 {
+			unit, s = s[0], s[1:]
+		}
+		units = append(units, unit)
+		if s != "" && strings.IndexByte(": \t", s[0]) < 0 && unit == 0 {
+			return v, fmt.Errorf("unexpected %q", s)
+		}
+	}
+
+	if len(v.parts) == 0 {
+		return v, ErrEmpty
+	}
+	if len(v.parts) > 3 {
+		return v, errors.New("too many parts: expected at most degrees (or hours), minutes and seconds")
+	}
+	// единицы, если указаны, должны идти по порядку: h или d, затем m, затем s
+	for i, u := range units {
+		if u != 0 && u != "hms"[i] && (i != 0 || u != 'd') {
+			return v, fmt.Errorf("unexpected unit %q in part %d", u, i+1)
+		}
+	}
+	v.unit = units[0]
+	for i, p := range v.parts {
+		if i > 0 && p >= 60 {
+			return v, fmt.Errorf("minutes and seconds must be less than 60, got %g", p)
+		}
+		if i < len(v.parts)-1 && p != math.Trunc(p) {
+			return v, errors.New("only the last part may have a fractional value")
+		}
+	}
+	return v, nil
+}
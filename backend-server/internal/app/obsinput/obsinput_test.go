@@ -0,0 +1,172 @@
+package obsinput
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRA(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr string
+	}{
+		{in: "187.5", want: 187.5},
+		{in: " 187.5° ", want: 187.5},
+		{in: "0", want: 0},
+		{in: "12.5h", want: 187.5},
+		{in: "12h30m", want: 187.5},
+		{in: "12h34m56.7s", want: 15 * (12 + 34/60.0 + 56.7/3600)},
+		{in: "12:34:56.7", want: 15 * (12 + 34/60.0 + 56.7/3600)},
+		{in: "12 34 56.7", want: 15 * (12 + 34/60.0 + 56.7/3600)},
+		{in: "12ʰ34ᵐ56.7ˢ", want: 15 * (12 + 34/60.0 + 56.7/3600)},
+		{in: "187d30m", want: 187.5},
+		{in: "187°30′36″", want: 187.51},
+		{in: "+23:59:59.999", want: 15 * (23 + 59/60.0 + 59.999/3600)},
+		{in: "", wantErr: "is required"},
+		{in: "-1", wantErr: "negative"},
+		{in: "360", wantErr: "out of range"},
+		{in: "24:00:00", wantErr: "out of range"},
+		{in: "24h", wantErr: "out of range"},
+		{in: "12:60:00", wantErr: "less than 60"},
+		{in: "12:30:60", wantErr: "less than 60"},
+		{in: "12.5:30", wantErr: "only the last part"},
+		{in: "12:34:56:01", wantErr: "too many parts"},
+		{in: "12m34h", wantErr: "unexpected unit"},
+		{in: "12x", wantErr: "unexpected"},
+		{in: "1.2.3", wantErr: "invalid number"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRA(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRA(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRA(%q): %v", tt.in, err)
+		} else if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ParseRA(%q) = %.10f, want %.10f", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr string
+	}{
+		{in: "-12.25", want: -12.25},
+		{in: "+12:34:56", want: 12 + 34/60.0 + 56/3600.0},
+		{in: "-12:34:56", want: -(12 + 34/60.0 + 56/3600.0)},
+		{in: "−12°34′56″", want: -(12 + 34/60.0 + 56/3600.0)},
+		{in: "-12 34 56", want: -(12 + 34/60.0 + 56/3600.0)},
+		// знак относится ко всему значению, а не только к градусам
+		{in: "-00:30:00", want: -0.5},
+		{in: "-0 30", want: -0.5},
+		{in: "90", want: 90},
+		{in: "-90:00:00", want: -90},
+		{in: "90:00:01", wantErr: "out of range"},
+		{in: "-90.0001", wantErr: "out of range"},
+		{in: "12h", wantErr: "hours"},
+		{in: "--12", wantErr: "unexpected"},
+		{in: "-", wantErr: "is required"},
+	}
+	for _, tt := range tests {
+		got, err := ParseDec(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseDec(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDec(%q): %v", tt.in, err)
+		} else if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ParseDec(%q) = %.10f, want %.10f", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseScale(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Scale
+		wantErr bool
+	}{
+		{"", "", false},
+		{"utc", ScaleUTC, false},
+		{" TT ", ScaleTT, false},
+		{"tdb", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseScale(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseScale(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	j2000 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	// J2000.0 в шкале TT: TT − UTC = 32 + 32.184 с
+	j2000TT := j2000.Add(-64184 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		in      string
+		scale   Scale
+		want    time.Time
+		wantErr string
+	}{
+		{name: "iso", in: "2020-07-23T03:12:45.123456Z", want: time.Date(2020, 7, 23, 3, 12, 45, 123456000, time.UTC)},
+		{name: "iso without zone", in: "2020-07-23 03:12", want: time.Date(2020, 7, 23, 3, 12, 0, 0, time.UTC)},
+		{name: "iso with offset", in: "2020-07-23T06:12:45+03:00", want: time.Date(2020, 7, 23, 3, 12, 45, 0, time.UTC)},
+		{name: "jd", in: "JD 2451545.0", want: j2000},
+		{name: "jd lowercase", in: "jd2451545", want: j2000},
+		{name: "bare jd", in: "2451545.0", want: j2000},
+		{name: "mjd", in: "MJD 51544.5", want: j2000},
+		{name: "bare mjd", in: "51544.5", want: j2000},
+		{name: "fractional mjd", in: "MJD 60000.25", want: time.Date(2023, 2, 25, 6, 0, 0, 0, time.UTC)},
+		{name: "jd tt", in: "JD 2451545.0", scale: ScaleTT, want: j2000TT},
+		{name: "jd tt suffix", in: "2451545.0 TT", want: j2000TT},
+		{name: "mjd tt suffix", in: "MJD 51544.5 tt", want: j2000TT},
+		{name: "utc suffix", in: "2020-07-23T03:12:45 UTC", want: time.Date(2020, 7, 23, 3, 12, 45, 0, time.UTC)},
+		{name: "iso tt", in: "2020-07-23T03:13:54.184", scale: ScaleTT, want: time.Date(2020, 7, 23, 3, 12, 45, 0, time.UTC)},
+		// на границе дополнительной секунды 2016-12-31 TT − UTC меняется с 68.184 на 69.184 с
+		{name: "tt before leap second", in: "2017-01-01T00:01:07.684 TT", want: time.Date(2016, 12, 31, 23, 59, 59, 500e6, time.UTC)},
+		{name: "tt after leap second", in: "2017-01-01T00:01:10.184 TT", want: time.Date(2017, 1, 1, 0, 0, 1, 0, time.UTC)},
+		{name: "jd tt after leap second", in: "JD 2457754.5 TT", want: time.Date(2016, 12, 31, 23, 58, 51, 816e6, time.UTC)},
+		{name: "empty", in: "  ", wantErr: "is required"},
+		{name: "conflicting scales", in: "JD 2451545.0 TT", scale: ScaleUTC, wantErr: "conflicts"},
+		{name: "bad jd", in: "JD abc", wantErr: "invalid Julian date"},
+		{name: "infinite jd", in: "JD Inf", wantErr: "invalid Julian date"},
+		{name: "unknown format", in: "23/07/2020", wantErr: "unsupported time format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.in, tt.scale)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := got.Sub(tt.want); d < -time.Millisecond || d > time.Millisecond {
+				t.Errorf("ParseTime(%q, %q) = %s, want %s", tt.in, tt.scale, got.Format(time.RFC3339Nano), tt.want.Format(time.RFC3339Nano))
+			}
+		})
+	}
+
+	if _, err := ParseTime("", ""); !errors.Is(err, ErrEmpty) {
+		t.Errorf("empty: err = %v, want %v", err, ErrEmpty)
+	}
+}
@@ -0,0 +1,168 @@
+package orbit
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidElements возвращается, если элементы не задают орбиту.
+var ErrInvalidElements = errors.New("orbit: invalid orbital elements")
+
+// Elements — классические элементы орбиты, параметризованные перигелийным
+// расстоянием, поэтому описывают и эллиптические, и незамкнутые (e ≥ 1) орбиты.
+// Углы отсчитываются в той системе, в которой задан вектор состояния.
+type Elements struct {
+	Q       float64 // перигелийное расстояние, а.е.
+	E       float64 // эксцентриситет
+	I       float64 // наклонение, рад
+	Node    float64 // долгота восходящего узла, рад
+	ArgPeri float64 // аргумент перигелия, рад
+	Tp      float64 // момент прохождения перигелия, юлианская дата (TT)
+}
+
+// A возвращает большую полуось q/(1−e): положительную для эллипса,
+// отрицательную для гиперболы и +Inf для параболы.
+func (el Elements) A() float64 {
+	if el.E == 1 {
+		return math.Inf(1)
+	}
+	return el.Q / (1 - el.E)
+}
+
+// ElementsFromDegrees строит элементы по углам в градусах — в том виде,
+// в каком их хранит и возвращает API.
+func ElementsFromDegrees(q, e, i, node, argPeri, tp float64) Elements {
+	return Elements{
+		Q:       q,
+		E:       e,
+		I:       i * Deg,
+		Node:    node * Deg,
+		ArgPeri: argPeri * Deg,
+		Tp:      tp,
+	}
+}
+
+// Validate проверяет, что элементы задают орбиту: q > 0, e ≥ 0, все значения конечны.
+func (el Elements) Validate() error {
+	for _, v := range [...]float64{el.Q, el.E, el.I, el.Node, el.ArgPeri, el.Tp} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return ErrInvalidElements
+		}
+	}
+	if el.Q <= 0 || el.E < 0 {
+		return ErrInvalidElements
+	}
+	return nil
+}
+
+// MeanMotion возвращает среднее движение (рад/сут); для незамкнутых орбит — 0.
+func (el Elements) MeanMotion(mu float64) float64 {
+	if el.E >= 1 {
+		return 0
+	}
+	a := el.A()
+	return math.Sqrt(mu / (a * a * a))
+}
+
+// Period возвращает период обращения (сутки); для незамкнутых орбит — +Inf.
+func (el Elements) Period(mu float64) float64 {
+	if el.E >= 1 {
+		return math.Inf(1)
+	}
+	return 2 * math.Pi / el.MeanMotion(mu)
+}
+
+// ElementsFromState вычисляет элементы орбиты по вектору состояния на момент epoch (JD TT).
+func ElementsFromState(s State, epoch, mu float64) Elements {
+	h := s.R.Cross(s.V)
+	hn := h.Norm()
+	r := s.R.Norm()
+	v2 := s.V.Dot(s.V)
+
+	// вектор эксцентриситета
+	ev := s.R.Scale(v2/mu - 1/r).Sub(s.V.Scale(s.R.Dot(s.V) / mu))
+	e := ev.Norm()
+
+	inc := math.Acos(math.Max(-1, math.Min(1, h[2]/hn)))
+
+	// направление на восходящий узел; для орбиты в плоскости эклиптики — ось X
+	node := Vec3{-h[1], h[0], 0}
+	nodeNorm := node.Norm()
+	var raan float64
+	if nodeNorm < 1e-14*hn {
+		node = Vec3{1, 0, 0}
+	} else {
+		node = node.Scale(1 / nodeNorm)
+		raan = NormalizeAngle(math.Atan2(node[1], node[0]))
+	}
+	hHat := h.Scale(1 / hn)
+	inPlane := hHat.Cross(node)
+
+	// перицентр; для круговой орбиты совмещается с узлом
+	var peri Vec3
+	if e < 1e-12 {
+		peri = node
+	} else {
+		peri = ev.Scale(1 / e)
+	}
+	argp := NormalizeAngle(math.Atan2(peri.Dot(inPlane), peri.Dot(node)))
+	nu := math.Atan2(s.R.Dot(hHat.Cross(peri)), s.R.Dot(peri))
+
+	p := hn * hn / mu
+	q := p / (1 + e)
+
+	return Elements{
+		Q:       q,
+		E:       e,
+		I:       inc,
+		Node:    raan,
+		ArgPeri: argp,
+		Tp:      epoch - timeFromPerihelion(q, e, nu, mu),
+	}
+}
+
+// timeFromPerihelion возвращает время движения от перигелия до истинной аномалии nu.
+// Используется универсальная аномалия χ, поэтому формула непрерывна при e → 1.
+func timeFromPerihelion(q, e, nu, mu float64) float64 {
+	t := math.Tan(nu / 2)
+	w := math.Sqrt(math.Abs(1-e)/(1+e)) * t
+
+	// ratio = atan(w)/w для эллипса и atanh(w)/w для гиперболы
+	var ratio float64
+	switch {
+	case math.Abs(w) < 1e-4:
+		if e < 1 {
+			ratio = 1 - w*w/3
+		} else {
+			ratio = 1 + w*w/3
+		}
+	case e < 1:
+		ratio = math.Atan(w) / w
+	default:
+		ratio = math.Atanh(w) / w
+	}
+
+	chi := 2 * math.Sqrt(q/(1+e)) * t * ratio
+	alpha := (1 - e) / q
+	z := alpha * chi * chi
+	return (q*chi + (1-alpha*q)*chi*chi*chi*stumpffS(z)) / math.Sqrt(mu)
+}
+
+// StateFromElements возвращает вектор состояния на момент epoch (JD TT)
+// в той же системе, в которой отсчитываются углы элементов.
+func StateFromElements(el Elements, epoch, mu float64) (State, error) {
+	if err := el.Validate(); err != nil {
+		return State{}, err
+	}
+
+	// перифокальный базис: P — на перигелий, Q — на 90° по движению
+	rot := RotZ(el.ArgPeri).Mul(RotX(el.I)).Mul(RotZ(el.Node)).Transpose()
+	pHat := rot.Apply(Vec3{1, 0, 0})
+	qHat := rot.Apply(Vec3{0, 1, 0})
+
+	atPeri := State{
+		R: pHat.Scale(el.Q),
+		V: qHat.Scale(math.Sqrt(mu * (1 + el.E) / el.Q)),
+	}
+	return Propagate(atPeri, epoch-el.Tp, mu)
+}
@@ -0,0 +1,56 @@
+package orbit
+
+import "math"
+
+// ObliquityJ2000 — наклон эклиптики к экватору на эпоху J2000.0 (84381.448″).
+const ObliquityJ2000 = 84381.448 * ArcSec
+
+var (
+	eclipticToEquatorial = RotX(-ObliquityJ2000)
+	equatorialToEcliptic = RotX(ObliquityJ2000)
+)
+
+// EclipticToEquatorial переводит вектор из эклиптики J2000 в экваториальную систему J2000.
+func EclipticToEquatorial(v Vec3) Vec3 { return eclipticToEquatorial.Apply(v) }
+
+// EquatorialToEcliptic переводит вектор из экваториальной системы J2000 в эклиптику J2000.
+func EquatorialToEcliptic(v Vec3) Vec3 { return equatorialToEcliptic.Apply(v) }
+
+// ToEquatorial переводит вектор состояния из эклиптики J2000 в экваториальную систему J2000.
+func (s State) ToEquatorial() State {
+	return State{R: EclipticToEquatorial(s.R), V: EclipticToEquatorial(s.V)}
+}
+
+// ToEcliptic переводит вектор состояния из экваториальной системы J2000 в эклиптику J2000.
+func (s State) ToEcliptic() State {
+	return State{R: EquatorialToEcliptic(s.R), V: EquatorialToEcliptic(s.V)}
+}
+
+// MeanObliquity возвращает средний наклон эклиптики (IAU 1976) на T юлианских столетий от J2000.
+func MeanObliquity(t float64) float64 {
+	return ObliquityJ2000 + (-46.8150*t-0.00059*t*t+0.001813*t*t*t)*ArcSec
+}
+
+// PrecessionMatrix возвращает матрицу прецессии IAU 1976 (Lieske), переводящую
+// экваториальные координаты J2000 в среднюю экваториальную систему эпохи,
+// отстоящей на T юлианских столетий от J2000. Обратный переход — Transpose.
+func PrecessionMatrix(t float64) Mat3 {
+	zeta := (2306.2181*t + 0.30188*t*t + 0.017998*t*t*t) * ArcSec
+	z := (2306.2181*t + 1.09468*t*t + 0.018203*t*t*t) * ArcSec
+	theta := (2004.3109*t - 0.42665*t*t - 0.041833*t*t*t) * ArcSec
+	return RotZ(-z).Mul(RotY(theta)).Mul(RotZ(-zeta))
+}
+
+// UnitVector возвращает единичный вектор направления с прямым восхождением ra и склонением dec.
+func UnitVector(ra, dec float64) Vec3 {
+	sd, cd := math.Sincos(dec)
+	sr, cr := math.Sincos(ra)
+	return Vec3{cd * cr, cd * sr, sd}
+}
+
+// RADec возвращает прямое восхождение в [0, 2π) и склонение направления v.
+func RADec(v Vec3) (ra, dec float64) {
+	ra = NormalizeAngle(math.Atan2(v[1], v[0]))
+	dec = math.Asin(v[2] / v.Norm())
+	return ra, dec
+}
@@ -0,0 +1,159 @@
+package orbit
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoConvergence возвращается, если уравнение Кеплера не удалось решить.
+var ErrNoConvergence = errors.New("orbit: kepler equation did not converge")
+
+const (
+	keplerMaxIterations = 50
+	keplerTolerance     = 1e-13
+)
+
+// stumpffC и stumpffS — функции Штумпфа C(z) и S(z).
+// Около нуля используются ряды, чтобы избежать потери точности.
+func stumpffC(z float64) float64 {
+	switch {
+	case z > 1:
+		return (1 - math.Cos(math.Sqrt(z))) / z
+	case z < -1:
+		return (math.Cosh(math.Sqrt(-z)) - 1) / -z
+	}
+	sum, term := 0.0, 0.5
+	for k := 1; math.Abs(term) > 1e-18; k++ {
+		sum += term
+		term *= -z / float64((2*k+1)*(2*k+2))
+	}
+	return sum
+}
+
+func stumpffS(z float64) float64 {
+	switch {
+	case z > 1:
+		sz := math.Sqrt(z)
+		return (sz - math.Sin(sz)) / (sz * z)
+	case z < -1:
+		sz := math.Sqrt(-z)
+		return (math.Sinh(sz) - sz) / (sz * -z)
+	}
+	sum, term := 0.0, 1.0/6
+	for k := 1; math.Abs(term) > 1e-18; k++ {
+		sum += term
+		term *= -z / float64((2*k+2)*(2*k+3))
+	}
+	return sum
+}
+
+// Lagrange — коэффициенты Лагранжа f, g, ḟ, ġ, связывающие состояние
+// в начальный момент с состоянием через dt суток: r = f·r₀ + g·v₀, v = ḟ·r₀ + ġ·v₀.
+type Lagrange struct {
+	F, G, FDot, GDot float64
+}
+
+// LagrangeCoefficients решает уравнение Кеплера в универсальных переменных для
+// смещения по времени dt и возвращает коэффициенты Лагранжа. Работает для
+// эллиптических, параболических и гиперболических орбит.
+func LagrangeCoefficients(s State, dt, mu float64) (Lagrange, error) {
+	r0 := s.R.Norm()
+	v0 := s.V.Norm()
+	sqrtMu := math.Sqrt(mu)
+	sigma0 := s.R.Dot(s.V) / sqrtMu
+	alpha := 2/r0 - v0*v0/mu
+
+	// для эллипса сдвиг приводится к одному периоду: состояние повторяется
+	tof := dt
+	if alpha > 0 {
+		period := 2 * math.Pi / math.Sqrt(mu*alpha*alpha*alpha)
+		tof = math.Mod(dt, period)
+	}
+
+	chi := initialChi(s, r0, sigma0, alpha, tof, mu)
+
+	var c, sz, r float64
+	converged := false
+	for range keplerMaxIterations {
+		z := alpha * chi * chi
+		c, sz = stumpffC(z), stumpffS(z)
+
+		chi2 := chi * chi
+		t1, t2, t3 := sigma0*chi2*c, (1-alpha*r0)*chi2*chi*sz, r0*chi
+		f := t1 + t2 + t3 - sqrtMu*tof
+		// невязка на уровне ошибок округления слагаемых — дальше уточнять нечего
+		if math.Abs(f) <= 1e-15*(math.Abs(t1)+math.Abs(t2)+math.Abs(t3)+sqrtMu*math.Abs(tof)) {
+			converged = true
+			break
+		}
+		r = sigma0*chi*(1-z*sz) + (1-alpha*r0)*chi2*c + r0
+		d2 := sigma0*(1-z*c) + (1-alpha*r0)*chi*(1-z*sz)
+
+		// метод Лагерра (n = 5) сходится из любого разумного начального приближения
+		const n = 5.0
+		disc := math.Sqrt(math.Abs((n-1)*(n-1)*r*r - n*(n-1)*f*d2))
+		den := r + math.Copysign(disc, r)
+		if den == 0 {
+			return Lagrange{}, ErrNoConvergence
+		}
+		delta := n * f / den
+		chi -= delta
+		if math.Abs(delta) <= keplerTolerance*math.Max(1, math.Abs(chi)) {
+			converged = true
+			break
+		}
+	}
+	if !converged || math.IsNaN(chi) {
+		return Lagrange{}, ErrNoConvergence
+	}
+
+	z := alpha * chi * chi
+	c, sz = stumpffC(z), stumpffS(z)
+	chi2 := chi * chi
+	r = sigma0*chi*(1-z*sz) + (1-alpha*r0)*chi2*c + r0
+
+	return Lagrange{
+		F:    1 - chi2/r0*c,
+		G:    tof - chi2*chi/sqrtMu*sz,
+		FDot: sqrtMu / (r * r0) * chi * (z*sz - 1),
+		GDot: 1 - chi2/r*c,
+	}, nil
+}
+
+// initialChi возвращает начальное приближение универсальной аномалии (Vallado, алгоритм 8).
+func initialChi(s State, r0, sigma0, alpha, dt, mu float64) float64 {
+	sqrtMu := math.Sqrt(mu)
+	switch {
+	case alpha > 1e-9:
+		return sqrtMu * dt * alpha
+	case alpha < -1e-9:
+		a := 1 / alpha
+		num := -2 * mu * alpha * dt
+		den := sigma0*sqrtMu + math.Copysign(math.Sqrt(-mu*a), dt)*(1-r0*alpha)
+		if arg := num / den; arg > 0 {
+			return math.Copysign(math.Sqrt(-a)*math.Log(arg), dt)
+		}
+		return sqrtMu * dt / r0
+	default:
+		// почти параболическая орбита: решение уравнения Баркера
+		h := s.R.Cross(s.V).Norm()
+		p := h * h / mu
+		w := math.Atan(math.Cbrt(math.Tan(0.5 * math.Atan2(1, 3*math.Sqrt(mu/(p*p*p))*dt))))
+		return math.Sqrt(p) * 2 / math.Tan(2*w)
+	}
+}
+
+// Propagate возвращает состояние через dt суток в задаче двух тел с параметром mu.
+func Propagate(s State, dt, mu float64) (State, error) {
+	if dt == 0 {
+		return s, nil
+	}
+	l, err := LagrangeCoefficients(s, dt, mu)
+	if err != nil {
+		return State{}, err
+	}
+	return State{
+		R: s.R.Scale(l.F).Add(s.V.Scale(l.G)),
+		V: s.R.Scale(l.FDot).Add(s.V.Scale(l.GDot)),
+	}, nil
+}
@@ -0,0 +1,52 @@
+// Package orbit реализует задачу двух тел: распространение состояния по
+// уравнению Кеплера в универсальных переменных, переход между элементами
+// орбиты и векторами состояния и повороты между эклиптической и
+// экваториальной системами J2000.
+//
+// Единицы: расстояния в а.е., время в сутках, скорости в а.е./сут, углы в радианах.
+package orbit
+
+import "math"
+
+const (
+	// GaussK — гауссова гравитационная постоянная.
+	GaussK = 0.01720209895
+	// MuSun — гравитационный параметр Солнца, а.е.³/сут².
+	MuSun = GaussK * GaussK
+	// SpeedOfLight — скорость света, а.е./сут.
+	SpeedOfLight = 173.1446326846693
+	// KmPerAU — число километров в астрономической единице.
+	KmPerAU = 149597870.7
+
+	// Deg и ArcSec — множители перевода градусов и угловых секунд в радианы.
+	Deg    = math.Pi / 180
+	ArcSec = Deg / 3600
+)
+
+// State — гелиоцентрический вектор состояния.
+type State struct {
+	R Vec3 // положение, а.е.
+	V Vec3 // скорость, а.е./сут
+}
+
+// NormalizeAngle приводит угол к диапазону [0, 2π).
+func NormalizeAngle(a float64) float64 {
+	a = math.Mod(a, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	// −ε + 2π округляется до 2π
+	if a >= 2*math.Pi {
+		a = 0
+	}
+	return a
+}
+
+// WrapAngle приводит угол к диапазону (−π, π].
+func WrapAngle(a float64) float64 {
+	a = NormalizeAngle(a)
+	if a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	return a
+}
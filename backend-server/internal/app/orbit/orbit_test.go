@@ -0,0 +1,144 @@
+package orbit
+
+import (
+	"math"
+	"testing"
+)
+
+// muEarth — гравитационный параметр Земли, км³/с²: примеры из учебников заданы
+// в километрах и секундах, а Propagate не зависит от единиц.
+const muEarth = 398600.4418
+
+// Vallado, «Fundamentals of Astrodynamics and Applications», пример 2-4: эллиптическая
+// орбита, состояние через 40 минут.
+func TestPropagatePublishedEllipse(t *testing.T) {
+	s := State{R: Vec3{1131.340, -2282.343, 6672.423}, V: Vec3{-5.64305, 4.30333, 2.42879}}
+	want := State{R: Vec3{-4219.7527, 4363.0292, -3958.7666}, V: Vec3{3.689866, -1.916735, -6.112511}}
+
+	got, err := Propagate(s, 40*60, muEarth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := got.R.Sub(want.R).Norm(); d > 1e-3 {
+		t.Errorf("r = %v, want %v (off by %.3g km)", got.R, want.R, d)
+	}
+	if d := got.V.Sub(want.V).Norm(); d > 1e-6 {
+		t.Errorf("v = %v, want %v (off by %.3g km/s)", got.V, want.V, d)
+	}
+}
+
+// Curtis, пример 3.7 (3-е изд.): спутник на гиперболической орбите с r₀ = 10 000 км,
+// v₀ = 10 км/с и θ₀ = 30° через час имеет истинную аномалию 100,04°; e = 1,4682.
+func TestPropagatePublishedHyperbola(t *testing.T) {
+	const r0, v0, theta0 = 10000.0, 10.0, 30 * Deg
+	// из v₀² = μ/r₀ · (1 + 2e cos θ₀ + e²)/(1 + e cos θ₀) и h² = μ r₀ (1 + e cos θ₀)
+	c := math.Cos(theta0)
+	k := v0 * v0 * r0 / muEarth
+	e := (-c*(2-k) + math.Sqrt(c*c*(2-k)*(2-k)-4*(1-k))) / 2
+	h := math.Sqrt(muEarth * r0 * (1 + e*c))
+	s := State{
+		R: Vec3{r0 * c, r0 * math.Sin(theta0), 0},
+		V: Vec3{-muEarth / h * math.Sin(theta0), muEarth / h * (e + c), 0},
+	}
+	if math.Abs(e-1.4682) > 1e-4 {
+		t.Fatalf("e = %.5f, want 1.4682", e)
+	}
+
+	got, err := Propagate(s, 3600, muEarth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := ElementsFromState(got, 0, muEarth)
+	theta := math.Atan2(got.R[1], got.R[0]) - el.ArgPeri
+	if d := math.Abs(theta/Deg - 100.04); d > 0.01 {
+		t.Errorf("θ = %.3f°, want 100.04°", theta/Deg)
+	}
+	if math.Abs(el.E-e) > 1e-10 {
+		t.Errorf("e drifted from %.12f to %.12f", e, el.E)
+	}
+}
+
+// Для параболы время от перигелия даётся уравнением Баркера
+// t = √(p³/μ)/2 · (D + D³/3), D = tg(ν/2), и его можно обратить в замкнутом виде.
+func TestPropagateParabolaBarker(t *testing.T) {
+	const q = 1.5 // а.е.
+	p := 2 * q
+	for _, dt := range []float64{-400, -30, 5, 90, 2000} {
+		m := dt / math.Sqrt(p*p*p/MuSun) * 2 // D + D³/3 = m
+		w := math.Cbrt(1.5*m + math.Sqrt(1+2.25*m*m))
+		d := w - 1/w
+		nu := 2 * math.Atan(d)
+		r := p / (1 + math.Cos(nu))
+		want := Vec3{r * math.Cos(nu), r * math.Sin(nu), 0}
+		speed := math.Sqrt(2 * MuSun / r)
+
+		got, err := Propagate(State{R: Vec3{q, 0, 0}, V: Vec3{0, math.Sqrt(2 * MuSun / q), 0}}, dt, MuSun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := got.R.Sub(want).Norm(); diff > 1e-10*r {
+			t.Errorf("dt = %g: r = %v, want %v", dt, got.R, want)
+		}
+		if diff := math.Abs(got.V.Norm() - speed); diff > 1e-10*speed {
+			t.Errorf("dt = %g: |v| = %.12g, want %.12g", dt, got.V.Norm(), speed)
+		}
+	}
+}
+
+func TestElementsStateRoundTrip(t *testing.T) {
+	const epoch = 2460000.5
+	tests := []struct {
+		name string
+		el   Elements
+	}{
+		{"2P/Encke", ElementsFromDegrees(0.33588, 0.84833, 11.7807, 334.5682, 186.5455, epoch-40)},
+		{"1P/Halley, retrograde", ElementsFromDegrees(0.58598, 0.96714, 162.2627, 58.4201, 111.3325, epoch+3000)},
+		{"near-circular", ElementsFromDegrees(2.5, 0.001, 7, 80, 200, epoch+100)},
+		{"parabola", ElementsFromDegrees(1.2, 1, 95, 10, 300, epoch-120)},
+		{"near-parabolic hyperbola", ElementsFromDegrees(0.8, 1.0002, 45, 200, 20, epoch+15)},
+		{"C/2019 Q4, hyperbola", ElementsFromDegrees(2.00652, 3.35637, 44.0526, 308.1499, 209.1242, epoch-700)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := StateFromElements(tc.el, epoch, MuSun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ElementsFromState(s, epoch, MuSun)
+
+			if d := math.Abs(got.Q-tc.el.Q) / tc.el.Q; d > 1e-10 {
+				t.Errorf("q = %.12f, want %.12f", got.Q, tc.el.Q)
+			}
+			if d := math.Abs(got.E - tc.el.E); d > 1e-10 {
+				t.Errorf("e = %.12f, want %.12f", got.E, tc.el.E)
+			}
+			for _, a := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"i", got.I, tc.el.I},
+				{"node", got.Node, tc.el.Node},
+				{"argPeri", got.ArgPeri, tc.el.ArgPeri},
+			} {
+				if d := math.Abs(WrapAngle(a.got - a.want)); d > 1e-9 {
+					t.Errorf("%s = %.10f°, want %.10f°", a.name, a.got/Deg, a.want/Deg)
+				}
+			}
+			if d := math.Abs(got.Tp - tc.el.Tp); d > 1e-7 {
+				t.Errorf("Tp = %.9f, want %.9f", got.Tp, tc.el.Tp)
+			}
+
+			// и обратно: элементы дают то же состояние
+			back, err := StateFromElements(got, epoch, MuSun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := back.R.Sub(s.R).Norm(); d > 1e-10*s.R.Norm() {
+				t.Errorf("state r differs by %.3g AU", d)
+			}
+			if d := back.V.Sub(s.V).Norm(); d > 1e-10*s.V.Norm() {
+				t.Errorf("state v differs by %.3g AU/day", d)
+			}
+		})
+	}
+}
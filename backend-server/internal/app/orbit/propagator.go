@@ -0,0 +1,19 @@
+package orbit
+
+// Propagator вычисляет гелиоцентрическое состояние тела в экваториальной системе J2000
+// на момент jd (TT).
+type Propagator interface {
+	At(jd float64) (State, error)
+}
+
+// Kepler — распространение по задаче двух тел от состояния State на момент Epoch (TT).
+type Kepler struct {
+	State State
+	Epoch float64
+	Mu    float64
+}
+
+// At реализует Propagator.
+func (k Kepler) At(jd float64) (State, error) {
+	return Propagate(k.State, jd-k.Epoch, k.Mu)
+}
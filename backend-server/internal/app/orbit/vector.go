@@ -0,0 +1,87 @@
+package orbit
+
+import "math"
+
+// Vec3 — трёхмерный вектор (координаты в а.е. или а.е./сут).
+type Vec3 [3]float64
+
+// Add возвращает сумму векторов.
+func (a Vec3) Add(b Vec3) Vec3 { return Vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]} }
+
+// Sub возвращает разность векторов.
+func (a Vec3) Sub(b Vec3) Vec3 { return Vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+
+// Scale возвращает вектор, умноженный на число.
+func (a Vec3) Scale(k float64) Vec3 { return Vec3{a[0] * k, a[1] * k, a[2] * k} }
+
+// Dot возвращает скалярное произведение.
+func (a Vec3) Dot(b Vec3) float64 { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+
+// Cross возвращает векторное произведение.
+func (a Vec3) Cross(b Vec3) Vec3 {
+	return Vec3{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// Norm возвращает длину вектора.
+func (a Vec3) Norm() float64 { return math.Sqrt(a.Dot(a)) }
+
+// Unit возвращает единичный вектор того же направления.
+func (a Vec3) Unit() Vec3 { return a.Scale(1 / a.Norm()) }
+
+// Mat3 — матрица поворота 3×3, хранится по строкам.
+type Mat3 [3][3]float64
+
+// Apply возвращает произведение матрицы на вектор.
+func (m Mat3) Apply(v Vec3) Vec3 {
+	return Vec3{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// Mul возвращает произведение матриц m·n.
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var out Mat3
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// Transpose возвращает транспонированную (для поворота — обратную) матрицу.
+func (m Mat3) Transpose() Mat3 {
+	var out Mat3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = m[j][i]
+		}
+	}
+	return out
+}
+
+// RotX возвращает матрицу поворота системы координат вокруг оси X на угол a (рад).
+func RotX(a float64) Mat3 {
+	s, c := math.Sincos(a)
+	return Mat3{{1, 0, 0}, {0, c, s}, {0, -s, c}}
+}
+
+// RotY возвращает матрицу поворота системы координат вокруг оси Y на угол a (рад).
+func RotY(a float64) Mat3 {
+	s, c := math.Sincos(a)
+	return Mat3{{c, 0, -s}, {0, 1, 0}, {s, 0, c}}
+}
+
+// RotZ возвращает матрицу поворота системы координат вокруг оси Z на угол a (рад).
+func RotZ(a float64) Mat3 {
+	s, c := math.Sincos(a)
+	return Mat3{{c, s, 0}, {-s, c, 0}, {0, 0, 1}}
+}
@@ -1,73 +1,274 @@
 package orbitclient
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"math"
+	"strings"
 	"time"
+
+	"backend-server/internal/app/config"
+	"backend-server/internal/app/planets"
+)
+
+// Orbit computation backends selectable in config
+const (
+	BackendPython = "python"
+	BackendGo     = "go"
+)
+
+// ErrUnavailable is returned when the orbit backend cannot be reached at all
+var ErrUnavailable = errors.New("orbit backend unavailable")
+
+// ErrFitFailed wraps failures caused by the request itself (bad input, no convergence):
+// resending the same request gives the same error
+var ErrFitFailed = errors.New("orbit fit failed")
+
+// Force values of ObservationReq: keep the observation in the fit or leave it out regardless of rejection
+const (
+	ForceInclude = "include"
+	ForceExclude = "exclude"
+)
+
+// Loss functions accepted in OrbitRequest.Loss
+const (
+	LossLinear = "linear"
+	LossHuber  = "huber"
+	LossCauchy = "cauchy"
 )
 
 // ObservationReq matches the python service expected object
 type ObservationReq struct {
-	RA   float64 `json:"ra"`
-	Dec  float64 `json:"dec"`
-	Time string  `json:"time"`
+	RA    float64 `json:"ra"`
+	Dec   float64 `json:"dec"`
+	Time  string  `json:"time"`
+	Force string  `json:"force,omitempty"`
+
+	// Observer is the topocentric observing site; nil means a geocentric observation
+	Observer *Observer `json:"observer,omitempty"`
+
+	// SigmaRA and SigmaDec are the astrometric uncertainties of RA·cos(Dec) and Dec in arcsec;
+	// the fit weights residuals by them. nil means unknown
+	SigmaRA  *float64 `json:"sigma_ra,omitempty"`
+	SigmaDec *float64 `json:"sigma_dec,omitempty"`
+}
+
+// Observer is an observing site given by its MPC parallax constants
+type Observer struct {
+	Longitude float64 `json:"longitude"`   // east of Greenwich, deg
+	RhoCos    float64 `json:"rho_cos_phi"` // in Earth equatorial radii
+	RhoSin    float64 `json:"rho_sin_phi"` // in Earth equatorial radii
+}
+
+// OrbitRequest is the input of an orbit computation
+type OrbitRequest struct {
+	Observations []ObservationReq `json:"observations"`
+
+	// Outlier handling: Loss is "linear" (default), "huber" or "cauchy"; observations with
+	// residuals above RejectSigma times the robust residual scale are rejected (0 disables)
+	Loss        string  `json:"loss,omitempty"`
+	RejectSigma float64 `json:"reject_sigma,omitempty"`
+
+	// Model is the motion model of the close-approach search: "two_body" (default) or "n_body"
+	// with perturbations by the planets and the Moon. The fit itself is always two-body.
+	// The python service supports two_body only
+	Model string `json:"model,omitempty"`
+
+	// Progress, if set, receives the stages of the computation as they happen
+	Progress func(ProgressEvent) `json:"-"`
+}
+
+// Stages reported in ProgressEvent
+const (
+	StageValidation    = "validation"
+	StageInitialOrbit  = "initial_orbit"
+	StageIteration     = "iteration"
+	StageCloseApproach = "close_approach"
+	StageResult        = "result"
+	StageError         = "error"
+)
+
+// ProgressEvent is one step of an orbit computation
+type ProgressEvent struct {
+	Stage     string         `json:"stage"`
+	Iteration int            `json:"iteration,omitempty"` // least-squares iteration number
+	RMS       float64        `json:"rms,omitempty"`       // current RMS residual, arcsec
+	Fraction  float64        `json:"fraction,omitempty"`  // close-approach scan progress, 0..1
+	Message   string         `json:"message,omitempty"`
+	Result    *OrbitResponse `json:"result,omitempty"`
+}
+
+// report passes the event to the request's progress callback, if any
+func (r *OrbitRequest) report(ev ProgressEvent) {
+	if r.Progress != nil {
+		r.Progress(ev)
+	}
 }
 
 // OrbitResponse represents the updated response including close approach
 type OrbitResponse struct {
-	A                         float64 `json:"a"`
-	Eccentricity              float64 `json:"eccentricity"`
-	Inclination               float64 `json:"inclination"`
-	LongitudeOfAscendingNode  float64 `json:"longitude_of_ascending_node"`
-	ArgumentOfPerihelion      float64 `json:"argument_of_perihelion"`
-	TimeOfPerihelion          string  `json:"time_of_perihelion"`
-	ClosestApproachTime       string  `json:"closest_approach_time"`
-	ClosestApproachDistanceAU float64 `json:"closest_approach_distance_au"`
-}
-
-// CalculateOrbit posts observations to the python orbit service and returns parsed JSON
-func CalculateOrbit(observations []ObservationReq) (*OrbitResponse, error) {
-	url := os.Getenv("ORBIT_SERVICE_URL")
-	if url == "" {
-		url = "http://localhost:8000/calculate-orbit"
-	}
+	PerihelionDistance        float64  `json:"perihelion_distance"` // q, AU
+	A                         *float64 `json:"a,omitempty"`         // q/(1−e), negative for hyperbolic orbits, absent for parabolic ones
+	Eccentricity              float64  `json:"eccentricity"`
+	Inclination               float64  `json:"inclination"`
+	LongitudeOfAscendingNode  float64  `json:"longitude_of_ascending_node"`
+	ArgumentOfPerihelion      float64  `json:"argument_of_perihelion"`
+	TimeOfPerihelion          string   `json:"time_of_perihelion"`
+	ClosestApproachTime       string   `json:"closest_approach_time"`
+	ClosestApproachDistanceAU float64  `json:"closest_approach_distance_au"`
 
-	payload := map[string]interface{}{"observations": observations}
-	b, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("marshal payload: %w", err)
+	// Fit quality: O−C residuals in the order of the request's observations,
+	// RMS of the residuals of accepted observations (arcsec), number of iterations and
+	// whether the fit converged
+	Residuals  []Residual `json:"residuals,omitempty"`
+	RMS        float64    `json:"rms"`
+	Iterations int        `json:"iterations"`
+	Converged  bool       `json:"converged"`
+
+	// NormalizedChi2 is the chi-square of the accepted residuals in units of the observations'
+	// sigma_ra/sigma_dec (1 arcsec when not given) per degree of freedom; close to 1 when the
+	// uncertainties are realistic. Omitted when there are no more residuals than fitted parameters
+	NormalizedChi2 *float64 `json:"normalized_chi2,omitempty"`
+
+	// Covariance is the 6×6 covariance of q, e, i, Ω, ω, T (AU, -, deg, deg, deg, days) and
+	// Sigma the 1-sigma uncertainties; both are omitted when they cannot be estimated
+	Covariance [][]float64    `json:"covariance,omitempty"`
+	Sigma      *ElementSigmas `json:"sigma,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// ElementSigmas holds 1-sigma uncertainties of the orbital elements
+type ElementSigmas struct {
+	PerihelionDistance       float64 `json:"perihelion_distance"`
+	Eccentricity             float64 `json:"eccentricity"`
+	Inclination              float64 `json:"inclination"`
+	LongitudeOfAscendingNode float64 `json:"longitude_of_ascending_node"`
+	ArgumentOfPerihelion     float64 `json:"argument_of_perihelion"`
+	TimeOfPerihelion         float64 `json:"time_of_perihelion"` // days
+}
+
+// NewElementSigmas returns the square roots of the covariance diagonal, or nil if the
+// covariance is not a finite 6×6 matrix
+func NewElementSigmas(cov [][]float64) *ElementSigmas {
+	if len(cov) != 6 {
+		return nil
 	}
+	var s [6]float64
+	for i, row := range cov {
+		if len(row) != 6 {
+			return nil
+		}
+		for _, v := range row {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil
+			}
+		}
+		if row[i] < 0 {
+			return nil
+		}
+		s[i] = math.Sqrt(row[i])
+	}
+	return &ElementSigmas{
+		PerihelionDistance:       s[0],
+		Eccentricity:             s[1],
+		Inclination:              s[2],
+		LongitudeOfAscendingNode: s[3],
+		ArgumentOfPerihelion:     s[4],
+		TimeOfPerihelion:         s[5],
+	}
+}
 
-	client := &http.Client{Timeout: 120 * time.Second}
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+// SemiMajorAxis returns q/(1−e), or nil for a parabolic orbit whose semi-major axis is infinite
+func SemiMajorAxis(q, e float64) *float64 {
+	if e == 1 {
+		return nil
 	}
-	req.Header.Set("Content-Type", "application/json")
+	a := q / (1 - e)
+	return &a
+}
+
+// Residual is the observed minus computed position of one observation, arcsec
+type Residual struct {
+	RA       float64 `json:"ra"` // in RA·cos(dec)
+	Dec      float64 `json:"dec"`
+	Rejected bool    `json:"rejected"` // left out of the fit as an outlier or by force
+}
+
+// OrbitCalculator computes an orbit from a set of observations
+type OrbitCalculator interface {
+	CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error)
+}
 
-	resp, err := client.Do(req)
+// New builds the calculator selected by cfg.Backend. If cfg.Fallback is set, it is used
+// when the main backend is unavailable; if cfg.Shadow is set, every request is also sent
+// to the shadow backend and the difference is logged for A/B comparison. The native Go
+// backend takes planet positions from src.
+func New(cfg config.OrbitConfig, src planets.Source) (OrbitCalculator, error) {
+	calc, err := newBackend(cfg.Backend, cfg, src)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+	if cfg.Fallback != "" && cfg.Fallback != cfg.Backend {
+		fallback, err := newBackend(cfg.Fallback, cfg, src)
+		if err != nil {
+			return nil, fmt.Errorf("fallback: %w", err)
+		}
+		calc = &FallbackCalculator{Primary: calc, Fallback: fallback}
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("orbit service returned %s: %s", resp.Status, string(body))
+	if cfg.Shadow != "" {
+		shadow, err := newBackend(cfg.Shadow, cfg, src)
+		if err != nil {
+			return nil, fmt.Errorf("shadow: %w", err)
+		}
+		calc = &CompareCalculator{Primary: calc, Shadow: shadow, PrimaryName: cfg.Backend, ShadowName: cfg.Shadow}
 	}
 
-	var out OrbitResponse
-	if err := json.Unmarshal(body, &out); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
+	return calc, nil
+}
+
+func newBackend(name string, cfg config.OrbitConfig, src planets.Source) (OrbitCalculator, error) {
+	switch name {
+	case BackendPython:
+		return NewHTTPCalculator(cfg.URL, cfg.Timeout, cfg.Retries), nil
+	case BackendGo:
+		calc := NewLocalCalculator()
+		calc.Options.Planets = src
+		return calc, nil
+	default:
+		return nil, fmt.Errorf("unknown orbit backend %q", name)
 	}
+}
 
-	return &out, nil
+// requestTimeLayout is used to send stored observation times; strings without a zone are UTC
+const requestTimeLayout = "2006-01-02T15:04:05.000000"
+
+// FormatTime formats t for ObservationReq.Time
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(requestTimeLayout)
+}
+
+// timeLayouts lists formats produced by astropy (Time.iso / Time.isot) and common ISO-8601 variants
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses a time string in one of the formats understood by the python service.
+// Strings without a zone are treated as UTC, the same way astropy does.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %q", s)
 }
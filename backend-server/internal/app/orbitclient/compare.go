@@ -0,0 +1,111 @@
+package orbitclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shadowTimeout bounds the shadow computation of a CompareCalculator
+const shadowTimeout = 5 * time.Minute
+
+// FallbackCalculator uses Fallback when Primary is unavailable (ErrUnavailable)
+type FallbackCalculator struct {
+	Primary  OrbitCalculator
+	Fallback OrbitCalculator
+}
+
+// CalculateOrbit tries the primary backend first
+func (c *FallbackCalculator) CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error) {
+	res, err := c.Primary.CalculateOrbit(ctx, req)
+	if errors.Is(err, ErrUnavailable) {
+		logrus.WithError(err).Warn("orbit backend unavailable, using fallback")
+		return c.Fallback.CalculateOrbit(ctx, req)
+	}
+	return res, err
+}
+
+// CompareCalculator answers with the Primary backend and runs the same request on the
+// Shadow backend in the background, logging the differences between the two solutions
+type CompareCalculator struct {
+	Primary     OrbitCalculator
+	Shadow      OrbitCalculator
+	PrimaryName string
+	ShadowName  string
+}
+
+// CalculateOrbit returns the primary result; the shadow result is only logged
+func (c *CompareCalculator) CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error) {
+	start := time.Now()
+	res, err := c.Primary.CalculateOrbit(ctx, req)
+	primaryTook := time.Since(start)
+
+	go func() {
+		shadowCtx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+		defer cancel()
+
+		// progress of the shadow computation is not shown to the client
+		shadowReq := *req
+		shadowReq.Progress = nil
+
+		start := time.Now()
+		shadowRes, shadowErr := c.Shadow.CalculateOrbit(shadowCtx, &shadowReq)
+
+		entry := logrus.WithFields(logrus.Fields{
+			"primary":       c.PrimaryName,
+			"shadow":        c.ShadowName,
+			"observations":  len(req.Observations),
+			"primary_took":  primaryTook.String(),
+			"shadow_took":   time.Since(start).String(),
+			"primary_error": errString(err),
+			"shadow_error":  errString(shadowErr),
+		})
+		if err == nil && shadowErr == nil {
+			entry = entry.WithFields(logrus.Fields{
+				"d_q":           shadowRes.PerihelionDistance - res.PerihelionDistance,
+				"d_e":           shadowRes.Eccentricity - res.Eccentricity,
+				"d_i":           angleDiff(shadowRes.Inclination, res.Inclination),
+				"d_node":        angleDiff(shadowRes.LongitudeOfAscendingNode, res.LongitudeOfAscendingNode),
+				"d_argperi":     angleDiff(shadowRes.ArgumentOfPerihelion, res.ArgumentOfPerihelion),
+				"d_approach_au": shadowRes.ClosestApproachDistanceAU - res.ClosestApproachDistanceAU,
+			})
+			if d, ok := timeDiffDays(shadowRes.TimeOfPerihelion, res.TimeOfPerihelion); ok {
+				entry = entry.WithField("d_tp_days", d)
+			}
+		}
+		entry.Info("orbit backend comparison")
+	}()
+
+	return res, err
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// angleDiff returns the difference of two angles in degrees wrapped to (-180, 180]
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(a-b, 360)
+	if d > 180 {
+		d -= 360
+	} else if d <= -180 {
+		d += 360
+	}
+	return d
+}
+
+// timeDiffDays returns a - b in days; ok is false if either time cannot be parsed
+func timeDiffDays(a, b string) (float64, bool) {
+	ta, errA := ParseTime(a)
+	tb, errB := ParseTime(b)
+	if errA != nil || errB != nil {
+		return 0, false
+	}
+	return ta.Sub(tb).Hours() / 24, true
+}
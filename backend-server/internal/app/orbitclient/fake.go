@@ -0,0 +1,44 @@
+package orbitclient
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeCalculator — OrbitCalculator в памяти для тестов: запоминает все запросы
+// и отвечает заданным ответом или ошибкой
+type FakeCalculator struct {
+	Response *OrbitResponse
+	Err      error
+
+	mu       sync.Mutex
+	requests []*OrbitRequest
+}
+
+// NewFakeCalculator возвращает FakeCalculator, отвечающий resp
+func NewFakeCalculator(resp *OrbitResponse) *FakeCalculator {
+	return &FakeCalculator{Response: resp}
+}
+
+// CalculateOrbit запоминает запрос и возвращает заданный ответ (копию) или ошибку Err
+func (f *FakeCalculator) CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	out := *f.Response
+	return &out, nil
+}
+
+// Requests возвращает полученные к этому моменту запросы
+func (f *FakeCalculator) Requests() []*OrbitRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*OrbitRequest(nil), f.requests...)
+}
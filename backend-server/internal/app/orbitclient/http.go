@@ -0,0 +1,166 @@
+package orbitclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// streamSuffix is appended to the service URL for the streaming endpoint, which answers
+// with newline-delimited JSON progress events ending in a result or error event
+const streamSuffix = "/stream"
+
+// HTTPCalculator calls the python orbit service over HTTP
+type HTTPCalculator struct {
+	URL     string
+	Retries int
+	client  *http.Client
+}
+
+// NewHTTPCalculator returns a calculator posting to url with the given per-attempt timeout.
+// Transport errors and 5xx responses are retried up to retries times.
+func NewHTTPCalculator(url string, timeout time.Duration, retries int) *HTTPCalculator {
+	return &HTTPCalculator{
+		URL:     url,
+		Retries: retries,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// retryableError marks failures worth retrying (network errors and 5xx responses)
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// CalculateOrbit posts observations to the python orbit service and returns parsed JSON
+func (c *HTTPCalculator) CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			logrus.WithError(lastErr).WithField("attempt", attempt).Warn("retrying orbit service request")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		out, err := c.do(ctx, b, req)
+		if err == nil {
+			return out, nil
+		}
+		if _, ok := err.(*retryableError); !ok {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+// do performs a single request attempt. Requests with a progress callback use the streaming endpoint.
+func (c *HTTPCalculator) do(ctx context.Context, payload []byte, orbitReq *OrbitRequest) (*OrbitResponse, error) {
+	url := c.URL
+	if orbitReq.Progress != nil {
+		url += streamSuffix
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("do request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if orbitReq.Progress != nil && resp.StatusCode == http.StatusOK {
+		return readStream(resp.Body, orbitReq)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("read body: %w", err)}
+	}
+
+	if resp.StatusCode >= 500 {
+		return nil, &retryableError{err: fmt.Errorf("orbit service returned %s: %s", resp.Status, string(body))}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: orbit service returned %s: %s", ErrFitFailed, resp.Status, string(body))
+	}
+
+	var out OrbitResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	// the python service reports fit failures as {"error": "..."} with status 200
+	if out.Error != "" {
+		return nil, fmt.Errorf("%w: orbit service error: %s", ErrFitFailed, out.Error)
+	}
+
+	return &out, nil
+}
+
+// readStream relays progress events from the streaming endpoint and returns the final result.
+// A stream that breaks before any event was relayed is retried; once progress has reached the
+// caller, restarting would replay stages and iterations it has already seen, so the error is final.
+func readStream(body io.Reader, req *OrbitRequest) (*OrbitResponse, error) {
+	relayed := false
+	brokenStream := func(err error) error {
+		if relayed {
+			return fmt.Errorf("orbit stream interrupted after progress was relayed: %w", err)
+		}
+		return &retryableError{err: err}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var ev ProgressEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("unmarshal progress event: %w", err)
+		}
+		req.report(ev)
+		relayed = true
+
+		switch ev.Stage {
+		case StageResult:
+			if ev.Result == nil {
+				return nil, fmt.Errorf("orbit service sent an empty result")
+			}
+			return ev.Result, nil
+		case StageError:
+			return nil, fmt.Errorf("%w: orbit service error: %s", ErrFitFailed, ev.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, brokenStream(fmt.Errorf("read stream: %w", err))
+	}
+	return nil, brokenStream(fmt.Errorf("orbit service closed the stream without a result"))
+}
@@ -0,0 +1,82 @@
+package orbitclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHTTPCalculatorStreamRetry checks that a broken stream is retried only while no progress
+// event has reached the caller: a restart after that would replay stages already relayed
+func TestHTTPCalculatorStreamRetry(t *testing.T) {
+	const result = `{"stage":"result","result":{"perihelion_distance":1.5,"eccentricity":0.5}}` + "\n"
+
+	tests := []struct {
+		name       string
+		firstBody  string // body of the first attempt, which ends without a result
+		wantCalls  int32
+		wantEvents []string
+		wantErr    bool
+	}{
+		{
+			name:       "broken before any event",
+			firstBody:  "",
+			wantCalls:  2,
+			wantEvents: []string{StageValidation, StageResult},
+		},
+		{
+			name:       "broken after progress",
+			firstBody:  `{"stage":"validation"}` + "\n" + `{"stage":"iteration","iteration":1}` + "\n",
+			wantCalls:  1,
+			wantEvents: []string{StageValidation, StageIteration},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != streamSuffix {
+					t.Errorf("request to %q, want the streaming endpoint", r.URL.Path)
+				}
+				if calls.Add(1) == 1 {
+					fmt.Fprint(w, tt.firstBody)
+					return
+				}
+				fmt.Fprint(w, `{"stage":"validation"}`+"\n"+result)
+			}))
+			defer srv.Close()
+
+			var events []string
+			req := &OrbitRequest{Progress: func(ev ProgressEvent) { events = append(events, ev.Stage) }}
+			c := NewHTTPCalculator(srv.URL, 5*time.Second, 1)
+
+			out, err := c.CalculateOrbit(context.Background(), req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if errors.Is(err, ErrUnavailable) {
+					t.Errorf("error %v would send the request to a fallback backend", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if out.PerihelionDistance != 1.5 {
+				t.Errorf("q = %v, want 1.5", out.PerihelionDistance)
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("%d requests, want %d", got, tt.wantCalls)
+			}
+			if fmt.Sprint(events) != fmt.Sprint(tt.wantEvents) {
+				t.Errorf("events %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package orbitclient
+
+import (
+	"context"
+	"fmt"
+
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/observer"
+	"backend-server/internal/app/orbitdet"
+)
+
+// responseTimeLayout matches astropy's Time.iso output used by the python service
+const responseTimeLayout = "2006-01-02 15:04:05.000"
+
+// LocalCalculator determines orbits in-process with the native Go solver (package orbitdet)
+type LocalCalculator struct {
+	Options orbitdet.Options
+}
+
+// NewLocalCalculator returns a LocalCalculator with default solver options
+func NewLocalCalculator() *LocalCalculator {
+	return &LocalCalculator{}
+}
+
+// CalculateOrbit fits the orbit and returns it in the same shape as the python service response
+func (c *LocalCalculator) CalculateOrbit(ctx context.Context, req *OrbitRequest) (*OrbitResponse, error) {
+	obs := make([]orbitdet.Observation, len(req.Observations))
+	for i, o := range req.Observations {
+		t, err := ParseTime(o.Time)
+		if err != nil {
+			return nil, fmt.Errorf("%w: observation %d: %v", ErrFitFailed, i, err)
+		}
+		obs[i] = orbitdet.Observation{Time: t, RA: o.RA, Dec: o.Dec, Force: orbitdet.Force(o.Force)}
+		if s := o.Observer; s != nil {
+			obs[i].Site = &observer.Site{Longitude: s.Longitude, RhoCos: s.RhoCos, RhoSin: s.RhoSin}
+		}
+		if o.SigmaRA != nil {
+			obs[i].SigmaRA = *o.SigmaRA
+		}
+		if o.SigmaDec != nil {
+			obs[i].SigmaDec = *o.SigmaDec
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts := c.Options
+	if req.Loss != "" && req.Loss != LossLinear {
+		opts.Loss = orbitdet.Loss(req.Loss)
+	}
+	if req.RejectSigma > 0 {
+		opts.RejectSigma = req.RejectSigma
+	}
+	if req.Model != "" {
+		opts.Model = nbody.Model(req.Model)
+	}
+	if req.Progress != nil {
+		opts.Progress = func(p orbitdet.Progress) {
+			req.report(ProgressEvent{Stage: string(p.Stage), Iteration: p.Iteration, RMS: p.RMS, Fraction: p.Fraction})
+		}
+	}
+
+	sol, err := orbitdet.Determine(obs, opts)
+	if err != nil {
+		req.report(ProgressEvent{Stage: StageError, Message: err.Error()})
+		return nil, fmt.Errorf("%w: %w", ErrFitFailed, err)
+	}
+
+	res := &OrbitResponse{
+		PerihelionDistance:        sol.PerihelionDistance,
+		A:                         SemiMajorAxis(sol.PerihelionDistance, sol.Eccentricity),
+		Eccentricity:              sol.Eccentricity,
+		Inclination:               sol.Inclination,
+		LongitudeOfAscendingNode:  sol.LongitudeOfAscendingNode,
+		ArgumentOfPerihelion:      sol.ArgumentOfPerihelion,
+		TimeOfPerihelion:          sol.TimeOfPerihelion.Format(responseTimeLayout),
+		ClosestApproachTime:       sol.ClosestApproachTime.Format(responseTimeLayout),
+		ClosestApproachDistanceAU: sol.ClosestApproachDistanceAU,
+		Residuals:                 make([]Residual, len(sol.Residuals)),
+		RMS:                       sol.RMS,
+		Iterations:                sol.Iterations,
+		Converged:                 sol.Converged,
+	}
+	for i, r := range sol.Residuals {
+		res.Residuals[i] = Residual{RA: r.RA, Dec: r.Dec, Rejected: r.Rejected}
+	}
+	if sol.DegreesOfFreedom > 0 {
+		res.NormalizedChi2 = &sol.Chi2
+	}
+	if sol.Covariance != nil {
+		cov := make([][]float64, 6)
+		for i := range cov {
+			cov[i] = sol.Covariance[i][:]
+		}
+		// a covariance with non-finite terms (a degenerate fit) cannot be sent as JSON
+		if res.Sigma = NewElementSigmas(cov); res.Sigma != nil {
+			res.Covariance = cov
+		}
+	}
+	req.report(ProgressEvent{Stage: StageResult, Result: res})
+	return res, nil
+}
@@ -0,0 +1,147 @@
+package orbitdet
+
+import (
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Covariance — ковариационная матрица шести параметров орбиты.
+type Covariance [6][6]float64
+
+// Sigmas возвращает стандартные отклонения (1σ) — корни диагональных элементов.
+func (c *Covariance) Sigmas() [6]float64 {
+	var s [6]float64
+	for i := range 6 {
+		s[i] = math.Sqrt(c[i][i])
+	}
+	return s
+}
+
+// elementStep — относительный шаг численного дифференцирования элементов по вектору состояния
+const elementStep = 1e-7
+
+// stateCovariance оценивает ковариацию вектора состояния (а.е., а.е./сут) как s²·(JᵀWJ)⁻¹,
+// где s² — сумма квадратов взвешенных невязок на одну степень свободы. Если наблюдений
+// не больше, чем параметров, оценить разброс нельзя и возвращается nil.
+func stateCovariance(fit *fitResult) (*Covariance, error) {
+	dof := 2*included(fit.obs) - 6
+	if dof <= 0 {
+		return nil, nil
+	}
+
+	params := toParams(fit.state)
+	jac, err := jacobian(fit.obs, params, fit.epoch)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]float64, 2*len(fit.obs))
+	if err := residuals(fit.state, fit.epoch, fit.obs, res); err != nil {
+		return nil, err
+	}
+	s2 := sumSquares(res) / float64(dof)
+
+	normal := make([][]float64, 6)
+	for i := range 6 {
+		normal[i] = make([]float64, 6)
+		for k := range jac {
+			for j := range 6 {
+				normal[i][j] += jac[k][i] * jac[k][j]
+			}
+		}
+	}
+	inv, err := invert(normal)
+	if err != nil {
+		return nil, err
+	}
+
+	// параметры подгонки — скорость в единицах k·а.е./сут, см. toParams
+	scale := [6]float64{1, 1, 1, orbit.GaussK, orbit.GaussK, orbit.GaussK}
+	var cov Covariance
+	for i := range 6 {
+		for j := range 6 {
+			cov[i][j] = s2 * inv[i][j] * scale[i] * scale[j]
+		}
+	}
+	return &cov, nil
+}
+
+// elementCovariance переносит ковариацию экваториального вектора состояния на элементы
+// q, e, i, Ω, ω, T (а.е., —, градусы, градусы, градусы, сутки) относительно эклиптики
+// линеаризацией: C_el = G·C·Gᵀ, где G — матрица производных элементов по состоянию.
+func elementCovariance(state orbit.State, epoch float64, cov *Covariance) *Covariance {
+	if cov == nil {
+		return nil
+	}
+
+	x := [6]float64{state.R[0], state.R[1], state.R[2], state.V[0], state.V[1], state.V[2]}
+	posScale := state.R.Norm()
+	velScale := state.V.Norm()
+
+	var g [6][6]float64 // g[i][j] = ∂элемент_i/∂состояние_j
+	for j := range 6 {
+		h := elementStep * posScale
+		if j >= 3 {
+			h = elementStep * velScale
+		}
+		plus, minus := x, x
+		plus[j] += h
+		minus[j] -= h
+		ep := elementVector(plus, epoch)
+		em := elementVector(minus, epoch)
+		for i := range 6 {
+			d := ep[i] - em[i]
+			if i >= 2 && i <= 4 {
+				d = orbit.WrapAngle(d*orbit.Deg) / orbit.Deg
+			}
+			g[i][j] = d / (2 * h)
+		}
+	}
+
+	var out Covariance
+	for i := range 6 {
+		for j := range 6 {
+			sum := 0.0
+			for k := range 6 {
+				for l := range 6 {
+					sum += g[i][k] * cov[k][l] * g[j][l]
+				}
+			}
+			out[i][j] = sum
+		}
+	}
+	return &out
+}
+
+// elementVector возвращает q, e, i, Ω, ω (градусы) и T (JD) для экваториального
+// вектора состояния x = (r, v).
+func elementVector(x [6]float64, epoch float64) [6]float64 {
+	s := orbit.State{
+		R: orbit.Vec3{x[0], x[1], x[2]},
+		V: orbit.Vec3{x[3], x[4], x[5]},
+	}
+	el := orbit.ElementsFromState(s.ToEcliptic(), epoch, orbit.MuSun)
+	return [6]float64{el.Q, el.E, el.I / orbit.Deg, el.Node / orbit.Deg, el.ArgPeri / orbit.Deg, el.Tp}
+}
+
+// invert обращает квадратную матрицу, решая систему для каждого столбца единичной матрицы.
+func invert(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	inv := make([][]float64, n)
+	for i := range inv {
+		inv[i] = make([]float64, n)
+	}
+	e := make([]float64, n)
+	for col := range n {
+		clear(e)
+		e[col] = 1
+		x, err := solve(a, e)
+		if err != nil {
+			return nil, err
+		}
+		for row := range n {
+			inv[row][col] = x[row]
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,126 @@
+package orbitdet
+
+import (
+	"math"
+	"testing"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbit"
+)
+
+func TestInvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        [][]float64
+		singular bool
+	}{
+		{"identity", [][]float64{{1, 0}, {0, 1}}, false},
+		{"needs pivoting", [][]float64{{0, 2, 1}, {1, 1, 0}, {3, 0, 4}}, false},
+		{"symmetric", [][]float64{{4, 1, 2}, {1, 3, 0}, {2, 0, 5}}, false},
+		{"singular", [][]float64{{1, 2}, {2, 4}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := invert(tt.a)
+			if tt.singular {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := len(tt.a)
+			for i := range n {
+				for j := range n {
+					sum := 0.0
+					for k := range n {
+						sum += tt.a[i][k] * inv[k][j]
+					}
+					want := 0.0
+					if i == j {
+						want = 1
+					}
+					if math.Abs(sum-want) > 1e-12 {
+						t.Errorf("(A·A⁻¹)[%d][%d] = %g, want %g", i, j, sum, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Без лишних степеней свободы разброс не оценивается.
+func TestCovarianceNeedsDegreesOfFreedom(t *testing.T) {
+	fit := &fitResult{obs: []prepared{{weight: 1}, {weight: 1}, {weight: 1}, {weight: 0}}}
+	cov, err := stateCovariance(fit)
+	if err != nil || cov != nil {
+		t.Errorf("stateCovariance = %v, %v; want nil, nil", cov, err)
+	}
+	if elementCovariance(orbit.State{}, 0, nil) != nil {
+		t.Error("elementCovariance of nil is not nil")
+	}
+}
+
+// Ковариация согласуется с фактическими ошибками элементов и растёт пропорционально
+// шуму наблюдений: при том же зерне генератора невязки просто масштабируются.
+func TestCovarianceMatchesNoise(t *testing.T) {
+	for _, c := range cometCases {
+		t.Run(c.name, func(t *testing.T) {
+			sol, err := Determine(c.observations(t), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sol.Covariance == nil || sol.StateCovariance == nil {
+				t.Fatal("no covariance")
+			}
+
+			for name, cov := range map[string]*Covariance{"state": sol.StateCovariance, "elements": sol.Covariance} {
+				for i := range 6 {
+					if cov[i][i] <= 0 {
+						t.Errorf("%s variance %d = %g, want positive", name, i, cov[i][i])
+					}
+					for j := range 6 {
+						if d := cov[i][j] - cov[j][i]; math.Abs(d) > 1e-9*math.Sqrt(cov[i][i]*cov[j][j]) {
+							t.Errorf("%s covariance is not symmetric at %d,%d", name, i, j)
+						}
+						if r := cov[i][j] / math.Sqrt(cov[i][i]*cov[j][j]); math.Abs(r) > 1+1e-9 {
+							t.Errorf("%s correlation %d,%d = %g", name, i, j, r)
+						}
+					}
+				}
+			}
+
+			// истинные ошибки элементов — в пределах 5σ
+			sigma := sol.Covariance.Sigmas()
+			errs := [6]float64{
+				sol.PerihelionDistance - c.q,
+				sol.Eccentricity - c.e,
+				orbit.WrapAngle((sol.Inclination-c.i)*orbit.Deg) / orbit.Deg,
+				orbit.WrapAngle((sol.LongitudeOfAscendingNode-c.node)*orbit.Deg) / orbit.Deg,
+				orbit.WrapAngle((sol.ArgumentOfPerihelion-c.argPeri)*orbit.Deg) / orbit.Deg,
+				astrotime.TT(sol.TimeOfPerihelion) - astrotime.TT(c.tp),
+			}
+			names := [6]string{"q", "e", "i", "node", "argPeri", "T"}
+			for i := range 6 {
+				if math.Abs(errs[i]) > 5*sigma[i] {
+					t.Errorf("%s is off by %.3g, more than 5σ = %.3g", names[i], errs[i], 5*sigma[i])
+				}
+			}
+
+			noisy := c
+			noisy.noise *= 2
+			solNoisy, err := Determine(noisy.observations(t), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigmaNoisy := solNoisy.Covariance.Sigmas()
+			for i := range 6 {
+				if r := sigmaNoisy[i] / sigma[i]; math.Abs(r-2) > 0.1 {
+					t.Errorf("%s: σ grows %.3f times with doubled noise, want 2", names[i], r)
+				}
+			}
+		})
+	}
+}
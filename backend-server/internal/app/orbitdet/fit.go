@@ -0,0 +1,181 @@
+package orbitdet
+
+import (
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+const (
+	lambdaInitial = 1e-3
+	lambdaMax     = 1e12
+	// diffStep — относительный шаг центральных разностей при вычислении якобиана
+	diffStep = 1e-6
+)
+
+// fitResult — орбита после дифференциальной коррекции.
+type fitResult struct {
+	obs        []prepared // наблюдения с весами, с которыми получена орбита
+	state      orbit.State
+	epoch      float64
+	rms        float64 // среднеквадратичная взвешенная невязка
+	iterations int
+	converged  bool
+}
+
+// Параметры коррекции — положение (а.е.) и скорость в единицах k·а.е./сут,
+// чтобы все шесть компонент были одного порядка.
+func toParams(s orbit.State) [6]float64 {
+	return [6]float64{
+		s.R[0], s.R[1], s.R[2],
+		s.V[0] / orbit.GaussK, s.V[1] / orbit.GaussK, s.V[2] / orbit.GaussK,
+	}
+}
+
+func fromParams(p [6]float64) orbit.State {
+	return orbit.State{
+		R: orbit.Vec3{p[0], p[1], p[2]},
+		V: orbit.Vec3{p[3] * orbit.GaussK, p[4] * orbit.GaussK, p[5] * orbit.GaussK},
+	}
+}
+
+// differentialCorrection уточняет начальную орбиту методом Левенберга — Марквардта,
+// минимизируя сумму квадратов невязок по всем наблюдениям. index — номер начальной
+// орбиты, под которым сообщается прогресс.
+func differentialCorrection(obs []prepared, c candidate, index int, opts Options) (*fitResult, error) {
+	m := 2 * len(obs)
+	params := toParams(c.state)
+
+	res := make([]float64, m)
+	if err := residuals(c.state, c.epoch, obs, res); err != nil {
+		return nil, err
+	}
+	cost := sumSquares(res)
+
+	lambda := lambdaInitial
+	trial := make([]float64, m)
+	result := &fitResult{obs: obs, epoch: c.epoch}
+
+	for result.iterations < opts.MaxIterations {
+		result.iterations++
+
+		jac, err := jacobian(obs, params, c.epoch)
+		if err != nil {
+			return nil, err
+		}
+
+		// нормальные уравнения JᵀJ·δ = −Jᵀr
+		var normal [6][6]float64
+		var grad [6]float64
+		for k := range m {
+			for i := range 6 {
+				grad[i] += jac[k][i] * res[k]
+				for j := range 6 {
+					normal[i][j] += jac[k][i] * jac[k][j]
+				}
+			}
+		}
+
+		accepted := false
+		var step [6]float64
+		for lambda <= lambdaMax {
+			a := make([][]float64, 6)
+			b := make([]float64, 6)
+			for i := range 6 {
+				a[i] = make([]float64, 6)
+				copy(a[i], normal[i][:])
+				a[i][i] += lambda * math.Max(normal[i][i], 1e-30)
+				b[i] = -grad[i]
+			}
+			delta, err := solve(a, b)
+			if err != nil {
+				lambda *= 10
+				continue
+			}
+
+			var next [6]float64
+			for i := range 6 {
+				next[i] = params[i] + delta[i]
+				step[i] = delta[i]
+			}
+			if err := residuals(fromParams(next), c.epoch, obs, trial); err == nil {
+				if trialCost := sumSquares(trial); trialCost < cost {
+					params = next
+					copy(res, trial)
+					lambda = math.Max(lambda/10, 1e-12)
+					accepted = true
+
+					decrease := (cost - trialCost) / math.Max(cost, 1e-300)
+					cost = trialCost
+					if decrease < opts.Tolerance || stepIsNegligible(step, params) {
+						result.converged = true
+					}
+					break
+				}
+			}
+			lambda *= 10
+		}
+
+		opts.report(Progress{
+			Stage:     StageIteration,
+			Candidate: index,
+			Iteration: result.iterations,
+			RMS:       math.Sqrt(cost / float64(m)),
+		})
+
+		// уменьшить невязки уже нельзя — найден минимум
+		if !accepted {
+			result.converged = true
+		}
+		if result.converged {
+			break
+		}
+	}
+
+	result.state = fromParams(params)
+	// исключённые наблюдения дают нулевые невязки и в RMS не учитываются
+	result.rms = math.Sqrt(cost / float64(2*max(included(obs), 1)))
+	return result, nil
+}
+
+// stepIsNegligible проверяет, что шаг пренебрежимо мал по сравнению с параметрами.
+func stepIsNegligible(step, params [6]float64) bool {
+	for i := range 6 {
+		if math.Abs(step[i]) > 1e-12*math.Max(1, math.Abs(params[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// jacobian вычисляет производные невязок по параметрам центральными разностями.
+func jacobian(obs []prepared, params [6]float64, epoch float64) ([][6]float64, error) {
+	m := 2 * len(obs)
+	jac := make([][6]float64, m)
+	plus := make([]float64, m)
+	minus := make([]float64, m)
+
+	posScale := math.Sqrt(params[0]*params[0] + params[1]*params[1] + params[2]*params[2])
+	velScale := math.Sqrt(params[3]*params[3] + params[4]*params[4] + params[5]*params[5])
+
+	for i := range 6 {
+		h := diffStep * posScale
+		if i >= 3 {
+			h = diffStep * velScale
+		}
+
+		p := params
+		p[i] += h
+		if err := residuals(fromParams(p), epoch, obs, plus); err != nil {
+			return nil, err
+		}
+		p[i] = params[i] - h
+		if err := residuals(fromParams(p), epoch, obs, minus); err != nil {
+			return nil, err
+		}
+		for k := range m {
+			jac[k][i] = (plus[k] - minus[k]) / (2 * h)
+		}
+	}
+	return jac, nil
+}
@@ -0,0 +1,226 @@
+package orbitdet
+
+import (
+	"math"
+	"sort"
+
+	"backend-server/internal/app/orbit"
+)
+
+const (
+	gaussMaxIterations = 100
+	gaussTolerance     = 1e-12
+	// maxCandidates — сколько лучших начальных орбит уточняется по всем наблюдениям
+	maxCandidates = 3
+)
+
+// candidate — начальная орбита: вектор состояния на эпоху среднего наблюдения.
+type candidate struct {
+	state orbit.State
+	epoch float64
+}
+
+// initialOrbits строит начальные орбиты методом Гаусса по нескольким тройкам
+// наблюдений и возвращает лучшие из них по невязкам на всех наблюдениях.
+func initialOrbits(obs []prepared) []candidate {
+	type scored struct {
+		candidate
+		cost float64
+	}
+
+	var all []scored
+	res := make([]float64, 2*len(obs))
+	for _, triple := range triples(len(obs)) {
+		o1, o2, o3 := obs[triple[0]], obs[triple[1]], obs[triple[2]]
+		if o2.t-o1.t <= 0 || o3.t-o2.t <= 0 {
+			continue
+		}
+		for _, c := range gauss(o1, o2, o3) {
+			if err := residuals(c.state, c.epoch, obs, res); err != nil {
+				continue
+			}
+			all = append(all, scored{candidate: c, cost: sumSquares(res)})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].cost < all[j].cost })
+
+	var out []candidate
+	for _, s := range all {
+		if len(out) == maxCandidates {
+			break
+		}
+		if !isDuplicate(out, s.candidate) {
+			out = append(out, s.candidate)
+		}
+	}
+	return out
+}
+
+// triples возвращает индексы троек наблюдений для метода Гаусса: в первую очередь
+// с наибольшим разнесением по времени.
+func triples(n int) [][3]int {
+	raw := [][3]int{
+		{0, n / 2, n - 1},
+		{0, n / 3, n - 1},
+		{0, 2 * n / 3, n - 1},
+		{0, n / 3, 2 * n / 3},
+		{n / 3, 2 * n / 3, n - 1},
+		// короткие тройки: на длинной дуге ряды для f и g грубы, и уточнение дальностей
+		// для истинного корня может разойтись
+		{0, n / 8, n / 4},
+		{3 * n / 8, n / 2, 5 * n / 8},
+		{3 * n / 4, 7 * n / 8, n - 1},
+	}
+	seen := make(map[[3]int]bool)
+	var out [][3]int
+	for _, t := range raw {
+		if t[0] < t[1] && t[1] < t[2] && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// isDuplicate проверяет, есть ли среди кандидатов практически совпадающая орбита.
+func isDuplicate(list []candidate, c candidate) bool {
+	for _, other := range list {
+		if math.Abs(other.epoch-c.epoch) < 1e-6 && other.state.R.Sub(c.state.R).Norm() < 1e-6*c.state.R.Norm() {
+			return true
+		}
+	}
+	return false
+}
+
+// gauss реализует метод Гаусса (Curtis, алгоритмы 5.5 и 5.6): для каждого
+// положительного корня уравнения восьмой степени наклонные дальности
+// итерационно уточняются с точными коэффициентами Лагранжа и поправкой за
+// время распространения света.
+func gauss(o1, o2, o3 prepared) []candidate {
+	const mu = orbit.MuSun
+	l := [3]orbit.Vec3{o1.los, o2.los, o3.los}
+	r := [3]orbit.Vec3{o1.observer, o2.observer, o3.observer}
+	t := [3]float64{o1.t, o2.t, o3.t}
+
+	p := [3]orbit.Vec3{l[1].Cross(l[2]), l[0].Cross(l[2]), l[0].Cross(l[1])}
+	d0 := l[0].Dot(p[0])
+	if math.Abs(d0) < 1e-14 {
+		// направления компланарны — метод Гаусса вырожден
+		return nil
+	}
+	var d [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			d[i][j] = r[i].Dot(p[j])
+		}
+	}
+
+	tau1, tau3 := t[0]-t[1], t[2]-t[1]
+	tau := tau3 - tau1
+
+	a := (-d[0][1]*tau3/tau + d[1][1] + d[2][1]*tau1/tau) / d0
+	b := (d[0][1]*(tau3*tau3-tau*tau)*tau3/tau + d[2][1]*(tau*tau-tau1*tau1)*tau1/tau) / (6 * d0)
+	e := r[1].Dot(l[1])
+	r2sq := r[1].Dot(r[1])
+
+	ca := -(a*a + 2*a*e + r2sq)
+	cb := -2 * mu * b * (a + e)
+	cc := -mu * mu * b * b
+
+	var out []candidate
+	for _, r2 := range positiveRoots(func(x float64) float64 {
+		x3 := x * x * x
+		return x3*x3*x*x + ca*x3*x3 + cb*x3 + cc
+	}) {
+		r23 := r2 * r2 * r2
+		rho := [3]float64{
+			((6*(d[2][0]*tau1/tau3+d[1][0]*tau/tau3)*r23+mu*d[2][0]*(tau*tau-tau1*tau1)*tau1/tau3)/
+				(6*r23+mu*(tau*tau-tau3*tau3)) - d[0][0]) / d0,
+			a + mu*b/r23,
+			((6*(d[0][2]*tau3/tau1-d[1][2]*tau/tau1)*r23+mu*d[0][2]*(tau*tau-tau3*tau3)*tau3/tau1)/
+				(6*r23+mu*(tau*tau-tau1*tau1)) - d[2][2]) / d0,
+		}
+		if rho[1] <= 0 {
+			continue
+		}
+
+		f1 := 1 - 0.5*mu*tau1*tau1/r23
+		f3 := 1 - 0.5*mu*tau3*tau3/r23
+		g1 := tau1 - mu*tau1*tau1*tau1/(6*r23)
+		g3 := tau3 - mu*tau3*tau3*tau3/(6*r23)
+
+		pos := func(i int) orbit.Vec3 { return r[i].Add(l[i].Scale(rho[i])) }
+		den := f1*g3 - f3*g1
+		state := orbit.State{R: pos(1), V: pos(0).Scale(-f3).Add(pos(2).Scale(f1)).Scale(1 / den)}
+
+		// итерационное уточнение дальностей
+		for range gaussMaxIterations {
+			// моменты излучения света, пришедшего к наблюдателю
+			tc := [3]float64{t[0] - rho[0]/orbit.SpeedOfLight, t[1] - rho[1]/orbit.SpeedOfLight, t[2] - rho[2]/orbit.SpeedOfLight}
+			lg1, err1 := orbit.LagrangeCoefficients(state, tc[0]-tc[1], mu)
+			lg3, err3 := orbit.LagrangeCoefficients(state, tc[2]-tc[1], mu)
+			if err1 != nil || err3 != nil {
+				break
+			}
+			f1, g1 = (f1+lg1.F)/2, (g1+lg1.G)/2
+			f3, g3 = (f3+lg3.F)/2, (g3+lg3.G)/2
+
+			den = f1*g3 - f3*g1
+			c1, c3 := g3/den, -g1/den
+			next := [3]float64{
+				(-d[0][0] + d[1][0]/c1 - d[2][0]*c3/c1) / d0,
+				(-c1*d[0][1] + d[1][1] - c3*d[2][1]) / d0,
+				(-c1/c3*d[0][2] + d[1][2]/c3 - d[2][2]) / d0,
+			}
+			if next[0] <= 0 || next[1] <= 0 || next[2] <= 0 || math.IsNaN(next[1]) {
+				break
+			}
+
+			change := 0.0
+			for i := range 3 {
+				change = math.Max(change, math.Abs(next[i]-rho[i])/next[i])
+			}
+			rho = next
+			state = orbit.State{R: pos(1), V: pos(0).Scale(-f3).Add(pos(2).Scale(f1)).Scale(1 / den)}
+			if change < gaussTolerance {
+				break
+			}
+		}
+
+		out = append(out, candidate{state: state, epoch: t[1] - rho[1]/orbit.SpeedOfLight})
+	}
+	return out
+}
+
+// positiveRoots находит положительные корни функции f на интервале [1e-3, 1e3]
+// перебором по логарифмической сетке с последующим делением отрезка пополам.
+func positiveRoots(f func(float64) float64) []float64 {
+	const steps = 2000
+	lo, hi := math.Log(1e-3), math.Log(1e3)
+
+	var roots []float64
+	x0 := math.Exp(lo)
+	f0 := f(x0)
+	for k := 1; k <= steps; k++ {
+		x1 := math.Exp(lo + (hi-lo)*float64(k)/steps)
+		f1 := f(x1)
+		if f0 == 0 {
+			roots = append(roots, x0)
+		} else if f0*f1 < 0 {
+			a, b, fa := x0, x1, f0
+			for range 200 {
+				m := 0.5 * (a + b)
+				fm := f(m)
+				if fa*fm <= 0 {
+					b = m
+				} else {
+					a, fa = m, fm
+				}
+			}
+			roots = append(roots, 0.5*(a+b))
+		}
+		x0, f0 = x1, f1
+	}
+	return roots
+}
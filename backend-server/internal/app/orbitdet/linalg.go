@@ -0,0 +1,51 @@
+package orbitdet
+
+import (
+	"errors"
+	"math"
+)
+
+// errSingular возвращается, если система линейных уравнений вырождена.
+var errSingular = errors.New("orbitdet: singular matrix")
+
+// solve решает систему a·x = b методом Гаусса с выбором главного элемента.
+// Матрица a и вектор b не изменяются.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n+1)
+		copy(m[i], a[i])
+		m[i][n] = b[i]
+	}
+
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if m[pivot][col] == 0 {
+			return nil, errSingular
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			k := m[row][col] / m[col][col]
+			for j := col; j <= n; j++ {
+				m[row][j] -= k * m[col][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := m[i][n]
+		for j := i + 1; j < n; j++ {
+			sum -= m[i][j] * x[j]
+		}
+		x[i] = sum / m[i][i]
+	}
+	return x, nil
+}
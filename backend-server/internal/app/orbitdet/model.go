@@ -0,0 +1,53 @@
+package orbitdet
+
+import (
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+// predict возвращает вычисленные прямое восхождение и склонение объекта с орбитой
+// state (на эпоху epoch) для наблюдения o с учётом времени распространения света.
+func predict(state orbit.State, epoch float64, o prepared) (ra, dec float64, err error) {
+	var rho orbit.Vec3
+	lightTime := 0.0
+	for range 3 {
+		s, err := orbit.Propagate(state, o.t-lightTime-epoch, orbit.MuSun)
+		if err != nil {
+			return 0, 0, err
+		}
+		rho = s.R.Sub(o.observer)
+		lightTime = rho.Norm() / orbit.SpeedOfLight
+	}
+	ra, dec = orbit.RADec(rho)
+	return ra, dec, nil
+}
+
+// residuals заполняет out невязками «вычислено − наблюдено» в единицах погрешностей
+// наблюдения (угловые секунды, делённые на σ), умноженными на √weight: out[2k] — по прямому восхождению, умноженному
+// на cos δ, out[2k+1] — по склонению. Исключённые наблюдения (weight = 0) дают нули.
+func residuals(state orbit.State, epoch float64, obs []prepared, out []float64) error {
+	for k, o := range obs {
+		if o.weight == 0 {
+			out[2*k], out[2*k+1] = 0, 0
+			continue
+		}
+		ra, dec, err := predict(state, epoch, o)
+		if err != nil {
+			return err
+		}
+		w := math.Sqrt(o.weight)
+		out[2*k] = w * orbit.WrapAngle(ra-o.ra) * math.Cos(o.dec) / orbit.ArcSec / o.sigmaRA
+		out[2*k+1] = w * (dec - o.dec) / orbit.ArcSec / o.sigmaDec
+	}
+	return nil
+}
+
+// sumSquares возвращает сумму квадратов элементов.
+func sumSquares(v []float64) float64 {
+	s := 0.0
+	for _, x := range v {
+		s += x * x
+	}
+	return s
+}
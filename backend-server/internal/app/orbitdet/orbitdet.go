@@ -0,0 +1,334 @@
+// Package orbitdet определяет гелиоцентрическую орбиту по угловым наблюдениям
+// (прямое восхождение и склонение) без внешнего Python-сервиса.
+//
+// Начальная орбита строится методом Гаусса по трём наблюдениям с итерационным
+// уточнением наклонных дальностей, затем уточняется дифференциальной коррекцией
+// Левенберга — Марквардта по всем наблюдениям.
+package orbitdet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"backend-server/internal/app/approach"
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/observer"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// MinObservations — минимальное число наблюдений для определения орбиты.
+const MinObservations = 3
+
+// DefaultSigma — погрешность наблюдения без заданных SigmaRA и SigmaDec, угловые секунды.
+const DefaultSigma = 1.0
+
+// ErrTooFewObservations возвращается, если наблюдений меньше MinObservations.
+var ErrTooFewObservations = fmt.Errorf("orbitdet: need at least %d observations", MinObservations)
+
+// ErrNoInitialOrbit возвращается, если метод Гаусса не дал ни одного решения.
+var ErrNoInitialOrbit = errors.New("orbitdet: initial orbit determination failed")
+
+// Observation — одно астрометрическое наблюдение из центра Земли.
+type Observation struct {
+	Time  time.Time      // момент наблюдения, UTC
+	RA    float64        // прямое восхождение (ICRS), градусы
+	Dec   float64        // склонение (ICRS), градусы
+	Force Force          // принудительное включение или исключение из подгонки
+	Site  *observer.Site // место наблюдения; nil — наблюдение геоцентрическое
+
+	// SigmaRA и SigmaDec — погрешности RA·cos δ и склонения, угловые секунды; невязки
+	// в подгонке делятся на них. 0 — погрешность неизвестна, принимается DefaultSigma.
+	SigmaRA  float64
+	SigmaDec float64
+}
+
+// Stage — этап определения орбиты.
+type Stage string
+
+const (
+	StageValidation    Stage = "validation"     // наблюдения приняты и приведены к TT
+	StageInitialOrbit  Stage = "initial_orbit"  // построены начальные орбиты методом Гаусса
+	StageIteration     Stage = "iteration"      // итерация дифференциальной коррекции
+	StageCloseApproach Stage = "close_approach" // поиск сближения с Землёй
+)
+
+// Progress — событие о ходе расчёта, передаваемое в Options.Progress.
+type Progress struct {
+	Stage      Stage
+	Candidates int     // число начальных орбит (StageInitialOrbit)
+	Candidate  int     // номер уточняемой начальной орбиты, с нуля (StageIteration)
+	Iteration  int     // номер итерации (StageIteration)
+	RMS        float64 // текущая среднеквадратичная невязка в единицах погрешностей наблюдений; при DefaultSigma — угловые секунды (StageIteration)
+	Fraction   float64 // доля пройденного интервала поиска, 0…1 (StageCloseApproach)
+}
+
+// Options задаёт параметры дифференциальной коррекции и поиска сближения.
+// Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	MaxIterations int     // максимум итераций Левенберга — Марквардта (100)
+	Tolerance     float64 // относительное изменение суммы квадратов невязок для остановки (1e-10)
+	ApproachYears float64 // длительность поиска сближения с Землёй от перигелия, годы (5); для орбит с периодом длиннее интервала — и до перигелия
+	// Model — модель движения при поиске сближения; орбита подгоняется в задаче двух тел.
+	Model nbody.Model
+	// Planets — источник положений Земли для наблюдателя и при поиске сближения и
+	// возмущающих тел в модели n_body (planets.DefaultSource).
+	Planets planets.Source
+
+	// Loss — функция потерь подгонки; робастные функции снижают вес выбросов.
+	Loss Loss
+	// RejectSigma — порог отбраковки: наблюдение исключается, если его невязка больше
+	// RejectSigma·σ. 0 — без отбраковки.
+	RejectSigma float64
+
+	// Progress, если задан, вызывается на каждом этапе расчёта в той же горутине.
+	Progress func(Progress)
+}
+
+func (o Options) report(p Progress) {
+	if o.Progress != nil {
+		o.Progress(p)
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxIterations <= 0 {
+		o.MaxIterations = 100
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = 1e-10
+	}
+	if o.ApproachYears <= 0 {
+		o.ApproachYears = 5
+	}
+	if o.Planets == nil {
+		o.Planets = planets.DefaultSource()
+	}
+	return o
+}
+
+// Solution — результат определения орбиты. Поля элементов повторяют
+// orbitclient.OrbitResponse: углы в градусах относительно эклиптики J2000.
+type Solution struct {
+	PerihelionDistance        float64   // перигелийное расстояние q, а.е.
+	Eccentricity              float64   // эксцентриситет
+	Inclination               float64   // наклонение, градусы
+	LongitudeOfAscendingNode  float64   // долгота восходящего узла, градусы
+	ArgumentOfPerihelion      float64   // аргумент перигелия, градусы
+	TimeOfPerihelion          time.Time // момент прохождения перигелия, UTC
+	ClosestApproachTime       time.Time // момент минимального сближения с Землёй, UTC
+	ClosestApproachDistanceAU float64   // минимальное расстояние до Земли, а.е.
+
+	Residuals []Residual // невязки наблюдений в порядке, в котором они переданы в Determine
+
+	Epoch    float64        // эпоха вектора состояния, JD (TT)
+	State    orbit.State    // гелиоцентрический вектор состояния, экватор J2000
+	Elements orbit.Elements // элементы орбиты, эклиптика J2000
+	RMS      float64        // среднеквадратичная невязка принятых наблюдений, угловые секунды
+	// Chi2 — нормированный χ²: сумма квадратов невязок принятых наблюдений в единицах
+	// их погрешностей, делённая на число степеней свободы DegreesOfFreedom (2n − 6).
+	// Около 1, если погрешности наблюдений оценены верно; при DegreesOfFreedom = 0 — 0.
+	Chi2             float64
+	DegreesOfFreedom int
+	Iterations       int  // число итераций дифференциальной коррекции
+	Converged        bool // достигнут ли критерий сходимости
+
+	// StateCovariance — ковариация State (а.е., а.е./сут); Covariance — ковариация элементов
+	// q, e, i, Ω, ω, T (а.е., —, градусы, градусы, градусы, сутки). nil, если наблюдений
+	// слишком мало для оценки разброса.
+	StateCovariance *Covariance
+	Covariance      *Covariance
+}
+
+// Residual — невязка «наблюдено − вычислено» одного наблюдения, угловые секунды.
+type Residual struct {
+	RA       float64 // по прямому восхождению, умноженному на cos δ
+	Dec      float64 // по склонению
+	Rejected bool    // наблюдение исключено из подгонки (отбраковано или исключено принудительно)
+}
+
+// prepared — наблюдение, приведённое к виду, удобному для вычислений.
+type prepared struct {
+	index    int        // номер наблюдения во входных данных
+	t        float64    // момент, JD (TT)
+	ra, dec  float64    // радианы
+	los      orbit.Vec3 // единичный вектор направления на объект
+	observer orbit.Vec3 // гелиоцентрическое положение наблюдателя, экватор J2000
+	force    Force
+	weight   float64 // вес в подгонке; 0 — наблюдение исключено
+
+	sigmaRA, sigmaDec float64 // погрешности координат, угловые секунды
+}
+
+// Determine определяет орбиту по наблюдениям.
+func Determine(observations []Observation, opts Options) (*Solution, error) {
+	opts = opts.withDefaults()
+
+	obs, err := prepare(observations, opts.Planets)
+	if err != nil {
+		return nil, err
+	}
+	if included(obs) < MinObservations {
+		return nil, ErrTooFewObservations
+	}
+	opts.report(Progress{Stage: StageValidation})
+
+	// начальные орбиты строятся только по наблюдениям, не исключённым принудительно
+	var active []prepared
+	for _, o := range obs {
+		if o.weight > 0 {
+			active = append(active, o)
+		}
+	}
+	candidates := initialOrbits(active)
+	if len(candidates) == 0 {
+		return nil, ErrNoInitialOrbit
+	}
+	opts.report(Progress{Stage: StageInitialOrbit, Candidates: len(candidates)})
+
+	var best *fitResult
+	for i, c := range candidates {
+		res, err := robustFit(obs, c, i, opts)
+		if err != nil {
+			continue
+		}
+		if best == nil || res.rms < best.rms {
+			best = res
+		}
+	}
+	if best == nil {
+		return nil, ErrNoInitialOrbit
+	}
+
+	return newSolution(best, opts)
+}
+
+// prepare переводит наблюдения в шкалу TT, радианы и добавляет положение наблюдателя:
+// центра Земли по источнику src или, если задано место наблюдения, точки на её поверхности.
+func prepare(observations []Observation, src planets.Source) ([]prepared, error) {
+	obs := make([]prepared, len(observations))
+	for i, o := range observations {
+		t := astrotime.TT(o.Time)
+		earth, err := src.State(planets.BodyEarth, t)
+		if err != nil {
+			return nil, fmt.Errorf("observation %d: %w", i, err)
+		}
+		ra, dec := o.RA*orbit.Deg, o.Dec*orbit.Deg
+		obs[i] = prepared{
+			index:    i,
+			t:        t,
+			ra:       ra,
+			dec:      dec,
+			los:      orbit.UnitVector(ra, dec),
+			observer: earth.R,
+			force:    o.Force,
+			weight:   1,
+			sigmaRA:  sigmaOrDefault(o.SigmaRA),
+			sigmaDec: sigmaOrDefault(o.SigmaDec),
+		}
+		if o.Site != nil {
+			obs[i].observer = obs[i].observer.Add(o.Site.Geocentric(o.Time))
+		}
+		if o.Force == ForceExclude {
+			obs[i].weight = 0
+		}
+	}
+	sort.SliceStable(obs, func(i, j int) bool { return obs[i].t < obs[j].t })
+	return obs, nil
+}
+
+func sigmaOrDefault(sigma float64) float64 {
+	if sigma > 0 {
+		return sigma
+	}
+	return DefaultSigma
+}
+
+// newSolution переводит результат коррекции в элементы орбиты, вычисляет невязки
+// всех наблюдений, включая исключённые, и ищет сближение с Землёй.
+func newSolution(fit *fitResult, opts Options) (*Solution, error) {
+	raw := unweighted(fit.obs)
+	res := make([]float64, 2*len(raw))
+	if err := residuals(fit.state, fit.epoch, raw, res); err != nil {
+		return nil, err
+	}
+	// residuals считает «вычислено − наблюдено» в единицах погрешностей,
+	// в ответе — «наблюдено − вычислено» в угловых секундах
+	oc := make([]Residual, len(raw))
+	sum, chi2, n := 0.0, 0.0, 0
+	for k, o := range fit.obs {
+		r := Residual{RA: -res[2*k] * o.sigmaRA, Dec: -res[2*k+1] * o.sigmaDec, Rejected: o.weight == 0}
+		oc[o.index] = r
+		if o.weight > 0 {
+			sum += r.RA*r.RA + r.Dec*r.Dec
+			chi2 += res[2*k]*res[2*k] + res[2*k+1]*res[2*k+1]
+			n += 2
+		}
+	}
+	dof := max(n-6, 0)
+	if dof > 0 {
+		chi2 /= float64(dof)
+	} else {
+		chi2 = 0
+	}
+
+	el := orbit.ElementsFromState(fit.state.ToEcliptic(), fit.epoch, orbit.MuSun)
+
+	closestJD, closestAU, err := closestApproach(fit.state, fit.epoch, el, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stateCov, err := stateCovariance(fit)
+	if err != nil {
+		// вырожденная матрица нормальных уравнений: разброс не оценивается
+		stateCov = nil
+	}
+
+	return &Solution{
+		PerihelionDistance:        el.Q,
+		Eccentricity:              el.E,
+		Inclination:               el.I / orbit.Deg,
+		LongitudeOfAscendingNode:  el.Node / orbit.Deg,
+		ArgumentOfPerihelion:      el.ArgPeri / orbit.Deg,
+		TimeOfPerihelion:          astrotime.UTCFromTT(el.Tp),
+		ClosestApproachTime:       astrotime.UTCFromTT(closestJD),
+		ClosestApproachDistanceAU: closestAU,
+		Residuals:                 oc,
+		Epoch:                     fit.epoch,
+		State:                     fit.state,
+		Elements:                  el,
+		StateCovariance:           stateCov,
+		Covariance:                elementCovariance(fit.state, fit.epoch, stateCov),
+		RMS:                       math.Sqrt(sum / float64(n)),
+		Chi2:                      chi2,
+		DegreesOfFreedom:          dof,
+		Iterations:                fit.iterations,
+		Converged:                 fit.converged,
+	}, nil
+}
+
+// closestApproach ищет минимальное расстояние до Земли (см. approach.Find) на интервале
+// approach.Window длительностью opts.ApproachYears лет от момента прохождения перигелия.
+// Орбита распространяется от состояния state на момент epoch в модели opts.Model.
+func closestApproach(state orbit.State, epoch float64, el orbit.Elements, opts Options) (jd, distance float64, err error) {
+	propagator, err := nbody.NewPropagator(opts.Model, state, epoch, opts.Planets)
+	if err != nil {
+		return 0, 0, err
+	}
+	start, end := approach.Window(el, opts.ApproachYears)
+	opts.report(Progress{Stage: StageCloseApproach})
+	res, err := approach.Find(propagator, approach.BodyTarget(opts.Planets, planets.BodyEarth), start, end, approach.Options{
+		Progress: func(fraction float64) {
+			opts.report(Progress{Stage: StageCloseApproach, Fraction: fraction})
+		},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return res.Closest.JD, res.Closest.DistanceAU, nil
+}
@@ -0,0 +1,175 @@
+package orbitdet
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/observer"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// cometCase — комета с опубликованными элементами (эклиптика J2000) и дугой наблюдений.
+// Сеть доступа к MPC в тестах нет, поэтому астрометрия строится из опубликованных элементов
+// той же моделью наблюдения, что используется в подгонке, с гауссовым шумом noise угл. сек.
+type cometCase struct {
+	name                           string
+	q, e, i, node, argPeri         float64 // а.е., градусы
+	tp                             time.Time
+	first                          time.Time // первое наблюдение
+	n                              int       // число наблюдений
+	step                           time.Duration
+	noise                          float64 // угловые секунды
+	tolQ, tolE, tolAngle, tolTDays float64
+}
+
+var cometCases = []cometCase{
+	{
+		// короткопериодическая: 2P/Encke, появление 2017 г. (JPL SBDB)
+		name: "2P/Encke", q: 0.33588, e: 0.84833, i: 11.7807, node: 334.5682, argPeri: 186.5455,
+		tp:    time.Date(2017, 3, 10, 2, 24, 0, 0, time.UTC),
+		first: time.Date(2016, 12, 20, 0, 0, 0, 0, time.UTC), n: 16, step: 4 * 24 * time.Hour,
+		noise: 0.5, tolQ: 1e-4, tolE: 1e-4, tolAngle: 0.01, tolTDays: 0.01,
+	},
+	{
+		// почти параболическая: C/1995 O1 (Хейла — Боппа) (JPL SBDB)
+		name: "C/1995 O1", q: 0.91414, e: 0.99509, i: 89.4298, node: 282.4707, argPeri: 130.5887,
+		tp:    time.Date(1997, 4, 1, 3, 18, 0, 0, time.UTC),
+		first: time.Date(1996, 6, 1, 0, 0, 0, 0, time.UTC), n: 20, step: 9 * 24 * time.Hour,
+		noise: 0.5, tolQ: 1e-4, tolE: 1e-4, tolAngle: 0.01, tolTDays: 0.01,
+	},
+}
+
+// observations строит астрометрию кометы: наблюдения через одно — геоцентрические
+// и из обсерватории Кисловодска.
+func (c cometCase) observations(t *testing.T) []Observation {
+	t.Helper()
+	el := orbit.ElementsFromDegrees(c.q, c.e, c.i, c.node, c.argPeri, astrotime.TT(c.tp))
+	state, err := orbit.StateFromElements(el, el.Tp, orbit.MuSun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state = state.ToEquatorial()
+
+	site := observer.FromGeodetic(43.74, 42.67, 2070)
+	rng := rand.New(rand.NewSource(1))
+	obs := make([]Observation, c.n)
+	for k := range obs {
+		obs[k] = Observation{Time: c.first.Add(time.Duration(k) * c.step)}
+		if k%2 == 1 {
+			obs[k].Site = &site
+		}
+		o, err := prepare(obs[k:k+1], planets.DefaultSource())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ra, dec, err := predict(state, el.Tp, o[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec += rng.NormFloat64() * c.noise * orbit.ArcSec
+		ra += rng.NormFloat64() * c.noise * orbit.ArcSec / math.Cos(dec)
+		obs[k].RA = orbit.NormalizeAngle(ra) / orbit.Deg
+		obs[k].Dec = dec / orbit.Deg
+	}
+	return obs
+}
+
+func TestDeterminePublishedComets(t *testing.T) {
+	for _, c := range cometCases {
+		t.Run(c.name, func(t *testing.T) {
+			sol, err := Determine(c.observations(t), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sol.Converged {
+				t.Errorf("fit did not converge in %d iterations", sol.Iterations)
+			}
+			if sol.RMS > 3*c.noise {
+				t.Errorf("rms = %.3f″, want about %.1f″", sol.RMS, c.noise)
+			}
+
+			checks := []struct {
+				name      string
+				got, want float64
+				tol       float64
+			}{
+				{"q", sol.PerihelionDistance, c.q, c.tolQ},
+				{"e", sol.Eccentricity, c.e, c.tolE},
+				{"i", sol.Inclination, c.i, c.tolAngle},
+				{"node", sol.LongitudeOfAscendingNode, c.node, c.tolAngle},
+				{"argPeri", sol.ArgumentOfPerihelion, c.argPeri, c.tolAngle},
+				{"T, days", astrotime.TT(sol.TimeOfPerihelion) - astrotime.TT(c.tp), 0, c.tolTDays},
+			}
+			for _, ch := range checks {
+				diff := ch.got - ch.want
+				if ch.name != "q" && ch.name != "e" && ch.name != "T, days" {
+					diff = orbit.WrapAngle(diff*orbit.Deg) / orbit.Deg
+				}
+				if math.Abs(diff) > ch.tol {
+					t.Errorf("%s = %.6f, want %.6f ± %g", ch.name, ch.got, ch.want, ch.tol)
+				}
+			}
+		})
+	}
+}
+
+// Метод Гаусса сам по себе должен дать начальную орбиту, достаточно близкую к истинной,
+// чтобы коррекция сошлась к ней, а не к ложному решению.
+func TestInitialOrbitsPublishedComets(t *testing.T) {
+	for _, c := range cometCases {
+		t.Run(c.name, func(t *testing.T) {
+			obs, err := prepare(c.observations(t), planets.DefaultSource())
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidates := initialOrbits(obs)
+			if len(candidates) == 0 {
+				t.Fatal("no initial orbit")
+			}
+			best := math.Inf(1)
+			for _, cand := range candidates {
+				el := orbit.ElementsFromState(cand.state.ToEcliptic(), cand.epoch, orbit.MuSun)
+				best = min(best, math.Abs(el.Q-c.q)/c.q)
+			}
+			if best > 0.05 {
+				t.Errorf("closest initial orbit has q off by %.1f%%, want within 5%%", best*100)
+			}
+		})
+	}
+}
+
+// sourceFunc — источник положений планет из функции, как эфемерида JPL в приложении.
+type sourceFunc func(b planets.Body, jd float64) (orbit.State, error)
+
+func (f sourceFunc) State(b planets.Body, jd float64) (orbit.State, error) { return f(b, jd) }
+
+// Положение наблюдателя берётся из того же источника, что и поиск сближения.
+func TestPrepareUsesPlanetSource(t *testing.T) {
+	earth := orbit.Vec3{0.3, -0.9, 0.1}
+	src := sourceFunc(func(b planets.Body, jd float64) (orbit.State, error) {
+		if b != planets.BodyEarth {
+			t.Errorf("asked for %s, want earth", b)
+		}
+		return orbit.State{R: earth}, nil
+	})
+	obs, err := prepare([]Observation{{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), RA: 10, Dec: 5}}, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obs[0].observer != earth {
+		t.Errorf("observer = %v, want %v", obs[0].observer, earth)
+	}
+
+	outOfRange := errors.New("epoch is outside the ephemeris span")
+	_, err = Determine(cometCases[0].observations(t), Options{Planets: sourceFunc(func(planets.Body, float64) (orbit.State, error) {
+		return orbit.State{}, outOfRange
+	})})
+	if !errors.Is(err, outOfRange) {
+		t.Errorf("err = %v, want %v", err, outOfRange)
+	}
+}
@@ -0,0 +1,187 @@
+package orbitdet
+
+import (
+	"math"
+	"sort"
+)
+
+// Force — принудительное решение по наблюдению, которое не меняет отбраковка.
+type Force string
+
+const (
+	ForceNone    Force = ""
+	ForceInclude Force = "include" // наблюдение всегда участвует в подгонке
+	ForceExclude Force = "exclude" // наблюдение никогда не участвует в подгонке
+)
+
+// Loss — функция потерь дифференциальной коррекции.
+type Loss string
+
+const (
+	LossLinear Loss = ""       // обычные наименьшие квадраты
+	LossHuber  Loss = "huber"  // квадратичная вблизи нуля, линейная на хвостах
+	LossCauchy Loss = "cauchy" // логарифмическая, сильнее подавляет выбросы
+)
+
+const (
+	// maxRobustPasses — максимум повторных подгонок с пересчётом весов и отбраковки
+	maxRobustPasses = 10
+	// weightTolerance — изменение весов, при котором пересчёт останавливается
+	weightTolerance = 1e-3
+	// постоянные Хьюбера и Коши, дающие 95 % эффективности на нормальном распределении
+	huberK  = 1.345
+	cauchyC = 2.385
+	// madScale переводит медианное абсолютное отклонение в σ нормального распределения
+	madScale = 1.4826
+)
+
+// robustFit уточняет начальную орбиту и, если заданы робастная функция потерь или порог
+// отбраковки, повторяет подгонку с пересчитанными весами, пока они не перестанут меняться.
+func robustFit(obs []prepared, c candidate, index int, opts Options) (*fitResult, error) {
+	obs = append([]prepared(nil), obs...)
+
+	res, err := differentialCorrection(obs, c, index, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Loss == LossLinear && opts.RejectSigma <= 0 {
+		return res, nil
+	}
+
+	for range maxRobustPasses {
+		changed, err := reweight(obs, res, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			break
+		}
+		res, err = differentialCorrection(obs, candidate{state: res.state, epoch: res.epoch}, index, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// reweight пересчитывает веса наблюдений по невязкам орбиты fit. Масштаб σ оценивается
+// по медианному абсолютному отклонению принятых наблюдений, поэтому сами выбросы его не
+// раздувают, и не бывает меньше 1: невязки выражены в погрешностях наблюдений, и
+// наблюдения, согласные со своими погрешностями, не отбраковываются, даже если остальные
+// легли на орбиту точнее. Наблюдения, у которых модуль невязки по обеим координатам больше
+// RejectSigma·σ, получают нулевой вес, но так, чтобы в подгонке осталось не меньше
+// MinObservations. Возвращает true, если веса изменились.
+func reweight(obs []prepared, fit *fitResult, opts Options) (bool, error) {
+	raw := unweighted(obs)
+	res := make([]float64, 2*len(raw))
+	if err := residuals(fit.state, fit.epoch, raw, res); err != nil {
+		return false, err
+	}
+
+	// нормированная невязка наблюдения — модуль двумерного отклонения (ΔRA·cosδ, ΔDec)
+	dev := make([]float64, len(obs))
+	var abs []float64
+	for k := range obs {
+		dev[k] = math.Hypot(res[2*k], res[2*k+1])
+		if obs[k].force != ForceExclude {
+			abs = append(abs, math.Abs(res[2*k]), math.Abs(res[2*k+1]))
+		}
+	}
+	sigma := max(madScale*median(abs), 1)
+
+	weights := make([]float64, len(obs))
+	for k, o := range obs {
+		u := dev[k] / sigma
+		switch {
+		case o.force == ForceExclude:
+			weights[k] = 0
+		case o.force != ForceInclude && opts.RejectSigma > 0 && u > opts.RejectSigma:
+			weights[k] = 0
+		default:
+			weights[k] = lossWeight(opts.Loss, u)
+		}
+	}
+
+	// если отбраковка оставила слишком мало наблюдений, возвращаются ближайшие к порогу
+	if n := countPositive(weights); n < MinObservations {
+		order := make([]int, 0, len(obs))
+		for k, o := range obs {
+			if weights[k] == 0 && o.force != ForceExclude {
+				order = append(order, k)
+			}
+		}
+		sort.Slice(order, func(i, j int) bool { return dev[order[i]] < dev[order[j]] })
+		for _, k := range order[:min(MinObservations-n, len(order))] {
+			weights[k] = lossWeight(opts.Loss, dev[k]/sigma)
+		}
+	}
+
+	changed := false
+	for k := range obs {
+		if (weights[k] == 0) != (obs[k].weight == 0) || math.Abs(weights[k]-obs[k].weight) > weightTolerance {
+			changed = true
+		}
+		obs[k].weight = weights[k]
+	}
+	return changed, nil
+}
+
+// lossWeight возвращает вес итеративно перевзвешенных наименьших квадратов для
+// невязки u, выраженной в единицах σ.
+func lossWeight(loss Loss, u float64) float64 {
+	switch loss {
+	case LossHuber:
+		if u <= huberK {
+			return 1
+		}
+		return huberK / u
+	case LossCauchy:
+		return 1 / (1 + (u/cauchyC)*(u/cauchyC))
+	default:
+		return 1
+	}
+}
+
+// unweighted возвращает копию наблюдений с единичными весами, чтобы вычислить
+// невязки всех наблюдений, в том числе исключённых.
+func unweighted(obs []prepared) []prepared {
+	out := append([]prepared(nil), obs...)
+	for i := range out {
+		out[i].weight = 1
+	}
+	return out
+}
+
+// included возвращает число наблюдений с ненулевым весом.
+func included(obs []prepared) int {
+	n := 0
+	for _, o := range obs {
+		if o.weight > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func countPositive(v []float64) int {
+	n := 0
+	for _, x := range v {
+		if x > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// median возвращает медиану значений; v переупорядочивается.
+func median(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
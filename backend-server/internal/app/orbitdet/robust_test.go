@@ -0,0 +1,136 @@
+package orbitdet
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLossWeight(t *testing.T) {
+	tests := []struct {
+		loss Loss
+		u    float64
+		want float64
+	}{
+		{LossLinear, 0, 1},
+		{LossLinear, 100, 1},
+		{LossHuber, 0, 1},
+		{LossHuber, huberK, 1},
+		{LossHuber, 2 * huberK, 0.5},
+		{LossHuber, 10 * huberK, 0.1},
+		{LossCauchy, 0, 1},
+		{LossCauchy, cauchyC, 0.5},
+		{LossCauchy, 3 * cauchyC, 0.1},
+	}
+	for _, tt := range tests {
+		if got := lossWeight(tt.loss, tt.u); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("lossWeight(%q, %g) = %g, want %g", tt.loss, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		v    []float64
+		want float64
+	}{
+		{nil, 0},
+		{[]float64{3}, 3},
+		{[]float64{5, 1, 3}, 3},
+		{[]float64{4, 1, 3, 2}, 2.5},
+		{[]float64{1, 1, 1, 1000}, 1},
+	}
+	for _, tt := range tests {
+		if got := median(append([]float64(nil), tt.v...)); got != tt.want {
+			t.Errorf("median(%v) = %g, want %g", tt.v, got, tt.want)
+		}
+	}
+}
+
+// Выброс в одном наблюдении отбраковывается, остальные остаются в подгонке, и орбита
+// получается такой же, как без выброса. Принудительные решения отбраковку перекрывают.
+func TestDetermineRejectsInjectedOutlier(t *testing.T) {
+	const (
+		bad     = 7  // номер испорченного наблюдения
+		outlier = 30 // сдвиг по склонению, угловые секунды
+	)
+	c := cometCases[0]
+
+	tests := []struct {
+		name         string
+		opts         Options
+		force        map[int]Force
+		wantRejected []int
+		wantRMS      float64 // верхняя граница RMS принятых наблюдений, угловые секунды
+	}{
+		{
+			name:         "sigma clipping",
+			opts:         Options{RejectSigma: 3},
+			wantRejected: []int{bad},
+			wantRMS:      3 * c.noise,
+		},
+		{
+			name:         "huber with clipping",
+			opts:         Options{Loss: LossHuber, RejectSigma: 3},
+			wantRejected: []int{bad},
+			wantRMS:      3 * c.noise,
+		},
+		{
+			name:         "cauchy without clipping",
+			opts:         Options{Loss: LossCauchy},
+			wantRejected: nil,
+			wantRMS:      outlier,
+		},
+		{
+			name:         "forced include",
+			opts:         Options{RejectSigma: 3},
+			force:        map[int]Force{bad: ForceInclude},
+			wantRejected: nil,
+			wantRMS:      outlier,
+		},
+		{
+			name:         "forced exclude of a good observation",
+			opts:         Options{RejectSigma: 3},
+			force:        map[int]Force{2: ForceExclude},
+			wantRejected: []int{2, bad},
+			wantRMS:      3 * c.noise,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs := c.observations(t)
+			obs[bad].Dec += outlier / 3600.0
+			for k, f := range tt.force {
+				obs[k].Force = f
+			}
+
+			sol, err := Determine(obs, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := make(map[int]bool)
+			for _, k := range tt.wantRejected {
+				want[k] = true
+			}
+			for k, r := range sol.Residuals {
+				if r.Rejected != want[k] {
+					t.Errorf("observation %d: rejected = %v, want %v (O−C %.2f″, %.2f″)", k, r.Rejected, want[k], r.RA, r.Dec)
+				}
+			}
+			if sol.RMS > tt.wantRMS {
+				t.Errorf("rms = %.3f″, want at most %.1f″", sol.RMS, tt.wantRMS)
+			}
+			if !want[bad] {
+				return
+			}
+			// невязка исключённого выброса по-прежнему видна в ответе, а орбита его не учитывает
+			if r := sol.Residuals[bad]; math.Abs(r.Dec-outlier) > 3*c.noise {
+				t.Errorf("outlier O−C in Dec = %.2f″, want about %d″", r.Dec, outlier)
+			}
+			if math.Abs(sol.PerihelionDistance-c.q) > c.tolQ {
+				t.Errorf("q = %.6f, want %.6f ± %g", sol.PerihelionDistance, c.q, c.tolQ)
+			}
+		})
+	}
+}
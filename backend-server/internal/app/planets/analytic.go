@@ -0,0 +1,197 @@
+package planets
+
+import (
+	"fmt"
+	"math"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbit"
+)
+
+// velocityStep — шаг численного дифференцирования положений аналитических теорий, сутки.
+const velocityStep = 0.01
+
+// Analytic — эфемерида планет и Луны по аналитическим теориям, вычисляемым на каждый
+// запрошенный момент: Земля — VSOP87 (Earth), остальные планеты — средние элементы
+// Standish (JPL, «Keplerian Elements for Approximate Positions of the Major Planets»,
+// таблица 1), Луна — главные члены теории ELP по Meeus. Охват не ограничен, но средние
+// элементы подобраны по DE405 на 1800–2050 годы, и вне этого интервала ошибка растёт.
+//
+// Ошибка положения — около 1″ у Земли, десятки угловых секунд у Меркурия — Марса и Луны
+// и до нескольких угловых минут у Юпитера и Сатурна (около 0.01 а.е.), поэтому теории —
+// лишь запасной источник за пределами охвата встроенной эфемериды (DefaultSource).
+type Analytic struct{}
+
+// Position возвращает гелиоцентрическое положение тела (а.е.) в экваториальной системе J2000
+// на момент jd (TT).
+func (Analytic) Position(b Body, jd float64) (orbit.Vec3, error) {
+	switch b {
+	case BodySun:
+		return orbit.Vec3{}, nil
+	case BodyEarth:
+		return Earth(jd), nil
+	case BodyMoon:
+		return Earth(jd).Add(moon(jd)), nil
+	}
+	el, ok := standish[b]
+	if !ok {
+		return orbit.Vec3{}, fmt.Errorf("planets: unknown body %d", int(b))
+	}
+	return el.position(jd), nil
+}
+
+// State реализует Source. Скорость — центральная разность положений с шагом velocityStep.
+func (a Analytic) State(b Body, jd float64) (orbit.State, error) {
+	before, err := a.Position(b, jd-velocityStep)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	after, err := a.Position(b, jd+velocityStep)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	r, err := a.Position(b, jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	return orbit.State{R: r, V: after.Sub(before).Scale(1 / (2 * velocityStep))}, nil
+}
+
+// meanElements — средние элементы планеты на J2000 и их скорости изменения за столетие
+// (Standish, таблица 1): a (а.е.), e, i, L, ϖ, Ω (градусы) относительно эклиптики J2000.
+type meanElements struct {
+	a, e, i, l, peri, node       float64
+	da, de, di, dl, dperi, dnode float64
+}
+
+var standish = map[Body]meanElements{
+	BodyMercury: {0.38709927, 0.20563593, 7.00497902, 252.25032350, 77.45779628, 48.33076593,
+		0.00000037, 0.00001906, -0.00594749, 149472.67411175, 0.16047689, -0.12534081},
+	BodyVenus: {0.72333566, 0.00677672, 3.39467605, 181.97909950, 131.60246718, 76.67984255,
+		0.00000390, -0.00004107, -0.00078890, 58517.81538729, 0.00268329, -0.27769418},
+	BodyMars: {1.52371034, 0.09339410, 1.84969142, -4.55343205, -23.94362959, 49.55953891,
+		0.00001847, 0.00007882, -0.00813131, 19140.30268499, 0.44441088, -0.29257343},
+	BodyJupiter: {5.20288700, 0.04838624, 1.30439695, 34.39644051, 14.72847983, 100.47390909,
+		-0.00011607, -0.00013253, -0.00183714, 3034.74612775, 0.21252668, 0.20469106},
+	BodySaturn: {9.53667594, 0.05386179, 2.48599187, 49.95424423, 92.59887831, 113.66242448,
+		-0.00125060, -0.00050991, 0.00193609, 1222.49362201, -0.41897216, -0.28867794},
+	BodyUranus: {19.18916464, 0.04725744, 0.77263783, 313.23810451, 170.95427630, 74.01692503,
+		-0.00196176, -0.00004397, -0.00242939, 428.48202785, 0.40805281, 0.04240589},
+	BodyNeptune: {30.06992276, 0.00859048, 1.77004347, -55.12002969, 44.96476227, 131.78422574,
+		0.00026291, 0.00005105, 0.00035372, 218.45945325, -0.32241464, -0.00508664},
+}
+
+// position возвращает гелиоцентрическое положение планеты (а.е.) в экваториальной системе J2000.
+func (m meanElements) position(jd float64) orbit.Vec3 {
+	t := astrotime.CenturiesSinceJ2000(jd)
+	a := m.a + m.da*t
+	e := m.e + m.de*t
+	i := (m.i + m.di*t) * orbit.Deg
+	l := (m.l + m.dl*t) * orbit.Deg
+	peri := (m.peri + m.dperi*t) * orbit.Deg
+	node := (m.node + m.dnode*t) * orbit.Deg
+
+	// уравнение Кеплера E − e·sin E = M решается методом Ньютона
+	M := orbit.WrapAngle(l - peri)
+	E := M + e*math.Sin(M)
+	for range 10 {
+		dE := (E - e*math.Sin(E) - M) / (1 - e*math.Cos(E))
+		E -= dE
+		if math.Abs(dE) < 1e-14 {
+			break
+		}
+	}
+	x := a * (math.Cos(E) - e)
+	y := a * math.Sqrt(1-e*e) * math.Sin(E)
+
+	ecl := orbit.RotZ(-node).Mul(orbit.RotX(-i)).Mul(orbit.RotZ(-(peri - node))).Apply(orbit.Vec3{x, y, 0})
+	return orbit.EclipticToEquatorial(ecl)
+}
+
+// lunarTerm — член ряда долготы и расстояния Луны (Meeus, таблица 47.A):
+// множители D, M, M′, F, коэффициент синуса долготы (1e-6°) и косинуса расстояния (1e-3 км).
+type lunarTerm struct {
+	d, m, mp, f float64
+	sl, sr      float64
+}
+
+var lunarLR = []lunarTerm{
+	{0, 0, 1, 0, 6288774, -20905355},
+	{2, 0, -1, 0, 1274027, -3699111},
+	{2, 0, 0, 0, 658314, -2955968},
+	{0, 0, 2, 0, 213618, -569925},
+	{0, 1, 0, 0, -185116, 48888},
+	{0, 0, 0, 2, -114332, -3149},
+	{2, 0, -2, 0, 58793, 246158},
+	{2, -1, -1, 0, 57066, -152138},
+	{2, 0, 1, 0, 53322, -170733},
+	{2, -1, 0, 0, 45758, -204586},
+	{0, 1, -1, 0, -40923, -129620},
+	{1, 0, 0, 0, -34720, 108743},
+	{0, 1, 1, 0, -30383, 104755},
+	{2, 0, 0, -2, 15327, 10321},
+	{0, 0, 1, 2, -12528, 0},
+	{0, 0, 1, -2, 10980, 79661},
+	{4, 0, -1, 0, 10675, -34782},
+	{0, 0, 3, 0, 10034, -23210},
+	{4, 0, -2, 0, 8548, -21636},
+	{2, 1, -1, 0, -7888, 24208},
+	{2, 1, 0, 0, -6766, 30824},
+	{1, 0, -1, 0, -5163, -8379},
+	{1, 1, 0, 0, 4987, -16675},
+	{2, -1, 1, 0, 4036, -12831},
+}
+
+// lunarB — члены ряда широты Луны (Meeus, таблица 47.B): множители D, M, M′, F
+// и коэффициент синуса (1e-6°).
+var lunarB = [][5]float64{
+	{0, 0, 0, 1, 5128122},
+	{0, 0, 1, 1, 280602},
+	{0, 0, 1, -1, 277693},
+	{2, 0, 0, -1, 173237},
+	{2, 0, -1, 1, 55413},
+	{2, 0, -1, -1, 46271},
+	{2, 0, 0, 1, 32573},
+	{0, 0, 2, 1, 17198},
+	{2, 0, 1, -1, 9266},
+	{0, 0, 2, -1, 8822},
+	{2, -1, 0, -1, 8216},
+	{2, 0, -2, -1, 4324},
+	{2, 0, 1, 1, 4200},
+}
+
+// moon возвращает геоцентрическое положение Луны (а.е.) в экваториальной системе J2000.
+// Главные члены рядов дают точность около 10″ по долготе и десятков километров по расстоянию.
+func moon(jd float64) orbit.Vec3 {
+	t := astrotime.CenturiesSinceJ2000(jd)
+	lp := (218.3164477 + 481267.88123421*t) * orbit.Deg
+	d := (297.8501921 + 445267.1114034*t) * orbit.Deg
+	m := (357.5291092 + 35999.0502909*t) * orbit.Deg
+	mp := (134.9633964 + 477198.8675055*t) * orbit.Deg
+	f := (93.2720950 + 483202.0175233*t) * orbit.Deg
+	// поправка за убывание эксцентриситета земной орбиты
+	ecc := 1 - 0.002516*t
+
+	var sl, sr, sb float64
+	for _, c := range lunarLR {
+		arg := c.d*d + c.m*m + c.mp*mp + c.f*f
+		k := math.Pow(ecc, math.Abs(c.m))
+		sl += c.sl * k * math.Sin(arg)
+		sr += c.sr * k * math.Cos(arg)
+	}
+	for _, c := range lunarB {
+		arg := c[0]*d + c[1]*m + c[2]*mp + c[3]*f
+		sb += c[4] * math.Pow(ecc, math.Abs(c[1])) * math.Sin(arg)
+	}
+
+	lon := lp + sl*1e-6*orbit.Deg
+	lat := sb * 1e-6 * orbit.Deg
+	r := (385000.56 + sr*1e-3) / orbit.KmPerAU
+
+	// средняя эклиптика даты → средний экватор даты → экватор J2000
+	sb2, cb := math.Sincos(lat)
+	sl2, cl := math.Sincos(lon)
+	ecl := orbit.Vec3{r * cb * cl, r * cb * sl2, r * sb2}
+	equ := orbit.RotX(-orbit.MeanObliquity(t)).Apply(ecl)
+	return orbit.PrecessionMatrix(t).Transpose().Apply(equ)
+}
@@ -0,0 +1,107 @@
+package planets
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Body — тело Солнечной системы, положение которого даёт эфемерида. Функция Earth
+// занимает имя, поэтому константы тел названы с префиксом Body.
+// Значения записываются во встроенный файл эфемериды (data/planets.cheb) и не должны меняться.
+type Body int
+
+const (
+	BodySun Body = iota
+	BodyMercury
+	BodyVenus
+	BodyEarth
+	BodyMoon
+	BodyMars
+	BodyJupiter
+	BodySaturn
+	BodyUranus
+	BodyNeptune
+
+	bodyCount = iota
+)
+
+// Perturbers — тела, притяжение которых учитывается при численном интегрировании
+// (кроме центрального — Солнца).
+var Perturbers = []Body{BodyMercury, BodyVenus, BodyEarth, BodyMoon, BodyMars, BodyJupiter, BodySaturn, BodyUranus, BodyNeptune}
+
+var bodyNames = [bodyCount]string{"sun", "mercury", "venus", "earth", "moon", "mars", "jupiter", "saturn", "uranus", "neptune"}
+
+// gmKm3s2 — гравитационные параметры тел по DE440, км³/с². Для планет с
+// одноимёнными спутниками (кроме Земли) — параметр всей системы.
+var gmKm3s2 = [bodyCount]float64{
+	BodySun:     132712440041.279419,
+	BodyMercury: 22031.868551,
+	BodyVenus:   324858.592,
+	BodyEarth:   398600.435507,
+	BodyMoon:    4902.800118,
+	BodyMars:    42828.375816,
+	BodyJupiter: 126712764.1,
+	BodySaturn:  37940584.8418,
+	BodyUranus:  5794556.4,
+	BodyNeptune: 6836527.10058,
+}
+
+// orbitAU — большие полуоси орбит тел вокруг центрального тела: планет — вокруг Солнца
+// (средние элементы Standish на J2000), Луны — вокруг Земли.
+var orbitAU = [bodyCount]float64{
+	BodyMercury: 0.38709927,
+	BodyVenus:   0.72333566,
+	BodyEarth:   1.00000261,
+	BodyMoon:    0.00256955529,
+	BodyMars:    1.52371034,
+	BodyJupiter: 5.20288700,
+	BodySaturn:  9.53667594,
+	BodyUranus:  19.18916464,
+	BodyNeptune: 30.06992276,
+}
+
+func (b Body) valid() bool { return b >= 0 && b < bodyCount }
+
+// String возвращает название тела строчными латинскими буквами.
+func (b Body) String() string {
+	if !b.valid() {
+		return fmt.Sprintf("body(%d)", int(b))
+	}
+	return bodyNames[b]
+}
+
+// GM возвращает гравитационный параметр тела, а.е.³/сут². Параметры планет
+// пересчитаны из DE440 в единицы, в которых параметр Солнца равен orbit.MuSun.
+func (b Body) GM() float64 {
+	if !b.valid() {
+		return 0
+	}
+	return gmKm3s2[b] / gmKm3s2[BodySun] * orbit.MuSun
+}
+
+// HillRadius возвращает радиус сферы Хилла тела, а.е.: a·∛(m / 3M), где a — большая
+// полуось орбиты, M — масса центрального тела (Солнца, для Луны — Земли). Для Солнца — 0.
+func (b Body) HillRadius() float64 {
+	if !b.valid() || b == BodySun {
+		return 0
+	}
+	central := BodySun
+	if b == BodyMoon {
+		central = BodyEarth
+	}
+	return orbitAU[b] * math.Cbrt(gmKm3s2[b]/(3*gmKm3s2[central]))
+}
+
+// ParseBody разбирает название тела без учёта регистра.
+func ParseBody(s string) (Body, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for b, n := range bodyNames {
+		if n == name {
+			return Body(b), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown body %q", s)
+}
@@ -0,0 +1,251 @@
+package planets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"backend-server/internal/app/orbit"
+)
+
+// Формат файла чебышёвской эфемериды (числа little-endian):
+//
+//	magic     [8]byte  "CHEBEPH1"
+//	start     float64  начало охвата, JD (TT)
+//	end       float64  конец охвата, JD (TT)
+//	count     uint32   число рядов
+//	count раз: body uint32, center uint32, interval float64 (сутки), n uint32
+//	коэффициенты рядов в том же порядке: для каждого интервала — x, y, z по n float32
+//
+// Ряд тела покрывает [start, end] интервалами длины interval, последний может выходить
+// за end. Положения — относительно центрального тела, в а.е., в экваториальной системе J2000.
+var chebMagic = [8]byte{'C', 'H', 'E', 'B', 'E', 'P', 'H', '1'}
+
+// seriesHeader — запись таблицы рядов в файле.
+type seriesHeader struct {
+	Body, Center uint32
+	Interval     float64
+	N            uint32
+}
+
+// ErrOutOfRange возвращается для момента за пределами охвата эфемериды.
+var ErrOutOfRange = errors.New("planets: epoch is outside the ephemeris span")
+
+// Series — чебышёвский ряд положения одного тела.
+type Series struct {
+	Body     Body
+	Center   Body      // тело, относительно которого заданы положения: Солнце или другое тело эфемериды
+	Interval float64   // длина интервала, сутки
+	N        int       // число коэффициентов на координату
+	Coef     []float64 // по интервалам: N коэффициентов x, затем y, затем z
+}
+
+// Chebyshev — эфемерида тел в виде чебышёвских рядов.
+type Chebyshev struct {
+	start, end float64
+	series     [bodyCount]*Series
+}
+
+// Span возвращает охват эфемериды, JD (TT).
+func (c *Chebyshev) Span() (start, end float64) { return c.start, c.end }
+
+// State возвращает гелиоцентрические положение (а.е.) и скорость (а.е./сут) тела
+// в экваториальной системе J2000 на момент jd (TT).
+func (c *Chebyshev) State(b Body, jd float64) (orbit.State, error) {
+	var s orbit.State
+	err := c.walk(b, jd, func(ser *Series, dt float64) {
+		rel := ser.state(dt, true)
+		s.R, s.V = s.R.Add(rel.R), s.V.Add(rel.V)
+	})
+	return s, err
+}
+
+// Position возвращает гелиоцентрическое положение тела (а.е.) в экваториальной системе J2000.
+func (c *Chebyshev) Position(b Body, jd float64) (orbit.Vec3, error) {
+	var r orbit.Vec3
+	err := c.walk(b, jd, func(ser *Series, dt float64) {
+		r = r.Add(ser.state(dt, false).R)
+	})
+	return r, err
+}
+
+// walk вызывает add для рядов тела b и его центров вплоть до Солнца.
+func (c *Chebyshev) walk(b Body, jd float64, add func(s *Series, dt float64)) error {
+	if !b.valid() {
+		return fmt.Errorf("planets: unknown body %d", int(b))
+	}
+	if jd < c.start || jd > c.end || math.IsNaN(jd) {
+		return fmt.Errorf("%w: JD %.1f, covered %.1f–%.1f", ErrOutOfRange, jd, c.start, c.end)
+	}
+	for b != BodySun {
+		ser := c.series[b]
+		if ser == nil {
+			return fmt.Errorf("planets: ephemeris has no %s", b)
+		}
+		add(ser, jd-c.start)
+		b = ser.Center
+	}
+	return nil
+}
+
+// state вычисляет положение относительно центра через dt суток от начала ряда, а если
+// velocity — и скорость.
+func (s *Series) state(dt float64, velocity bool) orbit.State {
+	records := len(s.Coef) / (3 * s.N)
+	k := min(int(dt/s.Interval), records-1)
+	x := 2*(dt-float64(k)*s.Interval)/s.Interval - 1
+	var st orbit.State
+	for i := range 3 {
+		c := s.Coef[(3*k+i)*s.N : (3*k+i+1)*s.N]
+		st.R[i] = chebyshevSum(c, x)
+		if velocity {
+			st.V[i] = chebyshevDerivative(c, x) * 2 / s.Interval
+		}
+	}
+	return st
+}
+
+// chebyshevSum вычисляет Σ cⱼ·Tⱼ(x) по схеме Кленшоу.
+func chebyshevSum(c []float64, x float64) float64 {
+	var b1, b2 float64
+	for j := len(c) - 1; j >= 1; j-- {
+		b1, b2 = 2*x*b1-b2+c[j], b1
+	}
+	return x*b1 - b2 + c[0]
+}
+
+// chebyshevDerivative вычисляет производную Σ cⱼ·Tⱼ(x) по x: T′ⱼ = j·Uⱼ₋₁.
+func chebyshevDerivative(c []float64, x float64) float64 {
+	sum := 0.0
+	u0, u1 := 0.0, 1.0 // Uⱼ₋₂, Uⱼ₋₁
+	for j := 1; j < len(c); j++ {
+		sum += c[j] * float64(j) * u1
+		u0, u1 = u1, 2*x*u1-u0
+	}
+	return sum
+}
+
+// Fit строит чебышёвский ряд тела body относительно center на [start, end] интервалами
+// длины interval по n коэффициентов, интерполируя pos в узлах Чебышёва.
+func Fit(body, center Body, start, end, interval float64, n int, pos func(jd float64) orbit.Vec3) Series {
+	count := records(start, end, interval)
+	s := Series{Body: body, Center: center, Interval: interval, N: n, Coef: make([]float64, 3*n*count)}
+	values := make([]orbit.Vec3, n)
+	for k := range count {
+		t0 := start + float64(k)*interval
+		for m := range values {
+			x := math.Cos(math.Pi * (float64(m) + 0.5) / float64(n))
+			values[m] = pos(t0 + (x+1)*interval/2)
+		}
+		for i := range 3 {
+			c := s.Coef[(3*k+i)*n : (3*k+i+1)*n]
+			for j := range c {
+				sum := 0.0
+				for m, v := range values {
+					sum += v[i] * math.Cos(math.Pi*float64(j)*(float64(m)+0.5)/float64(n))
+				}
+				c[j] = 2 * sum / float64(n)
+			}
+			c[0] /= 2
+		}
+	}
+	return s
+}
+
+// WriteChebyshev записывает ряды в файл эфемериды, охватывающий [start, end].
+func WriteChebyshev(w io.Writer, start, end float64, series []Series) error {
+	bw := bufio.NewWriter(w)
+	le := binary.LittleEndian
+	write := func(v any) error { return binary.Write(bw, le, v) }
+	if err := write(chebMagic); err != nil {
+		return err
+	}
+	if err := write([]float64{start, end}); err != nil {
+		return err
+	}
+	if err := write(uint32(len(series))); err != nil {
+		return err
+	}
+	for _, s := range series {
+		if err := write(seriesHeader{uint32(s.Body), uint32(s.Center), s.Interval, uint32(s.N)}); err != nil {
+			return err
+		}
+	}
+	for _, s := range series {
+		if want := 3 * s.N * records(start, end, s.Interval); len(s.Coef) != want {
+			return fmt.Errorf("planets: %s series has %d coefficients, want %d", s.Body, len(s.Coef), want)
+		}
+		coef := make([]float32, len(s.Coef))
+		for i, c := range s.Coef {
+			coef[i] = float32(c)
+		}
+		if err := write(coef); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadChebyshev читает файл эфемериды.
+func ReadChebyshev(r io.Reader) (*Chebyshev, error) {
+	br := bufio.NewReader(r)
+	le := binary.LittleEndian
+	var header struct {
+		Magic      [8]byte
+		Start, End float64
+		Count      uint32
+	}
+	if err := binary.Read(br, le, &header); err != nil {
+		return nil, fmt.Errorf("planets: read ephemeris header: %w", err)
+	}
+	if header.Magic != chebMagic {
+		return nil, errors.New("planets: not a Chebyshev ephemeris file")
+	}
+	if !(header.End > header.Start) || header.Count > bodyCount {
+		return nil, errors.New("planets: corrupt ephemeris header")
+	}
+
+	c := &Chebyshev{start: header.Start, end: header.End}
+	table := make([]seriesHeader, header.Count)
+	if err := binary.Read(br, le, table); err != nil {
+		return nil, fmt.Errorf("planets: read ephemeris series table: %w", err)
+	}
+	for _, t := range table {
+		b, center := Body(t.Body), Body(t.Center)
+		if !b.valid() || !center.valid() || b == BodySun || c.series[b] != nil ||
+			!(t.Interval > 0) || t.N < 2 || t.N > 64 {
+			return nil, fmt.Errorf("planets: corrupt ephemeris series for body %d", t.Body)
+		}
+		n := 3 * int(t.N) * records(header.Start, header.End, t.Interval)
+		coef := make([]float32, n)
+		if err := binary.Read(br, le, coef); err != nil {
+			return nil, fmt.Errorf("planets: read %s coefficients: %w", b, err)
+		}
+		s := &Series{Body: b, Center: center, Interval: t.Interval, N: int(t.N), Coef: make([]float64, n)}
+		for i, v := range coef {
+			s.Coef[i] = float64(v)
+		}
+		c.series[b] = s
+	}
+	// центры должны приводить к Солнцу без циклов
+	for b, s := range c.series {
+		for steps := 0; s != nil; steps++ {
+			if steps == bodyCount || (s.Center != BodySun && c.series[s.Center] == nil) {
+				return nil, fmt.Errorf("planets: %s is not connected to the Sun", Body(b))
+			}
+			if s.Center == BodySun {
+				break
+			}
+			s = c.series[s.Center]
+		}
+	}
+	return c, nil
+}
+
+// records возвращает число интервалов длины interval, покрывающих [start, end].
+func records(start, end, interval float64) int {
+	return int(math.Ceil((end - start) / interval))
+}
@@ -0,0 +1,142 @@
+package planets
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"backend-server/internal/app/orbit"
+)
+
+// circular — положение на круговой орбите радиуса r с периодом period суток.
+func circular(r, period float64) func(jd float64) orbit.Vec3 {
+	return func(jd float64) orbit.Vec3 {
+		s, c := math.Sincos(2 * math.Pi * jd / period)
+		return orbit.Vec3{r * c, r * s, 0.1 * r * s}
+	}
+}
+
+func TestChebyshevRoundTrip(t *testing.T) {
+	const start, end = 2451545.0, 2451545.0 + 1000
+	pos := circular(5, 4332.6)
+	moon := circular(0.00257, 27.32)
+	series := []Series{
+		Fit(BodyJupiter, BodySun, start, end, 128, 10, pos),
+		Fit(BodyMoon, BodyJupiter, start, end, 8, 10, moon),
+	}
+	var buf bytes.Buffer
+	if err := WriteChebyshev(&buf, start, end, series); err != nil {
+		t.Fatal(err)
+	}
+	eph, err := ReadChebyshev(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, e := eph.Span(); s != start || e != end {
+		t.Errorf("span = %v–%v, want %v–%v", s, e, start, end)
+	}
+
+	for _, jd := range []float64{start, start + 0.3, start + 128, start + 517.25, end} {
+		s, err := eph.State(BodyMoon, jd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Луна задана относительно Юпитера: координаты складываются по цепочке центров
+		want := pos(jd).Add(moon(jd))
+		// коэффициенты хранятся в float32: относительная ошибка около 1e-7
+		if d := s.R.Sub(want).Norm(); d > 1e-6 {
+			t.Errorf("JD %v: r off by %.2e AU", jd, d)
+		}
+		h := 1e-3
+		wantV := pos(jd + h).Add(moon(jd + h)).Sub(pos(jd - h).Add(moon(jd - h))).Scale(1 / (2 * h))
+		if d := s.V.Sub(wantV).Norm(); d > 1e-6*wantV.Norm() {
+			t.Errorf("JD %v: v = %v, want %v", jd, s.V, wantV)
+		}
+		r, err := eph.Position(BodyMoon, jd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != s.R {
+			t.Errorf("JD %v: Position %v differs from State %v", jd, r, s.R)
+		}
+	}
+
+	for _, jd := range []float64{start - 1, end + 1, math.NaN()} {
+		if _, err := eph.State(BodyJupiter, jd); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("JD %v: err = %v, want ErrOutOfRange", jd, err)
+		}
+	}
+	if _, err := eph.State(BodySaturn, start); err == nil {
+		t.Error("missing body: want error")
+	}
+}
+
+func TestReadChebyshevRejectsCorruptFiles(t *testing.T) {
+	var buf bytes.Buffer
+	s := Fit(BodyMars, BodySun, 0, 100, 50, 4, circular(1.5, 687))
+	if err := WriteChebyshev(&buf, 0, 100, []Series{s}); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+
+	badMagic := bytes.Clone(good)
+	badMagic[0] = 'X'
+	orphan := new(bytes.Buffer)
+	if err := WriteChebyshev(orphan, 0, 100, []Series{Fit(BodyMoon, BodyEarth, 0, 100, 50, 4, circular(0.0026, 27))}); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"bad magic":     badMagic,
+		"truncated":     good[:len(good)-4],
+		"orphan center": orphan.Bytes(),
+		"header only":   good[:20],
+	} {
+		if _, err := ReadChebyshev(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+// Встроенная эфемерида построена по аналитическим теориям и должна воспроизводить их
+// в пределах ошибки чебышёвской аппроксимации.
+func TestDefaultMatchesAnalytic(t *testing.T) {
+	eph, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := eph.Span()
+	for jd := start; jd <= end; jd += 997.3 {
+		for _, b := range Perturbers {
+			got, err := eph.Position(b, jd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := Analytic{}.Position(b, jd)
+			if d := got.Sub(want).Norm(); d > 5e-6 {
+				t.Errorf("%s at JD %.1f: off by %.2e AU", b, jd, d)
+			}
+		}
+	}
+}
+
+func TestDefaultSourceFallsBackOutsideSpan(t *testing.T) {
+	src := DefaultSource()
+	if _, ok := src.(Bounded); ok {
+		t.Error("default source must not limit the integration span")
+	}
+	eph, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, end := eph.Span()
+	jd := end + 3650
+	got, err := src.State(BodyJupiter, jd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Analytic{}.State(BodyJupiter, jd)
+	if got != want {
+		t.Errorf("state = %v, want analytic %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+// Package planets вычисляет гелиоцентрические положения планет,
+// необходимые для расчёта орбит и сближений.
+package planets
+
+import (
+	"math"
+
+	"backend-server/internal/app/astrotime"
+	"backend-server/internal/app/orbit"
+)
+
+// Earth возвращает гелиоцентрическое положение Земли (а.е.) в экваториальной
+// системе J2000 на юлианскую дату jd (TT). Точность — порядка 1″ (около 5e-6 а.е.).
+func Earth(jd float64) orbit.Vec3 {
+	l, b, r := earthLBR(jd)
+
+	// поправка перехода от динамической эклиптики VSOP87 к FK5 (Meeus, 32.3)
+	t := astrotime.CenturiesSinceJ2000(jd)
+	lp := l - (1.397*t+0.00031*t*t)*orbit.Deg
+	l += (-0.09033 + 0.03916*(math.Cos(lp)+math.Sin(lp))*math.Tan(b)) * orbit.ArcSec
+	b += 0.03916 * (math.Cos(lp) - math.Sin(lp)) * orbit.ArcSec
+
+	// средняя эклиптика даты → средний экватор даты → экватор J2000
+	sb, cb := math.Sincos(b)
+	sl, cl := math.Sincos(l)
+	ecl := orbit.Vec3{r * cb * cl, r * cb * sl, r * sb}
+	equ := orbit.RotX(-orbit.MeanObliquity(t)).Apply(ecl)
+	return orbit.PrecessionMatrix(t).Transpose().Apply(equ)
+}
+
+// earthLBR возвращает гелиоцентрические долготу, широту (рад) и расстояние (а.е.)
+// Земли относительно средней эклиптики и равноденствия даты по рядам VSOP87D.
+func earthLBR(jd float64) (l, b, r float64) {
+	tau := (jd - astrotime.J2000) / 365250
+	l = vsopSum(tau, earthL0, earthL1, earthL2, earthL3, earthL4, earthL5)
+	b = vsopSum(tau, earthB0, earthB1)
+	r = vsopSum(tau, earthR0, earthR1, earthR2, earthR3, earthR4)
+	return orbit.NormalizeAngle(l), b, r
+}
+
+// vsopSum вычисляет Σ τⁿ·Σ A·cos(B + C·τ) по сериям степеней n = 0, 1, ….
+func vsopSum(tau float64, series ...[][3]float64) float64 {
+	total, power := 0.0, 1.0
+	for _, terms := range series {
+		sum := 0.0
+		for _, term := range terms {
+			sum += term[0] * math.Cos(term[1]+term[2]*tau)
+		}
+		total += sum * power
+		power *= tau
+	}
+	return total * 1e-8
+}
@@ -0,0 +1,29 @@
+package planets
+
+import (
+	"bytes"
+	_ "embed"
+	"sync"
+)
+
+// embeddedEphemeris — встроенная эфемерида Меркурия — Нептуна и Луны на 1950–2150 годы.
+// Файл строится командой cmd/ephemgen из эфемериды JPL: go run ./cmd/ephemgen -de de440s.bsp.
+// Без файла JPL команда берёт положения из аналитических теорий (Analytic), и ряды
+// наследуют их точность.
+//
+//go:embed data/planets.cheb
+var embeddedEphemeris []byte
+
+var (
+	defaultOnce      sync.Once
+	defaultEphemeris *Chebyshev
+	defaultErr       error
+)
+
+// Default возвращает встроенную эфемериду. Файл разбирается при первом вызове.
+func Default() (*Chebyshev, error) {
+	defaultOnce.Do(func() {
+		defaultEphemeris, defaultErr = ReadChebyshev(bytes.NewReader(embeddedEphemeris))
+	})
+	return defaultEphemeris, defaultErr
+}
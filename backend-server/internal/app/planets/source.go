@@ -0,0 +1,76 @@
+package planets
+
+import "backend-server/internal/app/orbit"
+
+// Source — источник гелиоцентрических состояний тел в экваториальной системе J2000:
+// встроенная эфемерида, эфемерида JPL или аналитические теории.
+type Source interface {
+	// State возвращает положение (а.е.) и скорость (а.е./сут) тела b на момент jd (TT).
+	State(b Body, jd float64) (orbit.State, error)
+}
+
+// Bounded реализуют источники с ограниченным охватом, например эфемерида JPL.
+// За пределами охвата State возвращает ошибку.
+type Bounded interface {
+	// Span возвращает охват источника, JD (TT).
+	Span() (start, end float64)
+}
+
+// DefaultSource возвращает источник состояний, не требующий файла эфемериды JPL:
+// встроенную чебышёвскую эфемериду (Default), а за пределами её охвата — аналитические
+// теории (Analytic). Если встроенный файл не разбирается, остаются только они.
+func DefaultSource() Source {
+	eph, err := Default()
+	if err != nil {
+		return Analytic{}
+	}
+	return embedded{eph}
+}
+
+// embedded — встроенная эфемерида с аналитическими теориями за пределами её охвата.
+type embedded struct{ eph *Chebyshev }
+
+func (e embedded) State(b Body, jd float64) (orbit.State, error) {
+	if !e.covers(jd) {
+		return Analytic{}.State(b, jd)
+	}
+	return e.eph.State(b, jd)
+}
+
+// Position возвращает гелиоцентрическое положение тела (а.е.) в экваториальной системе J2000:
+// интегратор nbody запрашивает на каждом шаге только положения.
+func (e embedded) Position(b Body, jd float64) (orbit.Vec3, error) {
+	if !e.covers(jd) {
+		return Analytic{}.Position(b, jd)
+	}
+	return e.eph.Position(b, jd)
+}
+
+func (e embedded) covers(jd float64) bool {
+	start, end := e.eph.Span()
+	return jd >= start && jd <= end
+}
+
+// Osculating возвращает оскулирующие гелиоцентрические элементы орбиты тела b относительно
+// эклиптики J2000 на момент jd (TT) по источнику src. Для Земли берётся орбита барицентра
+// Земля — Луна: орбита самой Земли колеблется с периодом месяца, а большая полуось — на 1e-3 а.е.
+func Osculating(src Source, b Body, jd float64) (orbit.Elements, error) {
+	s, err := src.State(b, jd)
+	if err != nil {
+		return orbit.Elements{}, err
+	}
+	mu := orbit.MuSun + b.GM()
+	if b == BodyEarth {
+		moon, err := src.State(BodyMoon, jd)
+		if err != nil {
+			return orbit.Elements{}, err
+		}
+		fm := BodyMoon.GM() / (BodyEarth.GM() + BodyMoon.GM())
+		s = orbit.State{
+			R: s.R.Add(moon.R.Sub(s.R).Scale(fm)),
+			V: s.V.Add(moon.V.Sub(s.V).Scale(fm)),
+		}
+		mu += BodyMoon.GM()
+	}
+	return orbit.ElementsFromState(s.ToEcliptic(), jd, mu), nil
+}
@@ -0,0 +1,264 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	orbitJobPrefix      = "orbit-job."
+	orbitJobLeasePrefix = "orbit-job-lease."
+	orbitJobEventPrefix = "orbit-job-events."
+	orbitQueueKey       = servicePrefix + "orbit-jobs.queue"
+	orbitProcessingKey  = servicePrefix + "orbit-jobs.processing"
+
+	// orbitJobTTL — сколько хранится задача (и её результат) после последнего изменения
+	orbitJobTTL = 7 * 24 * time.Hour
+)
+
+// ErrOrbitJobNotFound возвращается, если задачи нет (не создавалась или истёк TTL).
+var ErrOrbitJobNotFound = errors.New("orbit job not found")
+
+// OrbitJobStatus — состояние задачи расчёта орбиты.
+type OrbitJobStatus string
+
+const (
+	OrbitJobQueued    OrbitJobStatus = "queued"
+	OrbitJobRunning   OrbitJobStatus = "running"
+	OrbitJobSucceeded OrbitJobStatus = "succeeded"
+	OrbitJobFailed    OrbitJobStatus = "failed"
+)
+
+// OrbitJob — задача асинхронного расчёта орбиты.
+// Payload и Result хранятся как JSON, их формат определяет обработчик.
+type OrbitJob struct {
+	ID          string          `json:"id"`
+	Status      OrbitJobStatus  `json:"status"`
+	OwnerID     *uint           `json:"owner_id"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Payload     json.RawMessage `json:"payload"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	CometID     uint            `json:"comet_id,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+func getOrbitJobKey(id string) string {
+	return servicePrefix + orbitJobPrefix + id
+}
+
+func getOrbitJobLeaseKey(id string) string {
+	return servicePrefix + orbitJobLeasePrefix + id
+}
+
+func getOrbitJobEventChannel(id string) string {
+	return servicePrefix + orbitJobEventPrefix + id
+}
+
+// EnqueueOrbitJob сохраняет задачу и ставит её в очередь.
+func (c *Client) EnqueueOrbitJob(ctx context.Context, job *OrbitJob) error {
+	now := time.Now().UTC()
+	job.Status = OrbitJobQueued
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal orbit job: %w", err)
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, getOrbitJobKey(job.ID), b, orbitJobTTL)
+		pipe.LPush(ctx, orbitQueueKey, job.ID)
+		return nil
+	})
+	return err
+}
+
+// GetOrbitJob читает задачу по ID.
+func (c *Client) GetOrbitJob(ctx context.Context, id string) (*OrbitJob, error) {
+	b, err := c.client.Get(ctx, getOrbitJobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrOrbitJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job OrbitJob
+	if err := json.Unmarshal(b, &job); err != nil {
+		return nil, fmt.Errorf("unmarshal orbit job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// SaveOrbitJob перезаписывает задачу, обновляя UpdatedAt и TTL.
+func (c *Client) SaveOrbitJob(ctx context.Context, job *OrbitJob) error {
+	job.UpdatedAt = time.Now().UTC()
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal orbit job: %w", err)
+	}
+	return c.client.Set(ctx, getOrbitJobKey(job.ID), b, orbitJobTTL).Err()
+}
+
+// claimOrbitJobScript переносит задачу из очереди в список обрабатываемых и в той же
+// операции берёт на неё аренду: RecoverOrbitJobs не увидит задачу в обработке без аренды.
+// KEYS: очередь, список обрабатываемых; ARGV: префикс ключа аренды, аренда в мс.
+var claimOrbitJobScript = redis.NewScript(`
+local id = redis.call('LMOVE', KEYS[1], KEYS[2], 'RIGHT', 'LEFT')
+if not id then
+	return false
+end
+redis.call('SET', ARGV[1] .. id, '1', 'PX', ARGV[2])
+return id
+`)
+
+// ClaimOrbitJob ждёт до timeout следующую задачу из очереди, переносит её в список
+// обрабатываемых и берёт аренду (lease) на неё. Если очередь пуста, возвращает nil, nil.
+// Пока задача выполняется, аренду нужно продлевать через ExtendOrbitJobLease.
+func (c *Client) ClaimOrbitJob(ctx context.Context, timeout, lease time.Duration) (*OrbitJob, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		id, err := claimOrbitJobScript.Run(ctx, c.client,
+			[]string{orbitQueueKey, orbitProcessingKey},
+			servicePrefix+orbitJobLeasePrefix, lease.Milliseconds()).Text()
+		if err == nil {
+			job, err := c.GetOrbitJob(ctx, id)
+			if errors.Is(err, ErrOrbitJobNotFound) {
+				// задача истекла, пока стояла в очереди
+				return nil, c.FinishOrbitJob(ctx, id)
+			}
+			return job, err
+		}
+		if !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		// Очередь пуста: ждём появления задачи, не забирая её (перенос в ту же позицию),
+		// и пробуем снова — задачу может раньше забрать другой воркер
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil
+		}
+		_, err = c.client.BLMove(ctx, orbitQueueKey, orbitQueueKey, "RIGHT", "RIGHT", remaining).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ExtendOrbitJobLease продлевает аренду выполняемой задачи.
+func (c *Client) ExtendOrbitJobLease(ctx context.Context, id string, lease time.Duration) error {
+	return c.client.Set(ctx, getOrbitJobLeaseKey(id), true, lease).Err()
+}
+
+// FinishOrbitJob убирает задачу из списка обрабатываемых и снимает аренду.
+func (c *Client) FinishOrbitJob(ctx context.Context, id string) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, orbitProcessingKey, 0, id)
+		pipe.Del(ctx, getOrbitJobLeaseKey(id))
+		return nil
+	})
+	return err
+}
+
+// RequeueOrbitJob сохраняет задачу со статусом queued и возвращает её в очередь.
+func (c *Client) RequeueOrbitJob(ctx context.Context, job *OrbitJob) error {
+	job.Status = OrbitJobQueued
+	job.UpdatedAt = time.Now().UTC()
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal orbit job: %w", err)
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, getOrbitJobKey(job.ID), b, orbitJobTTL)
+		pipe.LRem(ctx, orbitProcessingKey, 0, job.ID)
+		pipe.Del(ctx, getOrbitJobLeaseKey(job.ID))
+		pipe.LPush(ctx, orbitQueueKey, job.ID)
+		return nil
+	})
+	return err
+}
+
+// RecoverOrbitJobs возвращает в очередь задачи, чья аренда истекла:
+// их обработчик упал или бэкенд был перезапущен посреди расчёта.
+// Возвращает количество возвращённых задач.
+func (c *Client) RecoverOrbitJobs(ctx context.Context) (int, error) {
+	ids, err := c.client.LRange(ctx, orbitProcessingKey, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, id := range ids {
+		n, err := c.client.Exists(ctx, getOrbitJobLeaseKey(id)).Result()
+		if err != nil {
+			return recovered, err
+		}
+		if n > 0 {
+			continue
+		}
+
+		job, err := c.GetOrbitJob(ctx, id)
+		if errors.Is(err, ErrOrbitJobNotFound) {
+			if err := c.FinishOrbitJob(ctx, id); err != nil {
+				return recovered, err
+			}
+			continue
+		}
+		if err != nil {
+			return recovered, err
+		}
+
+		if err := c.RequeueOrbitJob(ctx, job); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+// PublishOrbitJobEvent рассылает событие задачи всем подписчикам (на любой реплике бэкенда).
+func (c *Client) PublishOrbitJobEvent(ctx context.Context, id string, event []byte) error {
+	return c.client.Publish(ctx, getOrbitJobEventChannel(id), event).Err()
+}
+
+// SubscribeOrbitJobEvents подписывается на события задачи. Подписка активна к моменту
+// возврата, поэтому состояние задачи, прочитанное после вызова, не пропустит событий.
+// Возвращает канал событий и функцию отписки, которая закрывает канал.
+func (c *Client) SubscribeOrbitJobEvents(ctx context.Context, id string) (<-chan []byte, func() error, error) {
+	ps := c.client.Subscribe(ctx, getOrbitJobEventChannel(id))
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, nil, err
+	}
+
+	events := make(chan []byte)
+	go func() {
+		defer close(events)
+		for msg := range ps.Channel() {
+			select {
+			case events <- []byte(msg.Payload):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, ps.Close, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"strings"
+
+	"backend-server/internal/app/ds"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Поля, по которым можно сортировать каталог комет.
+const (
+	SortByPerihelion      = "perihelion"
+	SortByEccentricity    = "eccentricity"
+	SortByClosestApproach = "closest_approach"
+)
+
+// closestApproachExpr возвращает минимальное расстояние сближения кометы с Землей.
+const closestApproachExpr = "(SELECT MIN(ca.distance_au) FROM close_approaches ca WHERE ca.comet_id = comets.id AND ca.body = 'earth' AND ca.deleted_at IS NULL)"
+
+// CometFilter описывает параметры выборки каталога комет.
+type CometFilter struct {
+	Name    string // подстрока имени (без учёта регистра)
+	OwnerID *uint  // только кометы указанного владельца
+	// MOIDLessThan — только кометы с MOID относительно Земли меньше заданного (AU);
+	// кометы без рассчитанного MOID не попадают в выборку
+	MOIDLessThan *float64
+	SortBy       string // одно из SortBy*; пустое значение — по дате создания
+	Desc         bool   // сортировка по убыванию
+	Limit        int
+	Offset       int
+}
+
+// ListComets возвращает страницу каталога комет и общее число комет, подходящих под фильтр.
+// Сближения подгружаются вместе с кометами, наблюдения — нет.
+func (r *Repository) ListComets(filter CometFilter) ([]ds.Comet, int64, error) {
+	query := r.db.Model(&ds.Comet{})
+	if name := strings.TrimSpace(filter.Name); name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
+	if filter.OwnerID != nil {
+		query = query.Where("owner_id = ?", *filter.OwnerID)
+	}
+	if filter.MOIDLessThan != nil {
+		query = query.Where("earth_moid < ?", *filter.MOIDLessThan)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var column string
+	switch filter.SortBy {
+	case SortByPerihelion:
+		column = "t"
+	case SortByEccentricity:
+		column = "e"
+	case SortByClosestApproach:
+		column = closestApproachExpr
+	default:
+		column = "created_at"
+	}
+	order := column + " ASC NULLS LAST"
+	if filter.Desc {
+		order = column + " DESC NULLS LAST"
+	}
+
+	var comets []ds.Comet
+	err := query.
+		Preload("CloseApproaches").
+		Order(order).
+		Order("id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&comets).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comets, total, nil
+}
+
+// GetComet возвращает комету по ID вместе с наблюдениями и сближениями.
+func (r *Repository) GetComet(id uint) (*ds.Comet, error) {
+	var comet ds.Comet
+	err := r.db.
+		Preload("Observations", func(db *gorm.DB) *gorm.DB { return db.Order("observed_at") }).
+		Preload("CloseApproaches", func(db *gorm.DB) *gorm.DB { return db.Order("closest_date") }).
+		First(&comet, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comet, nil
+}
+
+// GetCometByOrbitJob возвращает комету, созданную асинхронной задачей jobID, вместе с
+// наблюдениями и сближениями. Если такой нет, возвращает gorm.ErrRecordNotFound.
+func (r *Repository) GetCometByOrbitJob(jobID string) (*ds.Comet, error) {
+	var comet ds.Comet
+	if err := r.db.Select("id").Where("orbit_job_id = ?", jobID).First(&comet).Error; err != nil {
+		return nil, err
+	}
+	return r.GetComet(comet.ID)
+}
+
+// UpdateComet сохраняет изменённые поля кометы без затрагивания связанных записей.
+func (r *Repository) UpdateComet(comet *ds.Comet) error {
+	return r.db.Omit(clause.Associations).Save(comet).Error
+}
+
+// UpdateCometElements сохраняет комету с заданными вручную элементами: сбрасывает невязки
+// и флаги отбраковки её наблюдений и заменяет прежние сближения новыми approaches.
+func (r *Repository) UpdateCometElements(comet *ds.Comet, approaches []ds.CloseApproach) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(comet).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&ds.Observation{}).Where("comet_id = ?", comet.ID).
+			Updates(map[string]interface{}{"residual_ra": nil, "residual_dec": nil, "rejected": false}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("comet_id = ?", comet.ID).Delete(&ds.CloseApproach{}).Error; err != nil {
+			return err
+		}
+		for i := range approaches {
+			approaches[i].CometID = comet.ID
+		}
+		if len(approaches) > 0 {
+			return tx.Create(&approaches).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	comet.CloseApproaches = approaches
+	return nil
+}
+
+// DeleteComet мягко удаляет комету вместе с её наблюдениями и сближениями (через gorm.DeletedAt).
+func (r *Repository) DeleteComet(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comet_id = ?", id).Delete(&ds.Observation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comet_id = ?", id).Delete(&ds.CloseApproach{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ds.Comet{}, id).Error
+	})
+}
@@ -6,32 +6,93 @@ import (
 	"mime/multipart"
 	"path/filepath"
 	"strings"
-	"time"
 	"unicode"
 
 	"backend-server/internal/app/ds"
 
 	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
 )
 
-// CreateComet создает запись кометы с указанным именем.
-// Инициализирует обязательные поля дефолтными значениями (времена = now).
-func (r *Repository) CreateComet(name string) (*ds.Comet, error) {
-	now := time.Now()
-	comet := &ds.Comet{
-		Name:    name,
-		Epoch:   now,
-		A:       0.0,
-		E:       0.0,
-		I:       0.0,
-		Node:    0.0,
-		ArgPeri: 0.0,
-		T:       now,
-	}
-	if err := r.db.Create(comet).Error; err != nil {
-		return nil, err
-	}
-	return comet, nil
+// orbitColumns — поля кометы, которые заполняет расчёт орбиты.
+var orbitColumns = []string{
+	"Epoch", "Q", "A", "E", "I", "Node", "ArgPeri", "T",
+	"RMS", "Chi2", "Iterations", "Converged",
+	"SigmaQ", "SigmaE", "SigmaI", "SigmaNode", "SigmaArgPeri", "SigmaT", "Covariance",
+	"EarthMOID", "JupiterMOID",
+}
+
+// fitColumns — поля наблюдения, которые заполняет расчёт орбиты.
+var fitColumns = []string{"ResidualRA", "ResidualDec", "Rejected", "Force"}
+
+// CreateCometWithOrbit сохраняет комету с рассчитанной орбитой вместе с её наблюдениями
+// и сближениями в одной короткой транзакции. Орбита и сближения рассчитываются заранее,
+// до обращения к базе, чтобы транзакция не держала соединение на время расчёта.
+// OwnerID кометы проставляется всем её наблюдениям.
+func (r *Repository) CreateCometWithOrbit(comet *ds.Comet, approaches []ds.CloseApproach) error {
+	for i := range comet.Observations {
+		comet.Observations[i].OwnerID = comet.OwnerID
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// наблюдения создаются вместе с кометой через связь Observations
+		if err := tx.Create(comet).Error; err != nil {
+			return err
+		}
+
+		for i := range approaches {
+			approaches[i].CometID = comet.ID
+		}
+		if len(approaches) > 0 {
+			if err := tx.Create(&approaches).Error; err != nil {
+				return err
+			}
+		}
+		comet.CloseApproaches = approaches
+
+		return nil
+	})
+}
+
+// UpdateCometOrbit сохраняет пересчитанную орбиту кометы: элементы, невязки и флаги
+// наблюдений и MOID, а прежние сближения заменяет новыми.
+func (r *Repository) UpdateCometOrbit(comet *ds.Comet, approaches []ds.CloseApproach) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comet_id = ?", comet.ID).Delete(&ds.CloseApproach{}).Error; err != nil {
+			return err
+		}
+		return saveOrbit(tx, comet, approaches)
+	})
+	if err != nil {
+		return err
+	}
+
+	comet.CloseApproaches = approaches
+	return nil
+}
+
+// saveOrbit записывает в транзакции tx элементы орбиты кометы, результаты подгонки
+// её наблюдений и сближения approaches.
+func saveOrbit(tx *gorm.DB, comet *ds.Comet, approaches []ds.CloseApproach) error {
+	if err := tx.Model(comet).Select(orbitColumns).Updates(comet).Error; err != nil {
+		return err
+	}
+
+	for i := range comet.Observations {
+		if err := tx.Model(&comet.Observations[i]).Select(fitColumns).Updates(&comet.Observations[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	for i := range approaches {
+		approaches[i].CometID = comet.ID
+	}
+	if len(approaches) > 0 {
+		if err := tx.Create(&approaches).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateCometImageURL обновляет image_url у кометы.
@@ -53,25 +114,17 @@ func (r *Repository) UploadCometImage(id uint, fileHeader *multipart.FileHeader)
 		_ = r.minioClient.RemoveObject(context.Background(), r.bucketName, objectName, minio.RemoveObjectOptions{})
 	}
 
-	file, err := fileHeader.Open()
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
 	ext := filepath.Ext(fileHeader.Filename)
 	base := strings.TrimSuffix(fileHeader.Filename, ext)
 	latinBase := toLatin(base)
 
 	objectName := fmt.Sprintf("comet-%s%s", latinBase, ext)
 
-	_, err = r.minioClient.PutObject(context.Background(), r.bucketName, objectName, file, fileHeader.Size, minio.PutObjectOptions{ContentType: fileHeader.Header.Get("Content-Type")})
+	imageURL, err := r.putObject(objectName, fileHeader)
 	if err != nil {
 		return "", err
 	}
 
-	imageURL := fmt.Sprintf("http://%s/%s/%s", r.minioClient.EndpointURL().Host, r.bucketName, objectName)
-
 	if err := r.db.Model(&ds.Comet{}).Where("id = ?", id).Update("image_url", imageURL).Error; err != nil {
 		return "", err
 	}
@@ -79,6 +132,53 @@ func (r *Repository) UploadCometImage(id uint, fileHeader *multipart.FileHeader)
 	return imageURL, nil
 }
 
+// AddObservations сохраняет новые наблюдения существующей кометы.
+func (r *Repository) AddObservations(observations []ds.Observation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+	return r.db.Create(&observations).Error
+}
+
+// UploadObservationPhoto загружает фото наблюдения в Minio и обновляет photo_url наблюдения.
+func (r *Repository) UploadObservationPhoto(id uint, fileHeader *multipart.FileHeader) (string, error) {
+	objectName := fmt.Sprintf("observation-%d%s", id, filepath.Ext(fileHeader.Filename))
+
+	photoURL, err := r.putObject(objectName, fileHeader)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.db.Model(&ds.Observation{}).Where("id = ?", id).Update("photo_url", photoURL).Error; err != nil {
+		return "", err
+	}
+
+	return photoURL, nil
+}
+
+// UploadJobFile загружает файл, приложенный к асинхронной задаче расчёта, до того как
+// будут созданы комета и наблюдения. Возвращает ссылку, которую задача сохранит в записи.
+func (r *Repository) UploadJobFile(jobID, name string, fileHeader *multipart.FileHeader) (string, error) {
+	objectName := fmt.Sprintf("job-%s-%s%s", jobID, name, filepath.Ext(fileHeader.Filename))
+	return r.putObject(objectName, fileHeader)
+}
+
+// putObject загружает файл в бакет под именем objectName и возвращает публичную ссылку.
+func (r *Repository) putObject(objectName string, fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = r.minioClient.PutObject(context.Background(), r.bucketName, objectName, file, fileHeader.Size, minio.PutObjectOptions{ContentType: fileHeader.Header.Get("Content-Type")})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("http://%s/%s/%s", r.minioClient.EndpointURL().Host, r.bucketName, objectName), nil
+}
+
 // helper toLatin
 func toLatin(s string) string {
 	var out strings.Builder
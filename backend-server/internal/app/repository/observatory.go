@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"strings"
+
+	"backend-server/internal/app/ds"
+)
+
+// ListObservatories возвращает страницу справочника обсерваторий и их общее число.
+// search ищет по точному коду или подстроке названия (без учёта регистра).
+func (r *Repository) ListObservatories(search string, limit, offset int) ([]ds.Observatory, int64, error) {
+	query := r.db.Model(&ds.Observatory{})
+	if search = strings.TrimSpace(search); search != "" {
+		query = query.Where("UPPER(code) = UPPER(?) OR name ILIKE ?", search, "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var observatories []ds.Observatory
+	err := query.Order("code").Limit(limit).Offset(offset).Find(&observatories).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return observatories, total, nil
+}
+
+// GetObservatories возвращает обсерватории с указанными кодами; отсутствующие коды пропускаются.
+func (r *Repository) GetObservatories(codes []string) (map[string]ds.Observatory, error) {
+	out := make(map[string]ds.Observatory, len(codes))
+	if len(codes) == 0 {
+		return out, nil
+	}
+
+	var observatories []ds.Observatory
+	if err := r.db.Where("code IN ?", codes).Find(&observatories).Error; err != nil {
+		return nil, err
+	}
+	for _, o := range observatories {
+		out[o.Code] = o
+	}
+	return out, nil
+}
@@ -0,0 +1,322 @@
+// Package risk оценивает неопределённость сближения кометы с Землёй методом
+// Монте-Карло: из ковариации элементов орбиты выбираются виртуальные орбиты
+// (клоны), и для каждой ищется минимальное расстояние до Земли.
+package risk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+
+	"backend-server/internal/app/approach"
+	"backend-server/internal/app/nbody"
+	"backend-server/internal/app/orbit"
+	"backend-server/internal/app/planets"
+)
+
+// ErrNoCovariance возвращается, если ковариация не задана или не положительно определена.
+var ErrNoCovariance = errors.New("risk: covariance is missing or not positive definite")
+
+// Elements — элементы q, e, i, Ω, ω, T в единицах ковариации орбиты:
+// а.е., —, градусы, градусы, градусы, юлианская дата (TT).
+type Elements [6]float64
+
+// Options задаёт параметры оценки. Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	Clones      int         // число виртуальных орбит (1000)
+	ThresholdAU float64     // порог опасного сближения, а.е. (0.05)
+	Start       float64     // начало интервала поиска, JD (TT); 0 — момент перигелия номинальной орбиты, а если её период длиннее Years — за Years лет до него
+	Years       float64     // длительность интервала поиска, годы (5)
+	Workers     int         // размер пула горутин (число CPU)
+	Seed        uint64      // зерно генератора: одинаковое зерно даёт одинаковый результат
+	Model       nbody.Model // модель движения (задача двух тел); в модели n_body клоны считаются заметно дольше
+	// Planets — источник положений Земли и возмущающих тел (planets.DefaultSource).
+	Planets planets.Source
+}
+
+func (o Options) withDefaults(nominal Elements) Options {
+	if o.Clones <= 0 {
+		o.Clones = 1000
+	}
+	if o.ThresholdAU <= 0 {
+		o.ThresholdAU = 0.05
+	}
+	if o.Years <= 0 {
+		o.Years = 5
+	}
+	if o.Start == 0 {
+		o.Start = nominal[5]
+		// незамкнутая или долгопериодическая орбита проходит мимо Земли один раз —
+		// сближение может быть и до перигелия
+		el := orbit.ElementsFromDegrees(nominal[0], nominal[1], nominal[2], nominal[3], nominal[4], nominal[5])
+		if el.Period(orbit.MuSun) > o.Years*365.25 {
+			o.Start -= o.Years * 365.25
+			o.Years *= 2
+		}
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+	if o.Planets == nil {
+		o.Planets = planets.DefaultSource()
+	}
+	return o
+}
+
+// Approach — минимальное сближение одной орбиты с Землёй.
+type Approach struct {
+	JD         float64 // момент, JD (TT)
+	DistanceAU float64 // расстояние, а.е.
+}
+
+// Stats — выборочная статистика величины.
+type Stats struct {
+	Min, Max     float64
+	Mean, StdDev float64
+	P05, P50     float64
+	P95          float64
+}
+
+// Result — итог оценки.
+type Result struct {
+	Nominal  Approach // сближение номинальной орбиты
+	Clones   int      // число клонов с корректными элементами
+	Invalid  int      // отброшенные клоны (элементы не задают орбиту, см. orbit.ErrInvalidElements)
+	Within   int      // клоны, прошедшие ближе ThresholdAU
+	Fraction float64  // доля таких клонов среди корректных
+	Distance Stats    // распределение минимального расстояния, а.е.
+	Time     Stats    // распределение момента сближения, JD (TT)
+}
+
+// Assess выбирает opts.Clones орбит из нормального распределения со средним nominal
+// и ковариацией cov и для каждой ищет минимальное расстояние до Земли на интервале.
+// Клоны считаются в пуле из opts.Workers горутин; отмена ctx прерывает расчёт.
+func Assess(ctx context.Context, nominal Elements, cov [6][6]float64, opts Options) (*Result, error) {
+	opts = opts.withDefaults(nominal)
+
+	chol, err := cholesky(cov)
+	if err != nil {
+		return nil, err
+	}
+
+	// узлы сетки поиска у всех клонов одни и те же — положения Земли в них считаются один раз
+	earth := newCachedTarget(approach.BodyTarget(opts.Planets, planets.BodyEarth))
+
+	nominalApproach, err := closestApproach(nominal, earth.At, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// клоны выбираются заранее, чтобы результат не зависел от порядка работы горутин
+	clones := sampleClones(nominal, chol, opts.Clones, opts.Seed)
+
+	approaches := make([]Approach, len(clones))
+	valid := make([]bool, len(clones))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.Workers, len(clones)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				a, err := closestApproach(clones[k], earth.At, opts)
+				if err == nil {
+					approaches[k], valid[k] = a, true
+				}
+			}
+		}()
+	}
+
+feed:
+	for k := range clones {
+		select {
+		case jobs <- k:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Nominal: nominalApproach}
+	var dist, jd []float64
+	for k, a := range approaches {
+		if !valid[k] {
+			res.Invalid++
+			continue
+		}
+		dist = append(dist, a.DistanceAU)
+		jd = append(jd, a.JD)
+		if a.DistanceAU < opts.ThresholdAU {
+			res.Within++
+		}
+	}
+	res.Clones = len(dist)
+	if res.Clones > 0 {
+		res.Fraction = float64(res.Within) / float64(res.Clones)
+		res.Distance = stats(dist)
+		res.Time = stats(jd)
+	}
+	return res, nil
+}
+
+// sampleClones выбирает n элементов из нормального распределения со средним nominal
+// и ковариацией chol·cholᵀ генератором с зерном seed.
+func sampleClones(nominal Elements, chol [6][6]float64, n int, seed uint64) []Elements {
+	rng := rand.New(rand.NewPCG(seed, 0x9e3779b97f4a7c15))
+	clones := make([]Elements, n)
+	for k := range clones {
+		var z [6]float64
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		for i := range 6 {
+			clones[k][i] = nominal[i]
+			for j := 0; j <= i; j++ {
+				clones[k][i] += chol[i][j] * z[j]
+			}
+		}
+	}
+	return clones
+}
+
+// closestApproach ищет минимальное расстояние орбиты el до Земли earth на интервале поиска
+// (см. approach.Find).
+func closestApproach(el Elements, earth approach.Target, opts Options) (Approach, error) {
+	state, epoch, err := stateAtPerihelion(el)
+	if err != nil {
+		return Approach{}, err
+	}
+	propagator, err := nbody.NewPropagator(opts.Model, state, epoch, opts.Planets)
+	if err != nil {
+		return Approach{}, err
+	}
+	res, err := approach.Find(propagator, earth, opts.Start, opts.Start+opts.Years*365.25, approach.Options{})
+	if err != nil {
+		return Approach{}, err
+	}
+	return Approach{JD: res.Closest.JD, DistanceAU: res.Closest.DistanceAU}, nil
+}
+
+// cachedTarget запоминает состояния тела по моментам. Безопасен для одновременного
+// использования из нескольких горутин.
+type cachedTarget struct {
+	target approach.Target
+	mu     sync.RWMutex
+	states map[float64]orbit.State
+}
+
+func newCachedTarget(target approach.Target) *cachedTarget {
+	return &cachedTarget{target: target, states: make(map[float64]orbit.State)}
+}
+
+// At реализует approach.Target.
+func (c *cachedTarget) At(jd float64) (orbit.State, error) {
+	c.mu.RLock()
+	s, ok := c.states[jd]
+	c.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+	s, err := c.target(jd)
+	if err != nil {
+		return orbit.State{}, err
+	}
+	c.mu.Lock()
+	c.states[jd] = s
+	c.mu.Unlock()
+	return s, nil
+}
+
+// stateAtPerihelion переводит элементы в экваториальный вектор состояния на момент перигелия.
+func stateAtPerihelion(el Elements) (orbit.State, float64, error) {
+	elements := orbit.ElementsFromDegrees(el[0], el[1], el[2], el[3], el[4], el[5])
+	s, err := orbit.StateFromElements(elements, elements.Tp, orbit.MuSun)
+	if err != nil {
+		return orbit.State{}, 0, err
+	}
+	return s.ToEquatorial(), elements.Tp, nil
+}
+
+// cholesky возвращает нижнетреугольный множитель L ковариации (C = L·Lᵀ). Матрица,
+// которая из-за ошибок округления не вполне положительно определена, слегка
+// регуляризуется добавлением малой доли диагонали.
+func cholesky(cov [6][6]float64) ([6][6]float64, error) {
+	for _, jitter := range []float64{0, 1e-12, 1e-10, 1e-8} {
+		var l [6][6]float64
+		ok := true
+		for i := 0; i < 6 && ok; i++ {
+			for j := 0; j <= i; j++ {
+				sum := cov[i][j]
+				if i == j {
+					sum += jitter * cov[i][i]
+				}
+				for k := range j {
+					sum -= l[i][k] * l[j][k]
+				}
+				if i == j {
+					if sum <= 0 || math.IsNaN(sum) {
+						ok = false
+						break
+					}
+					l[i][i] = math.Sqrt(sum)
+				} else {
+					l[i][j] = sum / l[j][j]
+				}
+			}
+		}
+		if ok {
+			return l, nil
+		}
+	}
+	return [6][6]float64{}, ErrNoCovariance
+}
+
+// stats вычисляет статистику выборки; v переупорядочивается.
+func stats(v []float64) Stats {
+	sort.Float64s(v)
+	n := float64(len(v))
+
+	mean := 0.0
+	for _, x := range v {
+		mean += x
+	}
+	mean /= n
+
+	variance := 0.0
+	for _, x := range v {
+		variance += (x - mean) * (x - mean)
+	}
+	if len(v) > 1 {
+		variance /= n - 1
+	}
+
+	return Stats{
+		Min:    v[0],
+		Max:    v[len(v)-1],
+		Mean:   mean,
+		StdDev: math.Sqrt(variance),
+		P05:    percentile(v, 0.05),
+		P50:    percentile(v, 0.50),
+		P95:    percentile(v, 0.95),
+	}
+}
+
+// percentile возвращает квантиль p отсортированной выборки с линейной интерполяцией.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return sorted[i]*(1-frac) + sorted[i+1]*frac
+}
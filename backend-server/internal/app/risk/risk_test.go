@@ -0,0 +1,275 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// encke — элементы 2P/Encke, появление 2017 г. (JPL SBDB), в единицах Elements
+var encke = Elements{0.33588, 0.84833, 11.7807, 334.5682, 186.5455, 2457822.6}
+
+// diagonal возвращает диагональную ковариацию со стандартными отклонениями sigma.
+func diagonal(sigma Elements) [6][6]float64 {
+	var c [6][6]float64
+	for i, s := range sigma {
+		c[i][i] = s * s
+	}
+	return c
+}
+
+func TestCholesky(t *testing.T) {
+	correlated := diagonal(Elements{1e-6, 1e-7, 1e-4, 1e-4, 1e-4, 1e-3})
+	correlated[0][1], correlated[1][0] = 0.9e-13, 0.9e-13
+	correlated[2][5], correlated[5][2] = -0.5e-7, -0.5e-7
+
+	// ранг 1 с ошибками округления: положительно определена только после регуляризации
+	rankOne := [6][6]float64{}
+	v := Elements{1, 2, 3, 4, 5, 6}
+	for i := range 6 {
+		for j := range 6 {
+			rankOne[i][j] = v[i] * v[j]
+		}
+		rankOne[i][i] *= 1 - 1e-13
+	}
+
+	negative := diagonal(Elements{1, 1, 1, 1, 1, 1})
+	negative[3][3] = -1
+
+	tests := []struct {
+		name    string
+		cov     [6][6]float64
+		wantErr bool
+		tol     float64 // допустимая относительная невязка L·Lᵀ − C
+	}{
+		{"diagonal", diagonal(Elements{1e-5, 1e-6, 1e-3, 1e-3, 1e-3, 0.01}), false, 1e-12},
+		{"correlated", correlated, false, 1e-12},
+		{"rounding", rankOne, false, 1e-8},
+		{"zero", [6][6]float64{}, true, 0},
+		{"negative variance", negative, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := cholesky(tt.cov)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoCovariance) {
+					t.Fatalf("err = %v, want %v", err, ErrNoCovariance)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range 6 {
+				for j := range 6 {
+					if j > i && l[i][j] != 0 {
+						t.Errorf("L[%d][%d] = %g above the diagonal", i, j, l[i][j])
+					}
+					sum := 0.0
+					for k := range 6 {
+						sum += l[i][k] * l[j][k]
+					}
+					scale := math.Sqrt(tt.cov[i][i] * tt.cov[j][j])
+					if math.Abs(sum-tt.cov[i][j]) > tt.tol*scale {
+						t.Errorf("(L·Lᵀ)[%d][%d] = %g, want %g", i, j, sum, tt.cov[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+// Выборочные среднее и ковариация клонов сходятся к заданным.
+func TestSampleClones(t *testing.T) {
+	sigma := Elements{1e-5, 1e-6, 1e-3, 1e-3, 1e-3, 0.01}
+	corr := [6][6]float64{}
+	for i := range 6 {
+		corr[i][i] = 1
+	}
+	corr[0][1], corr[1][0] = 0.9, 0.9
+	corr[2][5], corr[5][2] = -0.6, -0.6
+	corr[3][4], corr[4][3] = 0.3, 0.3
+	var cov [6][6]float64
+	for i := range 6 {
+		for j := range 6 {
+			cov[i][j] = corr[i][j] * sigma[i] * sigma[j]
+		}
+	}
+	chol, err := cholesky(cov)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20000
+	clones := sampleClones(encke, chol, n, 3)
+	var mean Elements
+	for _, c := range clones {
+		for i := range 6 {
+			mean[i] += c[i] / n
+		}
+	}
+	for i := range 6 {
+		// стандартная ошибка среднего — σ/√n
+		if d := math.Abs(mean[i] - encke[i]); d > 4*sigma[i]/math.Sqrt(n) {
+			t.Errorf("mean[%d] is off by %.2fσ", i, d/sigma[i])
+		}
+	}
+	for i := range 6 {
+		for j := range 6 {
+			sum := 0.0
+			for _, c := range clones {
+				sum += (c[i] - mean[i]) * (c[j] - mean[j])
+			}
+			r := sum / (n - 1) / (sigma[i] * sigma[j])
+			if math.Abs(r-corr[i][j]) > 0.03 {
+				t.Errorf("sample correlation %d,%d = %.3f, want %.3f", i, j, r, corr[i][j])
+			}
+		}
+	}
+
+	if again := sampleClones(encke, chol, 10, 3); again[9] != clones[9] {
+		t.Errorf("same seed gave %v, then %v", clones[9], again[9])
+	}
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name string
+		v    []float64
+		want Stats
+	}{
+		{"single", []float64{2}, Stats{Min: 2, Max: 2, Mean: 2, P05: 2, P50: 2, P95: 2}},
+		{
+			"unsorted",
+			[]float64{5, 1, 4, 2, 3},
+			Stats{Min: 1, Max: 5, Mean: 3, StdDev: math.Sqrt(2.5), P05: 1.2, P50: 3, P95: 4.8},
+		},
+		{
+			"even",
+			[]float64{10, 0},
+			Stats{Min: 0, Max: 10, Mean: 5, StdDev: math.Sqrt(50), P05: 0.5, P50: 5, P95: 9.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stats(append([]float64(nil), tt.v...))
+			fields := []struct {
+				name      string
+				got, want float64
+			}{
+				{"Min", got.Min, tt.want.Min}, {"Max", got.Max, tt.want.Max},
+				{"Mean", got.Mean, tt.want.Mean}, {"StdDev", got.StdDev, tt.want.StdDev},
+				{"P05", got.P05, tt.want.P05}, {"P50", got.P50, tt.want.P50}, {"P95", got.P95, tt.want.P95},
+			}
+			for _, f := range fields {
+				if math.Abs(f.got-f.want) > 1e-12 {
+					t.Errorf("%s = %g, want %g", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssess(t *testing.T) {
+	ctx := context.Background()
+	tiny := diagonal(Elements{1e-9, 1e-10, 1e-7, 1e-7, 1e-7, 1e-6})
+	wide := diagonal(Elements{1e-3, 1e-4, 0.05, 0.05, 0.05, 0.5})
+
+	nominal, err := Assess(ctx, encke, tiny, Options{Clones: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d0 := nominal.Nominal.DistanceAU
+
+	tests := []struct {
+		name       string
+		cov        [6][6]float64
+		opts       Options
+		wantWithin func(r *Result) bool
+	}{
+		{
+			// клоны почти совпадают с номинальной орбитой и все проходят ближе порога чуть дальше неё
+			name:       "tiny covariance below threshold",
+			cov:        tiny,
+			opts:       Options{Clones: 40, ThresholdAU: d0 * 1.001, Seed: 1},
+			wantWithin: func(r *Result) bool { return r.Within == r.Clones && r.Fraction == 1 },
+		},
+		{
+			name:       "tiny covariance above threshold",
+			cov:        tiny,
+			opts:       Options{Clones: 40, ThresholdAU: d0 * 0.999, Seed: 1},
+			wantWithin: func(r *Result) bool { return r.Within == 0 && r.Fraction == 0 },
+		},
+		{
+			name: "wide covariance",
+			cov:  wide,
+			opts: Options{Clones: 40, ThresholdAU: d0, Seed: 1},
+			wantWithin: func(r *Result) bool {
+				return r.Within > 0 && r.Within < r.Clones && r.Fraction == float64(r.Within)/float64(r.Clones)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Assess(ctx, encke, tt.cov, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Clones+res.Invalid != tt.opts.Clones {
+				t.Errorf("clones %d + invalid %d, want %d", res.Clones, res.Invalid, tt.opts.Clones)
+			}
+			if res.Nominal != nominal.Nominal {
+				t.Errorf("nominal approach %+v, want %+v", res.Nominal, nominal.Nominal)
+			}
+			if !tt.wantWithin(res) {
+				t.Errorf("within = %d of %d (fraction %g)", res.Within, res.Clones, res.Fraction)
+			}
+			if d := res.Distance; !(d.Min <= d.P05 && d.P05 <= d.P50 && d.P50 <= d.P95 && d.P95 <= d.Max) {
+				t.Errorf("distance percentiles out of order: %+v", d)
+			}
+			if math.Abs(res.Distance.P50-d0) > 10*res.Distance.StdDev+1e-9 {
+				t.Errorf("median distance %g is far from the nominal %g", res.Distance.P50, d0)
+			}
+		})
+	}
+}
+
+// Результат определяется зерном и не зависит от числа горутин.
+func TestAssessDeterministic(t *testing.T) {
+	ctx := context.Background()
+	cov := diagonal(Elements{1e-3, 1e-4, 0.05, 0.05, 0.05, 0.5})
+
+	one, err := Assess(ctx, encke, cov, Options{Clones: 30, Seed: 7, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	many, err := Assess(ctx, encke, cov, Options{Clones: 30, Seed: 7, Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *one != *many {
+		t.Errorf("1 worker: %+v\n8 workers: %+v", one, many)
+	}
+
+	other, err := Assess(ctx, encke, cov, Options{Clones: 30, Seed: 8, Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Distance == one.Distance {
+		t.Error("different seeds gave the same distribution")
+	}
+}
+
+func TestAssessErrors(t *testing.T) {
+	if _, err := Assess(context.Background(), encke, [6][6]float64{}, Options{Clones: 1}); !errors.Is(err, ErrNoCovariance) {
+		t.Errorf("zero covariance: err = %v, want %v", err, ErrNoCovariance)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cov := diagonal(Elements{1e-3, 1e-4, 0.05, 0.05, 0.05, 0.5})
+	if _, err := Assess(ctx, encke, cov, Options{Clones: 100}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -44,9 +44,8 @@ const ObservationRow: FC<ObservationRowProps> = ({
       {/* Прямое восхождение */}
       <div className="flex-1 min-w-0 flex items-center bg-black rounded-md border border-gray-600 px-4 py-3 focus-within:border-blue-400 focus-within:shadow-sm transition">
         <input
-          type="number"
-          step="any"
-          placeholder="Прямое восхождение"
+          type="text"
+          placeholder="Прямое восхождение (187.5 или 12h30m00s)"
           value={observation.ra}
           onChange={(e) => onChange("ra", e.target.value)}
           className="flex-1 min-w-0 bg-black text-white placeholder-gray-400 focus:outline-none transition"
@@ -56,9 +55,8 @@ const ObservationRow: FC<ObservationRowProps> = ({
       {/* Склонение */}
       <div className="flex-1 min-w-0 flex items-center bg-black rounded-md border border-gray-600 px-4 py-3 focus-within:border-blue-400 focus-within:shadow-sm transition">
         <input
-          type="number"
-          step="any"
-          placeholder="Склонение"
+          type="text"
+          placeholder="Склонение (-12.5 или -12:30:00)"
           value={observation.dec}
           onChange={(e) => onChange("dec", e.target.value)}
           className="flex-1 min-w-0 bg-black text-white placeholder-gray-400 focus:outline-none transition"
@@ -69,7 +67,7 @@ const ObservationRow: FC<ObservationRowProps> = ({
       <div className="flex-1 min-w-0 flex items-center bg-black rounded-md border border-gray-600 px-4 py-3 focus-within:border-blue-400 focus-within:shadow-sm transition">
         <input
           type="text"
-          placeholder="Время измерения (ISO 8601)"
+          placeholder="Время (ISO 8601, JD или MJD)"
           value={observation.time}
           onChange={(e) => onChange("time", e.target.value)}
           className="flex-1 min-w-0 bg-black text-white placeholder-gray-400 focus:outline-none transition"
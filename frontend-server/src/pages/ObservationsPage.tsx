@@ -17,6 +17,92 @@ type Observation = {
 
 const MIN_OBSERVATIONS = 5;
 
+type ProgressEvent = {
+  stage: string;
+  iteration?: number;
+  rms?: number;
+  fraction?: number;
+  message?: string;
+};
+
+type OrbitJob = {
+  id: string;
+  status: "queued" | "running" | "succeeded" | "failed";
+  result?: unknown;
+  error?: string;
+};
+
+// Текст состояния расчёта для кнопки
+const describeProgress = (job: OrbitJob | null, progress: ProgressEvent | null): string => {
+  if (!job || job.status === "queued") return "В очереди...";
+  switch (progress?.stage) {
+    case "validation":
+      return "Проверка наблюдений...";
+    case "initial_orbit":
+      return "Начальная орбита...";
+    case "iteration":
+      return `Итерация ${progress.iteration}, RMS ${progress.rms?.toFixed(2)}″`;
+    case "close_approach":
+      return `Поиск сближения: ${Math.round((progress.fraction ?? 0) * 100)}%`;
+    case "result":
+      return "Сохранение...";
+    default:
+      return "Расчёт...";
+  }
+};
+
+// Читает поток Server-Sent Events задачи до её завершения.
+// fetch вместо EventSource — чтобы передать заголовок Authorization.
+const streamJobEvents = async (
+  id: string,
+  token: string | null,
+  onStatus: (job: OrbitJob) => void,
+  onProgress: (event: ProgressEvent) => void
+): Promise<OrbitJob> => {
+  const response = await fetch(`/api/orbit/jobs/${id}/events`, {
+    headers: token ? { Authorization: token } : {},
+  });
+  if (!response.ok || !response.body) {
+    throw new Error(`events stream failed: ${response.status}`);
+  }
+
+  const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) break;
+    buffer += value;
+
+    let sep;
+    while ((sep = buffer.indexOf("\n\n")) >= 0) {
+      const chunk = buffer.slice(0, sep);
+      buffer = buffer.slice(sep + 2);
+
+      let event = "message";
+      let data = "";
+      for (const line of chunk.split("\n")) {
+        if (line.startsWith("event:")) event = line.slice(6).trim();
+        else if (line.startsWith("data:")) data += line.slice(5).trim();
+      }
+      if (!data) continue;
+
+      if (event === "status") {
+        const job = JSON.parse(data) as OrbitJob;
+        onStatus(job);
+        if (job.status === "succeeded" || job.status === "failed") return job;
+      } else if (event === "progress") {
+        onProgress(JSON.parse(data) as ProgressEvent);
+      }
+    }
+  }
+
+  // поток оборвался — узнаём итог обычным запросом
+  const { data } = await axios.get<OrbitJob>(`/api/orbit/jobs/${id}`, {
+    headers: token ? { Authorization: token } : {},
+  });
+  return data;
+};
+
 const ObservationsPage: FC = () => {
   const navigate = useNavigate();
   const [cometName, setCometName] = useState<string>("");
@@ -29,8 +115,12 @@ const ObservationsPage: FC = () => {
     }))
   );
   const [photo, setPhoto] = useState<File | null>(null);
+  // отчёт MPC в 80-колоночном формате — вместо ручного ввода наблюдений
+  const [mpcFile, setMpcFile] = useState<File | null>(null);
   const [previewUrl, setPreviewUrl] = useState<string | null>(null);
   const [loading, setLoading] = useState(false);
+  const [job, setJob] = useState<OrbitJob | null>(null);
+  const [progress, setProgress] = useState<ProgressEvent | null>(null);
 
   const handleChange = (id: string, field: keyof Observation, value: string) => {
     setObservations((prev) =>
@@ -46,6 +136,11 @@ const ObservationsPage: FC = () => {
     }
   };
 
+  const handleMpcFileChange = (e: ChangeEvent<HTMLInputElement>) => {
+    setMpcFile(e.target.files && e.target.files[0] ? e.target.files[0] : null);
+    e.target.value = "";
+  };
+
   const handleRemovePhoto = () => {
     setPhoto(null);
     setPreviewUrl(null);
@@ -67,30 +162,61 @@ const ObservationsPage: FC = () => {
   const predictResult = async () => {
   if (!cometName) return;
   setLoading(true);
+  setJob(null);
+  setProgress(null);
 
   try {
     const formData = new FormData();
     formData.append("name", cometName);
-    formData.append(
-      "observations",
-      JSON.stringify(
-        observations.map(({ ra, dec, time }) => ({
-          ra: parseFloat(ra),
-          dec: parseFloat(dec),
-          time,
-        }))
-      )
-    );
+    if (mpcFile) {
+      formData.append("mpc", mpcFile);
+    } else {
+      formData.append(
+        "observations",
+        JSON.stringify(
+          // координаты и время отправляются как введены: сервер понимает градусы,
+          // часы и шестидесятеричную запись, ISO 8601, JD и MJD
+          observations.map(({ ra, dec, time }) => ({ ra, dec, time }))
+        )
+      );
+    }
     if (photo) formData.append("photo", photo);
 
-    const response = await axios.post("/api/orbit/calculate", formData, {
-      headers: { "Content-Type": "multipart/form-data" },
+    const token = localStorage.getItem("token");
+    const response = await axios.post<OrbitJob>("/api/orbit/jobs", formData, {
+      headers: {
+        "Content-Type": "multipart/form-data",
+        ...(token ? { Authorization: token } : {}),
+      },
     });
+    setJob(response.data);
+
+    const finished = await streamJobEvents(response.data.id, token, setJob, setProgress);
+    if (finished.status !== "succeeded") {
+      throw new Error(finished.error || "orbit job failed");
+    }
 
-    navigate("/results", { state: response.data });
+    navigate("/results", { state: finished.result });
   } catch (error) {
     console.error("Ошибка при отправке:", error);
-    alert("Ошибка при отправке данных");
+    // ошибки отчёта MPC приходят построчно
+    const details = axios.isAxiosError(error) ? error.response?.data?.details : undefined;
+    if (mpcFile && Array.isArray(details)) {
+      alert(
+        "Ошибки в отчёте MPC:\n" +
+          details.map((d: { line: number; error: string }) => `строка ${d.line}: ${d.error}`).join("\n")
+      );
+    } else if (Array.isArray(details)) {
+      // ошибки наблюдений приходят по полям
+      alert(
+        "Ошибки в наблюдениях:\n" +
+          details
+            .map((d: { index: number; field: string; error: string }) => `строка ${d.index + 1}, ${d.field}: ${d.error}`)
+            .join("\n")
+      );
+    } else {
+      alert("Ошибка при отправке данных");
+    }
   } finally {
     setLoading(false);
   }
@@ -117,33 +243,60 @@ const ObservationsPage: FC = () => {
               className="w-full p-3 rounded-lg bg-black/20 border border-gray-700 placeholder-gray-400 text-white focus:outline-none focus:border-white transition"
             />
 
-            <div className="flex flex-col w-full bg-black/10 backdrop-blur-md border border-gray-700 rounded-lg p-4 divide-y divide-gray-700 hover:bg-black/50 transition-colors">
-              <AnimatePresence>
-                {observations.map((obs, index) => (
-                  <motion.div
-                    key={obs.id}
-                    initial={{ opacity: 0, y: -10 }}
-                    animate={{ opacity: 1, y: 0 }}
-                    exit={{ opacity: 0, x: -50 }}
-                    layout
-                  >
-                    <ObservationRow
-                      index={index}
-                      observation={obs}
-                      onChange={(field, value) => handleChange(obs.id, field, value)}
-                      onRemove={() => removeObservation(obs.id)}
-                    />
-                  </motion.div>
-                ))}
-              </AnimatePresence>
-
-              <button
-                onClick={addObservation}
-                className="w-full mt-2 flex items-center justify-center border border-gray-700 rounded-lg p-2 text-gray-300 hover:border-white hover:text-white transition"
+            <div className="flex items-center gap-3">
+              <label
+                htmlFor="mpc-upload"
+                className="flex-1 text-center border border-gray-700 rounded-lg p-3 text-gray-300 cursor-pointer hover:border-white hover:text-white transition"
               >
-                <i className="bi bi-plus-lg text-xl" />
-              </button>
+                <i className="bi bi-file-earmark-text mr-2" />
+                {mpcFile ? mpcFile.name : "Загрузить отчёт MPC (80 колонок)"}
+                <input
+                  id="mpc-upload"
+                  type="file"
+                  accept=".txt,.obs,.mpc,text/plain"
+                  onChange={handleMpcFileChange}
+                  className="hidden"
+                />
+              </label>
+              {mpcFile && (
+                <button
+                  onClick={() => setMpcFile(null)}
+                  className="border border-gray-700 rounded-lg p-3 text-gray-300 hover:border-white hover:text-white transition"
+                >
+                  <i className="bi bi-x-lg" />
+                </button>
+              )}
             </div>
+
+            {!mpcFile && (
+              <div className="flex flex-col w-full bg-black/10 backdrop-blur-md border border-gray-700 rounded-lg p-4 divide-y divide-gray-700 hover:bg-black/50 transition-colors">
+                <AnimatePresence>
+                  {observations.map((obs, index) => (
+                    <motion.div
+                      key={obs.id}
+                      initial={{ opacity: 0, y: -10 }}
+                      animate={{ opacity: 1, y: 0 }}
+                      exit={{ opacity: 0, x: -50 }}
+                      layout
+                    >
+                      <ObservationRow
+                        index={index}
+                        observation={obs}
+                        onChange={(field, value) => handleChange(obs.id, field, value)}
+                        onRemove={() => removeObservation(obs.id)}
+                      />
+                    </motion.div>
+                  ))}
+                </AnimatePresence>
+
+                <button
+                  onClick={addObservation}
+                  className="w-full mt-2 flex items-center justify-center border border-gray-700 rounded-lg p-2 text-gray-300 hover:border-white hover:text-white transition"
+                >
+                  <i className="bi bi-plus-lg text-xl" />
+                </button>
+              </div>
+            )}
           </div>
 
           {/* Правая часть — загрузка фото */}
@@ -199,7 +352,7 @@ const ObservationsPage: FC = () => {
                     : "bg-white text-black hover:bg-gray-200"
                 }`}
               >
-                {loading ? "Отправка..." : "Предсказать результат"}
+                {loading ? (job ? describeProgress(job, progress) : "Отправка...") : "Предсказать результат"}
               </button>
             </div>
           </div>
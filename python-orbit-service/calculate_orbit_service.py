@@ -1,12 +1,17 @@
 # calculate_orbit_service_gauss_improved.py
 
-from typing import List, Dict, Any
+import json
+import queue
+import threading
+import time as time_module
+from typing import List, Dict, Any, Callable, Optional
 from fastapi import FastAPI
+from fastapi.responses import StreamingResponse
 from pydantic import BaseModel
 import numpy as np
 from astropy.time import Time, TimeDelta
 import astropy.units as u
-from astropy.coordinates import SkyCoord, get_body_barycentric, solar_system_ephemeris
+from astropy.coordinates import EarthLocation, SkyCoord, get_body_barycentric, solar_system_ephemeris
 from poliastro.bodies import Sun
 from poliastro.twobody.orbit import Orbit
 from scipy.optimize import least_squares
@@ -19,13 +24,64 @@ app = FastAPI(
 # ---------------------------
 # Модели FastAPI
 # ---------------------------
+class Observer(BaseModel):
+    # параллактические постоянные MPC: долгота к востоку (градусы), ρ·cos φ′ и ρ·sin φ′
+    # в экваториальных радиусах Земли
+    longitude: float
+    rho_cos_phi: float
+    rho_sin_phi: float
+
 class Observation(BaseModel):
     ra: float
     dec: float
     time: str
+    # место наблюдения; None — наблюдение геоцентрическое
+    observer: Optional[Observer] = None
+    # "include" / "exclude" — принудительно оставить или исключить наблюдение из подгонки
+    force: Optional[str] = None
+    # погрешности координат (RA·cos δ и Dec), угловые секунды; None — неизвестна
+    sigma_ra: Optional[float] = None
+    sigma_dec: Optional[float] = None
 
 class OrbitInput(BaseModel):
     observations: List[Observation]
+    # функция потерь: "linear", "huber" или "cauchy"
+    loss: Optional[str] = None
+    # порог отбраковки в единицах робастной σ невязок; 0 — без отбраковки
+    reject_sigma: float = 0.0
+    # модель движения при поиске сближения; сервис поддерживает только "two_body",
+    # "n_body" считается Go-бэкендом (orbit.backend = go)
+    model: Optional[str] = None
+
+# Отбраковка выбросов
+MAX_REJECTION_PASSES = 10
+MAD_SCALE = 1.4826
+MIN_INCLUDED = 3
+
+# Погрешность наблюдения без заданной sigma_ra/sigma_dec, угловые секунды
+DEFAULT_SIGMA_ARCSEC = 1.0
+
+# Колбэк прогресса: получает словарь события {"stage": ..., ...}
+ProgressCallback = Optional[Callable[[Dict[str, Any]], None]]
+
+# Экваториальный радиус Земли (WGS84), км
+EARTH_RADIUS_KM = 6378.137
+
+# Минимальный интервал между событиями итераций, секунды
+PROGRESS_INTERVAL = 0.5
+
+# Орбиты параметризуются перигелийным расстоянием q, поэтому допускают e ≥ 1;
+# при |e − 1| меньше допуска орбита строится как параболическая
+Q_BOUNDS = (1e-3, 100.0)
+ECC_BOUNDS = (0.0, 10.0)
+PARABOLIC_TOLERANCE = 1e-9
+
+# Длительность поиска сближения от перигелия; для незамкнутых орбит — и до него, годы
+APPROACH_YEARS = 5
+
+def _report(progress: ProgressCallback, event: Dict[str, Any]) -> None:
+    if progress is not None:
+        progress(event)
 
 # ---------------------------
 # Вспомогательные функции
@@ -36,23 +92,48 @@ def _ra_dec_to_unit_vector(ra_deg: float, dec_deg: float) -> np.ndarray:
     vec = np.array([cart.x.value, cart.y.value, cart.z.value], dtype=float)
     return vec / np.linalg.norm(vec)
 
-def _build_orbit_from_elements(a_au, ecc, inc_deg, raan_deg, argp_deg, tp_time):
-    a = a_au * u.au
-    ecc = ecc * u.one
+def _observer_offset_km(observer: Optional[Dict[str, float]], t: Time) -> np.ndarray:
+    """Геоцентрическое положение наблюдателя (км, GCRS); нулевой вектор для геоцентра."""
+    if not observer:
+        return np.zeros(3)
+    lon = np.deg2rad(observer["longitude"])
+    loc = EarthLocation.from_geocentric(
+        EARTH_RADIUS_KM * observer["rho_cos_phi"] * np.cos(lon),
+        EARTH_RADIUS_KM * observer["rho_cos_phi"] * np.sin(lon),
+        EARTH_RADIUS_KM * observer["rho_sin_phi"],
+        unit=u.km,
+    )
+    pos, _ = loc.get_gcrs_posvel(t)
+    return pos.xyz.to(u.km).value
+
+def _semi_major_axis(q_au, ecc):
+    """a = q/(1 − e): отрицательна для гиперболы, None для параболы."""
+    if abs(ecc - 1) < PARABOLIC_TOLERANCE:
+        return None
+    return q_au / (1 - ecc)
+
+def _build_orbit_from_elements(q_au, ecc, inc_deg, raan_deg, argp_deg, tp_time):
     inc = inc_deg * u.deg
     raan = raan_deg * u.deg
     argp = argp_deg * u.deg
     nu = 0 * u.deg
-    return Orbit.from_classical(Sun, a, ecc, inc, raan, argp, nu, epoch=tp_time)
+    a_au = _semi_major_axis(q_au, ecc)
+    if a_au is None:
+        # полупараметр параболы p = 2q
+        return Orbit.parabolic(Sun, 2 * q_au * u.au, inc, raan, argp, nu, epoch=tp_time)
+    return Orbit.from_classical(Sun, a_au * u.au, ecc * u.one, inc, raan, argp, nu, epoch=tp_time)
 
-def _predict_angles_from_elements(a_au, ecc, inc_deg, raan_deg, argp_deg, tp_time, times):
-    orbit = _build_orbit_from_elements(a_au, ecc, inc_deg, raan_deg, argp_deg, tp_time)
+def _predict_angles_from_elements(q_au, ecc, inc_deg, raan_deg, argp_deg, tp_time, times, offsets=None):
+    """offsets — геоцентрические положения наблюдателей (км) для каждого момента times."""
+    orbit = _build_orbit_from_elements(q_au, ecc, inc_deg, raan_deg, argp_deg, tp_time)
     out = []
-    for t in times:
+    for i, t in enumerate(times):
         dt = t - orbit.epoch
         orb_t = orbit.propagate(TimeDelta(dt.sec * u.s))
         r_obj = orb_t.r.to(u.km).value
         r_earth = np.array(get_body_barycentric("earth", t).xyz.to(u.km).value)
+        if offsets is not None:
+            r_earth = r_earth + offsets[i]
         topo = r_obj - r_earth
         topo /= np.linalg.norm(topo)
         pred_coord = SkyCoord(
@@ -84,8 +165,11 @@ def gauss_initial_orbit_improved(obs_list):
 
     rho_hat = np.array([ra_dec_to_unit(ra[i], dec[i]) for i in range(3)])
 
-    # Позиции Земли в km
-    r_earth = np.array([get_body_barycentric("earth", t).xyz.to(u.km).value for t in times]).T
+    # Позиции наблюдателей в km: центр Земли плюс смещение места наблюдения
+    r_earth = np.array([
+        get_body_barycentric("earth", t).xyz.to(u.km).value + _observer_offset_km(obs.get("observer"), t)
+        for obs, t in zip(obs_list[:3], times)
+    ]).T
 
     # Итеративное уточнение расстояния rho_mag
     rho_mag = np.array([1.0, 1.0, 1.0]) * u.au.to(u.km)
@@ -104,7 +188,31 @@ def gauss_initial_orbit_improved(obs_list):
 # ---------------------------
 # Функция расчета орбиты с использованием Гаусса
 # ---------------------------
-def calculate_orbit(observations: List[Dict[str, Any]]) -> Dict[str, Any]:
+def _reject_outliers(oc_ra, oc_dec, forces, reject_sigma):
+    """Возвращает маску принятых наблюдений и робастную σ невязок (в единицах погрешностей
+    наблюдений, не меньше 1: наблюдения, согласные со своими погрешностями, не отбраковываются).
+    Наблюдение отбраковывается, если модуль невязки sqrt(ΔRA·cosδ² + ΔDec²) > reject_sigma·σ."""
+    excluded = np.array([f == "exclude" for f in forces])
+    included = np.array([f == "include" for f in forces])
+    dev = np.hypot(oc_ra, oc_dec)
+
+    pool = np.concatenate([np.abs(oc_ra[~excluded]), np.abs(oc_dec[~excluded])])
+    sigma = max(MAD_SCALE * float(np.median(pool)) if len(pool) else 0.0, 1.0)
+
+    mask = ~excluded
+    if reject_sigma > 0:
+        mask = mask & ((dev <= reject_sigma * sigma) | included)
+        # не оставляем меньше MIN_INCLUDED наблюдений: возвращаем ближайшие к порогу
+        if mask.sum() < MIN_INCLUDED:
+            for k in np.argsort(dev):
+                if mask.sum() >= MIN_INCLUDED:
+                    break
+                if not excluded[k]:
+                    mask[k] = True
+    return mask, sigma
+
+def calculate_orbit(observations: List[Dict[str, Any]], progress: ProgressCallback = None,
+                    loss: Optional[str] = None, reject_sigma: float = 0.0) -> Dict[str, Any]:
     if len(observations) < 5:
         raise ValueError("Нужно минимум 5 наблюдений")
 
@@ -114,24 +222,49 @@ def calculate_orbit(observations: List[Dict[str, Any]]) -> Dict[str, Any]:
     obs_angles = np.empty(len(times) * 2)
     obs_angles[0::2] = obs_ra_rad
     obs_angles[1::2] = obs_dec_rad
+    offsets = np.array([_observer_offset_km(obs.get("observer"), t) for obs, t in zip(observations, times)])
+    # невязки в подгонке делятся на погрешности наблюдений
+    weights = np.empty(len(times) * 2)
+    weights[0::2] = [1.0 / (obs.get("sigma_ra") or DEFAULT_SIGMA_ARCSEC) for obs in observations]
+    weights[1::2] = [1.0 / (obs.get("sigma_dec") or DEFAULT_SIGMA_ARCSEC) for obs in observations]
+    _report(progress, {"stage": "validation"})
 
     # --- стартовое приближение через улучшенный метод Гаусса ---
     init_orbit = gauss_initial_orbit_improved(observations)
-    a0 = init_orbit.a.to(u.au).value
+    _report(progress, {"stage": "initial_orbit"})
+    q0 = init_orbit.r_p.to(u.au).value
     ecc0 = init_orbit.ecc.value
     inc0 = init_orbit.inc.to(u.deg).value
     raan0 = init_orbit.raan.to(u.deg).value
     argp0 = init_orbit.argp.to(u.deg).value
     tp_guess = init_orbit.epoch
-    x0 = np.array([a0, ecc0, inc0, raan0, argp0, tp_guess.mjd])
 
-    lower_bounds = [0.1, 0.0, 0.0, 0.0, 0.0, tp_guess.mjd - 1000]
-    upper_bounds = [10.0, 0.99, 180.0, 360.0, 360.0, tp_guess.mjd + 1000]
+    lower_bounds = [Q_BOUNDS[0], ECC_BOUNDS[0], 0.0, 0.0, 0.0, tp_guess.mjd - 1000]
+    upper_bounds = [Q_BOUNDS[1], ECC_BOUNDS[1], 180.0, 360.0, 360.0, tp_guess.mjd + 1000]
+    # least_squares требует начальную точку строго внутри границ
+    x0 = np.clip([q0, ecc0, inc0, raan0, argp0, tp_guess.mjd],
+                 np.nextafter(lower_bounds, np.inf), np.nextafter(upper_bounds, -np.inf))
+
+    # least_squares не сообщает об итерациях, поэтому прогресс считается по вычислениям невязок:
+    # iteration — номер вычисления, rms — лучшая невязка на данный момент
+    state = {"nfev": 0, "best_rms": None, "reported_at": 0.0}
+
+    def residuals_with_progress(x):
+        res = residuals(x)
+        state["nfev"] += 1
+        rms = float(np.sqrt(np.mean(res ** 2)))
+        if state["best_rms"] is None or rms < state["best_rms"]:
+            state["best_rms"] = rms
+        now = time_module.monotonic()
+        if now - state["reported_at"] >= PROGRESS_INTERVAL:
+            state["reported_at"] = now
+            _report(progress, {"stage": "iteration", "iteration": state["nfev"], "rms": state["best_rms"]})
+        return res
 
     def residuals(x):
-        a, ecc, inc, raan, argp, tp_mjd = x
+        q, ecc, inc, raan, argp, tp_mjd = x
         tp_time = Time(tp_mjd, format="mjd", scale="utc")
-        pred = _predict_angles_from_elements(a, ecc, inc, raan, argp, tp_time, times)
+        pred = _predict_angles_from_elements(q, ecc, inc, raan, argp, tp_time, times, offsets)
         dra = ((pred[0::2] - obs_angles[0::2] + np.pi) % (2 * np.pi)) - np.pi
         ddec = pred[1::2] - obs_angles[1::2]
         res = np.empty_like(pred)
@@ -139,45 +272,129 @@ def calculate_orbit(observations: List[Dict[str, Any]]) -> Dict[str, Any]:
         res[1::2] = ddec * (180 / np.pi) * 3600
         return res
 
-    result = least_squares(
-        residuals,
-        x0,
-        bounds=(lower_bounds, upper_bounds),
-        ftol=1e-10,
-        xtol=1e-10,
-        max_nfev=100000,
-        verbose=2
-    )
+    forces = [obs.get("force") or "" for obs in observations]
+    mask = np.array([f != "exclude" for f in forces])
+    if mask.sum() < MIN_INCLUDED:
+        raise ValueError(f"Нужно минимум {MIN_INCLUDED} неисключённых наблюдения")
+    scipy_loss = loss if loss in ("huber", "cauchy") else "linear"
+    f_scale = 1.0
 
-    if not result.success:
+    def masked_residuals(x):
+        res = residuals_with_progress(x)
+        return res * weights * np.repeat(mask, 2)
+
+    # подгонка повторяется, пока набор отбракованных наблюдений меняется
+    x_start = x0
+    for _ in range(MAX_REJECTION_PASSES):
+        result = least_squares(
+            masked_residuals,
+            x_start,
+            bounds=(lower_bounds, upper_bounds),
+            loss=scipy_loss,
+            f_scale=f_scale,
+            ftol=1e-10,
+            xtol=1e-10,
+            max_nfev=100000,
+            verbose=2
+        )
+        if scipy_loss == "linear" and reject_sigma <= 0:
+            break
+
+        current = residuals(result.x)
+        # отбраковка — по невязкам в единицах погрешностей наблюдений, как и в подгонке
+        new_mask, sigma = _reject_outliers(-current[0::2] * np.cos(obs_dec_rad) * weights[0::2],
+                                           -current[1::2] * weights[1::2], forces, reject_sigma)
+        f_scale = sigma
+        if np.array_equal(new_mask, mask):
+            break
+        mask = new_mask
+        x_start = result.x
+
+    # status 0 — исчерпан лимит вычислений: решение возвращается с converged=False,
+    # отрицательный статус — ошибка входных данных
+    if result.status < 0:
         raise RuntimeError(f"Оптимизация не сошлась: {result.message}")
 
-    a, ecc, inc, raan, argp, tp_mjd = result.x
+    q, ecc, inc, raan, argp, tp_mjd = result.x
     tp_iso = Time(tp_mjd, format="mjd", scale="utc").iso
 
-    return {
-        "a": float(a),
+    # невязки «наблюдено − вычислено», по RA умножены на cos(Dec)
+    final = residuals(result.x)
+    oc_ra = -final[0::2] * np.cos(obs_dec_rad)
+    oc_dec = -final[1::2]
+    rms = float(np.sqrt(np.mean(np.concatenate([oc_ra[mask], oc_dec[mask]]) ** 2)))
+
+    # нормированный χ²: невязки в единицах погрешностей наблюдений на степень свободы
+    normalized_chi2 = None
+    dof = 2 * int(mask.sum()) - len(result.x)
+    if dof > 0:
+        chi = np.concatenate([oc_ra[mask] * weights[0::2][mask], oc_dec[mask] * weights[1::2][mask]])
+        normalized_chi2 = float(np.sum(chi ** 2)) / dof
+
+    # ковариация элементов s²·(JᵀJ)⁻¹: параметры подгонки — сами элементы
+    # (q, e, i, Ω, ω в а.е./градусах и T в сутках), якобиан берётся из least_squares
+    covariance = None
+    if dof > 0 and result.jac is not None:
+        jac = np.asarray(result.jac)
+        s2 = float(np.sum((final * weights * np.repeat(mask, 2)) ** 2)) / dof
+        cov = np.linalg.pinv(jac.T @ jac) * s2
+        if np.all(np.isfinite(cov)):
+            covariance = cov
+
+    response = {
+        "perihelion_distance": float(q),
+        "a": _semi_major_axis(float(q), float(ecc)),
         "eccentricity": float(ecc),
         "inclination": float(inc),
         "longitude_of_ascending_node": float(raan),
         "argument_of_perihelion": float(argp),
         "time_of_perihelion": tp_iso,
+        "residuals": [
+            {"ra": float(r), "dec": float(d), "rejected": not bool(m)}
+            for r, d, m in zip(oc_ra, oc_dec, mask)
+        ],
+        "rms": rms,
+        "iterations": int(result.njev if result.njev is not None else result.nfev),
+        "converged": bool(result.success),
     }
+    if normalized_chi2 is not None:
+        response["normalized_chi2"] = normalized_chi2
+    if covariance is not None:
+        sigma = np.sqrt(np.clip(np.diag(covariance), 0, None))
+        response["covariance"] = covariance.tolist()
+        response["sigma"] = {
+            "perihelion_distance": float(sigma[0]),
+            "eccentricity": float(sigma[1]),
+            "inclination": float(sigma[2]),
+            "longitude_of_ascending_node": float(sigma[3]),
+            "argument_of_perihelion": float(sigma[4]),
+            "time_of_perihelion": float(sigma[5]),
+        }
+    return response
 
 # ---------------------------
 # Функция предсказания сближения с Землёй
 # ---------------------------
-def predict_close_approach(a, ecc, inc, raan, argp, tp_time, start_time=None, end_time=None, step_days=1.0):
-    orbit = _build_orbit_from_elements(a, ecc, inc, raan, argp, tp_time)
+def predict_close_approach(q, ecc, inc, raan, argp, tp_time, start_time=None, end_time=None, step_days=1.0,
+                           progress: ProgressCallback = None):
+    orbit = _build_orbit_from_elements(q, ecc, inc, raan, argp, tp_time)
+    span = TimeDelta(365.25 * APPROACH_YEARS, format='jd')
     if start_time is None:
         start_time = orbit.epoch
+        # незамкнутая или долгопериодическая орбита проходит мимо Земли один раз,
+        # сближение может быть и до перигелия; период в сутках — 365.25·a^1.5
+        a_au = _semi_major_axis(q, ecc)
+        if a_au is None or a_au <= 0 or 365.25 * a_au ** 1.5 > span.jd:
+            start_time = orbit.epoch - span
     if end_time is None:
-        end_time = start_time + TimeDelta(365.25 * 5, format='jd')
+        end_time = orbit.epoch + span
     times = start_time + TimeDelta(np.arange(0, (end_time - start_time).jd, step_days) * u.day)
 
     min_dist = None
     min_time = None
-    for t in times:
+    for i, t in enumerate(times):
+        if i % 30 == 0:
+            _report(progress, {"stage": "close_approach", "fraction": i / len(times)})
         dt = t - orbit.epoch
         orb_t = orbit.propagate(TimeDelta(dt.sec * u.s))
         r_obj = orb_t.r.to(u.km).value
@@ -187,6 +404,7 @@ def predict_close_approach(a, ecc, inc, raan, argp, tp_time, start_time=None, en
             min_dist = dist
             min_time = t
 
+    _report(progress, {"stage": "close_approach", "fraction": 1.0})
     return {
         "closest_approach_time": min_time.iso,
         "closest_approach_distance_au": float(min_dist * u.km.to(u.au))
@@ -195,24 +413,56 @@ def predict_close_approach(a, ecc, inc, raan, argp, tp_time, start_time=None, en
 # ---------------------------
 # FastAPI endpoint
 # ---------------------------
+def calculate_orbit_with_approach(input_data: OrbitInput, progress: ProgressCallback = None) -> Dict[str, Any]:
+    if input_data.model not in (None, "", "two_body"):
+        raise ValueError(f"Модель {input_data.model} не поддерживается этим сервисом: используйте orbit.backend = go")
+    obs_list = [obs.dict() for obs in input_data.observations]
+    orbit = calculate_orbit(obs_list, progress, input_data.loss, input_data.reject_sigma)
+    close_approach = predict_close_approach(
+        orbit["perihelion_distance"],
+        orbit["eccentricity"],
+        orbit["inclination"],
+        orbit["longitude_of_ascending_node"],
+        orbit["argument_of_perihelion"],
+        Time(orbit["time_of_perihelion"], scale="utc"),
+        progress=progress
+    )
+    orbit.update(close_approach)
+    return orbit
+
 @app.post("/calculate-orbit")
 async def calculate_orbit_endpoint(input_data: OrbitInput):
-    obs_list = [obs.dict() for obs in input_data.observations]
     try:
-        orbit = calculate_orbit(obs_list)
-        close_approach = predict_close_approach(
-            orbit["a"],
-            orbit["eccentricity"],
-            orbit["inclination"],
-            orbit["longitude_of_ascending_node"],
-            orbit["argument_of_perihelion"],
-            Time(orbit["time_of_perihelion"], scale="utc")
-        )
-        orbit.update(close_approach)
-        return orbit
+        return calculate_orbit_with_approach(input_data)
     except Exception as e:
         return {"error": str(e)}
 
+@app.post("/calculate-orbit/stream")
+def calculate_orbit_stream_endpoint(input_data: OrbitInput):
+    """Тот же расчёт, но ответ — поток NDJSON: события прогресса, затем
+    {"stage": "result", "result": {...}} или {"stage": "error", "message": "..."}."""
+    events: "queue.Queue[Optional[Dict[str, Any]]]" = queue.Queue()
+
+    def worker():
+        try:
+            result = calculate_orbit_with_approach(input_data, events.put)
+            events.put({"stage": "result", "result": result})
+        except Exception as e:
+            events.put({"stage": "error", "message": str(e)})
+        finally:
+            events.put(None)
+
+    threading.Thread(target=worker, daemon=True).start()
+
+    def stream():
+        while True:
+            event = events.get()
+            if event is None:
+                return
+            yield json.dumps(event) + "\n"
+
+    return StreamingResponse(stream(), media_type="application/x-ndjson")
+
 # ---------------------------
 # Эфемериды
 # ---------------------------